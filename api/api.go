@@ -1,9 +1,11 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/asim/go-micro/v3/registry"
 	"github.com/asim/go-micro/v3/server"
@@ -46,6 +48,28 @@ type Endpoint struct {
 	Body string
 	// Stream flag
 	Stream bool
+	// Timeout of the request, zero uses the client default
+	Timeout time.Duration
+	// Cache responses for the duration, zero unless the service sets Cache-Control
+	Cache time.Duration
+	// Aggregate calls made by the aggregate handler
+	Aggregate []*Call
+}
+
+// Call is one of the requests an aggregate endpoint fans out to
+type Call struct {
+	// Name of the field the response is set to, empty merges it into the top level
+	Name string `json:"name"`
+	// Service and endpoint called e.g. go.micro.srv.users Users.Read
+	Service  string `json:"service"`
+	Endpoint string `json:"endpoint"`
+	// Fields of the request mapped to fields of the http request e.g. {"id": "user_id"},
+	// the whole http request is passed on if there are none
+	Fields map[string]string `json:"fields,omitempty"`
+	// Policy when the call fails; fail, omit or default
+	Policy string `json:"policy,omitempty"`
+	// Default response used by the default policy
+	Default json.RawMessage `json:"default,omitempty"`
 }
 
 // Service represents an API service
@@ -97,6 +121,17 @@ func Encode(e *Endpoint) map[string]string {
 	set("method", strings.Join(e.Method, ","))
 	set("path", strings.Join(e.Path, ","))
 	set("host", strings.Join(e.Host, ","))
+	set("body", e.Body)
+	if e.Timeout > 0 {
+		set("timeout", e.Timeout.String())
+	}
+	if e.Cache > 0 {
+		set("cache", e.Cache.String())
+	}
+	if len(e.Aggregate) > 0 {
+		b, _ := json.Marshal(e.Aggregate)
+		set("aggregate", string(b))
+	}
 
 	return ep
 }
@@ -107,6 +142,14 @@ func Decode(e map[string]string) *Endpoint {
 		return nil
 	}
 
+	timeout, _ := time.ParseDuration(e["timeout"])
+	cache, _ := time.ParseDuration(e["cache"])
+
+	var aggregate []*Call
+	if v := e["aggregate"]; len(v) > 0 {
+		json.Unmarshal([]byte(v), &aggregate)
+	}
+
 	return &Endpoint{
 		Name:        e["endpoint"],
 		Description: e["description"],
@@ -114,6 +157,10 @@ func Decode(e map[string]string) *Endpoint {
 		Path:        slice(e["path"]),
 		Host:        slice(e["host"]),
 		Handler:     e["handler"],
+		Body:        e["body"],
+		Timeout:     timeout,
+		Cache:       cache,
+		Aggregate:   aggregate,
 	}
 }
 
@@ -147,6 +194,17 @@ func Validate(e *Endpoint) error {
 		return errors.New("invalid handler")
 	}
 
+	for _, c := range e.Aggregate {
+		if len(c.Service) == 0 || len(c.Endpoint) == 0 {
+			return errors.New("aggregate call requires a service and endpoint")
+		}
+		switch c.Policy {
+		case "", "fail", "omit", "default":
+		default:
+			return errors.New("invalid aggregate policy " + c.Policy)
+		}
+	}
+
 	return nil
 }
 
@@ -16,6 +16,17 @@ func TestEncoding(t *testing.T) {
 			Method:      []string{"GET"},
 			Path:        []string{"/test"},
 		},
+		{
+			Name:    "Profile",
+			Handler: "aggregate",
+			Host:    []string{"foo.com"},
+			Method:  []string{"GET"},
+			Path:    []string{"/profile"},
+			Aggregate: []*Call{
+				{Name: "user", Service: "go.micro.srv.users", Endpoint: "Users.Read", Fields: map[string]string{"id": "id"}},
+				{Name: "orders", Service: "go.micro.srv.orders", Endpoint: "Orders.List", Policy: "omit"},
+			},
+		},
 	}
 
 	compare := func(expect, got []string) bool {
@@ -109,6 +120,14 @@ func TestEncoding(t *testing.T) {
 		if ok := compare(d.Host, de.Host); !ok {
 			t.Fatalf("expected %v got %v", d.Host, de.Host)
 		}
+		if len(de.Aggregate) != len(d.Aggregate) {
+			t.Fatalf("expected %d aggregate calls got %d", len(d.Aggregate), len(de.Aggregate))
+		}
+		for i, c := range d.Aggregate {
+			if g := de.Aggregate[i]; g.Name != c.Name || g.Service != c.Service || g.Endpoint != c.Endpoint || g.Policy != c.Policy {
+				t.Fatalf("expected %+v got %+v", c, g)
+			}
+		}
 	}
 }
 
@@ -0,0 +1,300 @@
+// Package aggregate provides a handler which fans out to several rpc
+// endpoints concurrently and merges their responses into one json document
+package aggregate
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+
+	"github.com/asim/go-micro/v3/api"
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/metadata"
+)
+
+const (
+	Handler = "aggregate"
+)
+
+// Policies applied when a call fails
+const (
+	// PolicyFail fails the whole request
+	PolicyFail = "fail"
+	// PolicyOmit leaves the response out
+	PolicyOmit = "omit"
+	// PolicyDefault uses the default response of the call
+	PolicyDefault = "default"
+)
+
+type aggregateHandler struct {
+	opts handler.Options
+	s    *api.Service
+}
+
+// result of a call
+type result struct {
+	rsp json.RawMessage
+	err error
+}
+
+func (a *aggregateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, a.opts.MaxRecvSize)
+	defer r.Body.Close()
+
+	var service *api.Service
+
+	if a.s != nil {
+		// we were given the service
+		service = a.s
+	} else if a.opts.Router != nil {
+		// try get service from router
+		s, err := a.opts.Router.Route(r)
+		if err != nil {
+			writeError(w, errors.InternalServerError("go.micro.api", err.Error()))
+			return
+		}
+		service = s
+	} else {
+		// we have no way of routing the request
+		writeError(w, errors.InternalServerError("go.micro.api", "no route found"))
+		return
+	}
+
+	calls := service.Endpoint.Aggregate
+	if len(calls) == 0 {
+		writeError(w, errors.InternalServerError("go.micro.api", "no calls to aggregate"))
+		return
+	}
+
+	fields, err := requestFields(r)
+	if err != nil {
+		writeError(w, errors.BadRequest("go.micro.api", err.Error()))
+		return
+	}
+
+	ctx := requestContext(r)
+
+	var opts []client.CallOption
+	if service.Endpoint.Timeout > 0 {
+		opts = append(opts, client.WithRequestTimeout(service.Endpoint.Timeout))
+	}
+
+	// fan out to every call
+	results := make([]result, len(calls))
+	var wg sync.WaitGroup
+
+	for i, c := range calls {
+		wg.Add(1)
+		go func(i int, c *api.Call) {
+			defer wg.Done()
+			results[i] = a.call(ctx, c, fields, opts...)
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	// merge the responses in the order of the calls
+	out := make(map[string]json.RawMessage)
+
+	for i, c := range calls {
+		res := results[i]
+
+		if res.err != nil {
+			switch c.Policy {
+			case PolicyOmit:
+				if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+					logger.Debugf("omitting %s %s: %v", c.Service, c.Endpoint, res.err)
+				}
+				continue
+			case PolicyDefault:
+				res.rsp = c.Default
+				if len(res.rsp) == 0 {
+					res.rsp = json.RawMessage("null")
+				}
+			default:
+				writeError(w, res.err)
+				return
+			}
+		}
+
+		if len(c.Name) > 0 {
+			out[c.Name] = res.rsp
+			continue
+		}
+
+		// merge the fields of the response into the top level
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(res.rsp, &fields); err != nil {
+			writeError(w, errors.InternalServerError("go.micro.api", "response of %s %s is not an object", c.Service, c.Endpoint))
+			return
+		}
+		for k, v := range fields {
+			out[k] = v
+		}
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		writeError(w, errors.InternalServerError("go.micro.api", err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (a *aggregateHandler) String() string {
+	return "aggregate"
+}
+
+// call makes a single request through the client
+func (a *aggregateHandler) call(ctx context.Context, c *api.Call, fields map[string]interface{}, opts ...client.CallOption) result {
+	body := fields
+	if len(c.Fields) > 0 {
+		body = make(map[string]interface{})
+		for dst, src := range c.Fields {
+			if v, ok := get(fields, src); ok {
+				set(body, dst, v)
+			}
+		}
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return result{err: errors.InternalServerError("go.micro.api", err.Error())}
+	}
+
+	request := json.RawMessage(b)
+	var response json.RawMessage
+
+	req := a.opts.Client.NewRequest(c.Service, c.Endpoint, &request, client.WithContentType("application/json"))
+	if err := a.opts.Client.Call(ctx, req, &response, opts...); err != nil {
+		return result{err: err}
+	}
+	if len(response) == 0 {
+		response = json.RawMessage("{}")
+	}
+	return result{rsp: response}
+}
+
+// requestFields returns the fields of the json body, query and path
+func requestFields(r *http.Request) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	switch r.Method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 0 {
+			if err := json.Unmarshal(b, &fields); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for k, v := range r.URL.Query() {
+		if len(v) == 1 {
+			fields[k] = v[0]
+		} else {
+			fields[k] = v
+		}
+	}
+
+	// fields matched by the path pattern of the route
+	if md, ok := metadata.FromContext(r.Context()); ok {
+		for k, v := range md {
+			if k = strings.ToLower(k); strings.HasPrefix(k, "x-api-field-") {
+				fields[strings.TrimPrefix(k, "x-api-field-")] = v
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// requestContext returns a context with the headers of the request as metadata
+func requestContext(r *http.Request) context.Context {
+	md, ok := metadata.FromContext(r.Context())
+	if !ok {
+		md = make(metadata.Metadata)
+	}
+	md["Host"] = r.Host
+	md["Method"] = r.Method
+	for k := range r.Header {
+		md[textproto.CanonicalMIMEHeaderKey(k)] = r.Header.Get(k)
+	}
+	for k := range md {
+		if strings.HasPrefix(strings.ToLower(k), "x-api-") {
+			delete(md, k)
+		}
+	}
+	return metadata.MergeContext(r.Context(), md, true)
+}
+
+// get the value at a dotted path e.g. user.id
+func get(fields map[string]interface{}, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	var v interface{} = fields
+	for _, p := range parts {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[p]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// set the value at a dotted path creating maps as needed
+func set(fields map[string]interface{}, path string, v interface{}) {
+	parts := strings.Split(path, ".")
+	m := fields
+	for _, p := range parts[:len(parts)-1] {
+		n, ok := m[p].(map[string]interface{})
+		if !ok {
+			n = make(map[string]interface{})
+			m[p] = n
+		}
+		m = n
+	}
+	m[parts[len(parts)-1]] = v
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	ce := errors.Parse(err.Error())
+	if ce.Code == 0 {
+		ce.Code = 500
+		ce.Id = "go.micro.api"
+		ce.Status = http.StatusText(500)
+		ce.Detail = "error during request: " + ce.Detail
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(ce.Code))
+	w.Write([]byte(ce.Error()))
+}
+
+// NewHandler returns a handler which aggregates the responses of the calls of the routed endpoint
+func NewHandler(opts ...handler.Option) handler.Handler {
+	return &aggregateHandler{
+		opts: handler.NewOptions(opts...),
+	}
+}
+
+// WithService returns a handler for the given service
+func WithService(s *api.Service, opts ...handler.Option) handler.Handler {
+	return &aggregateHandler{
+		opts: handler.NewOptions(opts...),
+		s:    s,
+	}
+}
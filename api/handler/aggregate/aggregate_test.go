@@ -0,0 +1,89 @@
+package aggregate
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/asim/go-micro/v3/api"
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/server"
+)
+
+type TestUsers struct{}
+
+func (t *TestUsers) Read(ctx context.Context, req map[string]interface{}, rsp *map[string]interface{}) error {
+	*rsp = map[string]interface{}{"id": req["id"], "name": "john"}
+	return nil
+}
+
+func (t *TestUsers) Fail(ctx context.Context, req map[string]interface{}, rsp *map[string]interface{}) error {
+	return errors.NotFound("test.users", "not found")
+}
+
+func TestAggregate(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := server.NewServer(
+		server.Name("test.users"),
+		server.Registry(r),
+		server.Address("127.0.0.1:0"),
+	)
+	if err := s.Handle(s.NewHandler(new(TestUsers))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r))
+
+	serve := func(calls ...*api.Call) (int, string) {
+		h := WithService(&api.Service{
+			Endpoint: &api.Endpoint{Name: "Profile", Handler: Handler, Aggregate: calls},
+		}, handler.WithClient(c))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/profile?user_id=1", nil))
+		return w.Code, strings.TrimSpace(w.Body.String())
+	}
+
+	user := &api.Call{Name: "user", Service: "test.users", Endpoint: "TestUsers.Read", Fields: map[string]string{"id": "user_id"}}
+
+	code, body := serve(
+		user,
+		&api.Call{Service: "test.users", Endpoint: "TestUsers.Read", Fields: map[string]string{"id": "user_id"}},
+	)
+	if code != 200 || body != `{"id":"1","name":"john","user":{"id":"1","name":"john"}}` {
+		t.Fatalf("unexpected response %d %s", code, body)
+	}
+
+	testData := []struct {
+		policy string
+		code   int
+		body   string
+	}{
+		{"", 404, ""},
+		{PolicyOmit, 200, `{"user":{"id":"1","name":"john"}}`},
+		{PolicyDefault, 200, `{"orders":[],"user":{"id":"1","name":"john"}}`},
+	}
+
+	for _, d := range testData {
+		code, body := serve(user, &api.Call{
+			Name:     "orders",
+			Service:  "test.users",
+			Endpoint: "TestUsers.Fail",
+			Policy:   d.policy,
+			Default:  []byte(`[]`),
+		})
+		if code != d.code {
+			t.Fatalf("policy %q: expected %d got %d %s", d.policy, d.code, code, body)
+		}
+		if len(d.body) > 0 && body != d.body {
+			t.Fatalf("policy %q: expected %s got %s", d.policy, d.body, body)
+		}
+	}
+}
@@ -73,8 +73,14 @@ func (a *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	cx := ctx.FromRequest(r)
 	// create strategy
 	so := selector.WithStrategy(strategy(service.Services))
+	callOpts := []client.CallOption{client.WithSelectOption(so)}
 
-	if err := c.Call(cx, req, rsp, client.WithSelectOption(so)); err != nil {
+	// apply the endpoint timeout
+	if service.Endpoint.Timeout > 0 {
+		callOpts = append(callOpts, client.WithRequestTimeout(service.Endpoint.Timeout))
+	}
+
+	if err := c.Call(cx, req, rsp, callOpts...); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		ce := errors.Parse(err.Error())
 		switch ce.Code {
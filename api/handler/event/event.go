@@ -2,6 +2,7 @@
 package event
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -123,8 +124,14 @@ func (e *event) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// create publication
 	p := c.NewMessage(topic, ev)
 
-	// publish event
-	if err := c.Publish(ctx.FromRequest(r), p); err != nil {
+	// publish event, by the deadline of the request if it has one
+	cx := ctx.FromRequest(r)
+	if d, ok := r.Context().Deadline(); ok {
+		var cancel context.CancelFunc
+		cx, cancel = context.WithDeadline(cx, d)
+		defer cancel()
+	}
+	if err := c.Publish(cx, p); err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
@@ -0,0 +1,123 @@
+package grpcweb
+
+import (
+	"net/http"
+
+	"github.com/asim/go-micro/v3/errors"
+)
+
+// Code is a gRPC status code
+type Code uint32
+
+const (
+	OK Code = iota
+	Canceled
+	Unknown
+	InvalidArgument
+	DeadlineExceeded
+	NotFound
+	AlreadyExists
+	PermissionDenied
+	ResourceExhausted
+	FailedPrecondition
+	Aborted
+	OutOfRange
+	Unimplemented
+	Internal
+	Unavailable
+	DataLoss
+	Unauthenticated
+)
+
+var (
+	// names of the codes as used by the connect protocol
+	names = []string{
+		"ok",
+		"canceled",
+		"unknown",
+		"invalid_argument",
+		"deadline_exceeded",
+		"not_found",
+		"already_exists",
+		"permission_denied",
+		"resource_exhausted",
+		"failed_precondition",
+		"aborted",
+		"out_of_range",
+		"unimplemented",
+		"internal",
+		"unavailable",
+		"data_loss",
+		"unauthenticated",
+	}
+
+	// http status codes of errors.Error mapped to gRPC codes
+	codes = map[int32]Code{
+		http.StatusBadRequest:          InvalidArgument,
+		http.StatusUnauthorized:        Unauthenticated,
+		http.StatusForbidden:           PermissionDenied,
+		http.StatusNotFound:            NotFound,
+		http.StatusMethodNotAllowed:    Unimplemented,
+		http.StatusRequestTimeout:      DeadlineExceeded,
+		http.StatusConflict:            AlreadyExists,
+		http.StatusPreconditionFailed:  FailedPrecondition,
+		http.StatusTooManyRequests:     ResourceExhausted,
+		499:                            Canceled,
+		http.StatusInternalServerError: Internal,
+		http.StatusNotImplemented:      Unimplemented,
+		http.StatusServiceUnavailable:  Unavailable,
+		http.StatusGatewayTimeout:      DeadlineExceeded,
+	}
+
+	// http status codes of connect errors
+	statuses = map[Code]int{
+		Canceled:           499,
+		Unknown:            http.StatusInternalServerError,
+		InvalidArgument:    http.StatusBadRequest,
+		DeadlineExceeded:   http.StatusGatewayTimeout,
+		NotFound:           http.StatusNotFound,
+		AlreadyExists:      http.StatusConflict,
+		PermissionDenied:   http.StatusForbidden,
+		ResourceExhausted:  http.StatusTooManyRequests,
+		FailedPrecondition: http.StatusBadRequest,
+		Aborted:            http.StatusConflict,
+		OutOfRange:         http.StatusBadRequest,
+		Unimplemented:      http.StatusNotImplemented,
+		Internal:           http.StatusInternalServerError,
+		Unavailable:        http.StatusServiceUnavailable,
+		DataLoss:           http.StatusInternalServerError,
+		Unauthenticated:    http.StatusUnauthorized,
+	}
+)
+
+// String returns the connect name of the code
+func (c Code) String() string {
+	if int(c) < len(names) {
+		return names[c]
+	}
+	return "unknown"
+}
+
+// Status returns the http status code a connect error is sent with
+func (c Code) Status() int {
+	if s, ok := statuses[c]; ok {
+		return s
+	}
+	return http.StatusOK
+}
+
+// status returns the gRPC code and message of an error
+func status(err error) (Code, string) {
+	if err == nil {
+		return OK, ""
+	}
+	ce := errors.FromError(err)
+	code, ok := codes[ce.Code]
+	if !ok {
+		code = Unknown
+	}
+	if len(ce.Detail) == 0 {
+		return code, err.Error()
+	}
+	return code, ce.Detail
+}
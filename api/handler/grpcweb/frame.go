@@ -0,0 +1,106 @@
+package grpcweb
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// flag of a compressed message
+	flagCompressed byte = 0x01
+	// flag of the connect end of stream message
+	flagEndStream byte = 0x02
+	// flag of the grpc-web trailers
+	flagTrailer byte = 0x80
+)
+
+// readFrame reads a length prefixed message returning its flags and data
+func readFrame(r io.Reader, max int64) (byte, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(hdr[1:])
+	if int64(size) > max {
+		return 0, nil, fmt.Errorf("message of %d bytes exceeds the maximum of %d", size, max)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	if hdr[0]&flagCompressed != 0 {
+		return 0, nil, errors.New("compressed messages are not supported")
+	}
+	return hdr[0], data, nil
+}
+
+// writeFrame writes a length prefixed message
+func writeFrame(w io.Writer, flags byte, data []byte) error {
+	b := make([]byte, 5+len(data))
+	b[0] = flags
+	binary.BigEndian.PutUint32(b[1:], uint32(len(data)))
+	copy(b[5:], data)
+	_, err := w.Write(b)
+	return err
+}
+
+// trailer encodes the grpc-web trailers of a status
+func trailer(code Code, msg string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "grpc-status: %d\r\n", code)
+	if len(msg) > 0 {
+		fmt.Fprintf(&b, "grpc-message: %s\r\n", encodeMessage(msg))
+	}
+	return []byte(b.String())
+}
+
+// encodeMessage percent encodes a grpc-message as required by the gRPC spec
+func encodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// decodeText decodes a grpc-web-text body which may be made up of
+// several separately padded base64 chunks
+func decodeText(b []byte) ([]byte, error) {
+	text := strings.Join(strings.Fields(string(b)), "")
+	if len(text)%4 != 0 {
+		return nil, errors.New("invalid base64 body")
+	}
+	out := make([]byte, 0, len(text)/4*3)
+	for i := 0; i < len(text); i += 4 {
+		d, err := base64.StdEncoding.DecodeString(text[i : i+4])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d...)
+	}
+	return out, nil
+}
+
+// textWriter base64 encodes everything written for grpc-web-text
+type textWriter struct {
+	w io.Writer
+}
+
+func (t *textWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(t.w, base64.StdEncoding.EncodeToString(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
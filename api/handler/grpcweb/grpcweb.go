@@ -0,0 +1,423 @@
+// Package grpcweb provides a handler for gRPC-Web and Connect protocol requests.
+// Requests like /greeter.Say/Hello are resolved to the greeter service and
+// the Say.Hello endpoint which is called with the protobuf codec.
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/api/internal/proto"
+	"github.com/asim/go-micro/v3/api/resolver"
+	"github.com/asim/go-micro/v3/api/resolver/grpc"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/metadata"
+)
+
+const (
+	Handler = "grpcweb"
+)
+
+type protocol int
+
+const (
+	grpcWeb protocol = iota
+	grpcWebText
+	connectUnary
+	connectStream
+)
+
+var (
+	// content types of each protocol, the codec is the suffix
+	protocols = map[string]protocol{
+		"application/grpc-web":            grpcWeb,
+		"application/grpc-web+proto":      grpcWeb,
+		"application/grpc-web+json":       grpcWeb,
+		"application/grpc-web-text":       grpcWebText,
+		"application/grpc-web-text+proto": grpcWebText,
+		"application/grpc-web-text+json":  grpcWebText,
+		"application/proto":               connectUnary,
+		"application/json":                connectUnary,
+		"application/connect+proto":       connectStream,
+		"application/connect+json":        connectStream,
+	}
+
+	// grpc-timeout units
+	units = map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+)
+
+type grpcwebHandler struct {
+	opts     handler.Options
+	resolver resolver.Resolver
+}
+
+// connectError is the json error of the connect protocol
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// endStream is the last message of a connect stream
+type endStream struct {
+	Error *connectError `json:"error,omitempty"`
+}
+
+func (h *grpcwebHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.opts.MaxRecvSize)
+	defer r.Body.Close()
+
+	ct := r.Header.Get("Content-Type")
+	// Strip charset from Content-Type (like `application/json; charset=UTF-8`)
+	if idx := strings.IndexRune(ct, ';'); idx >= 0 {
+		ct = ct[:idx]
+	}
+
+	p, ok := protocols[ct]
+	if !ok {
+		writeError(w, errors.New("go.micro.api", "unsupported content type "+ct, http.StatusUnsupportedMediaType))
+		return
+	}
+	if r.Method != "POST" {
+		writeError(w, errors.New("go.micro.api", "method not allowed", http.StatusMethodNotAllowed))
+		return
+	}
+
+	service, endpoint, err := h.resolve(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// the backend is called with the json or protobuf codec
+	codec := "application/protobuf"
+	if strings.HasSuffix(ct, "json") {
+		codec = "application/json"
+	}
+
+	ctx, cancel := h.context(r)
+	defer cancel()
+
+	c := &call{
+		client:   h.opts.Client,
+		service:  service,
+		endpoint: endpoint,
+		codec:    codec,
+	}
+
+	switch p {
+	case grpcWeb, grpcWebText:
+		c.stream = h.isStream(service, endpoint)
+		serveGRPCWeb(ctx, w, r, ct, p == grpcWebText, c, h.opts.MaxRecvSize)
+	case connectUnary:
+		serveConnectUnary(ctx, w, r, ct, c)
+	case connectStream:
+		c.stream = true
+		serveConnectStream(ctx, w, r, ct, c, h.opts.MaxRecvSize)
+	}
+}
+
+func (h *grpcwebHandler) String() string {
+	return "grpcweb"
+}
+
+// resolve the service and endpoint from a path like /greeter.Say/Hello
+func (h *grpcwebHandler) resolve(r *http.Request) (string, string, error) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return "", "", errors.NotFound("go.micro.api", "unknown method "+r.URL.Path)
+	}
+
+	ep, err := h.resolver.Resolve(r)
+	if err != nil {
+		return "", "", errors.NotFound("go.micro.api", err.Error())
+	}
+	if len(ep.Name) == 0 {
+		return "", "", errors.NotFound("go.micro.api", "unknown service "+parts[0])
+	}
+
+	// Say.Hello
+	name := parts[0][strings.LastIndex(parts[0], ".")+1:]
+	return ep.Name, name + "." + parts[1], nil
+}
+
+// isStream checks the registry for whether the endpoint streams its responses
+func (h *grpcwebHandler) isStream(service, endpoint string) bool {
+	services, err := h.opts.Client.Options().Registry.GetService(service)
+	if err != nil {
+		return false
+	}
+	for _, svc := range services {
+		for _, ep := range svc.Endpoints {
+			if ep.Name == endpoint {
+				return ep.Metadata["stream"] == "true"
+			}
+		}
+	}
+	return false
+}
+
+// context returns the request context with the headers as metadata and the timeout applied
+func (h *grpcwebHandler) context(r *http.Request) (context.Context, context.CancelFunc) {
+	md, ok := metadata.FromContext(r.Context())
+	if !ok {
+		md = make(metadata.Metadata)
+	}
+	md["Host"] = r.Host
+	md["Method"] = r.Method
+	for k := range r.Header {
+		md[textproto.CanonicalMIMEHeaderKey(k)] = r.Header.Get(k)
+	}
+	ctx := metadata.MergeContext(r.Context(), md, true)
+
+	if d := timeout(r.Header); d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return context.WithCancel(ctx)
+}
+
+// timeout parses the Connect-Timeout-Ms or grpc-timeout headers
+func timeout(hdr http.Header) time.Duration {
+	if v := hdr.Get("Connect-Timeout-Ms"); len(v) > 0 {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	if v := hdr.Get("Grpc-Timeout"); len(v) > 1 {
+		unit, ok := units[v[len(v)-1]]
+		if !ok {
+			return 0
+		}
+		if n, err := strconv.ParseInt(v[:len(v)-1], 10, 64); err == nil && n > 0 {
+			return time.Duration(n) * unit
+		}
+	}
+	return 0
+}
+
+// call makes a request to the backend passing the messages through untouched
+type call struct {
+	client   client.Client
+	service  string
+	endpoint string
+	codec    string
+	stream   bool
+}
+
+// do calls the endpoint, send is called with every response
+func (c *call) do(ctx context.Context, data []byte, send func([]byte) error) error {
+	request := c.message(data)
+
+	if !c.stream {
+		req := c.client.NewRequest(c.service, c.endpoint, request, client.WithContentType(c.codec))
+		response := c.message(nil)
+		if err := c.client.Call(ctx, req, response); err != nil {
+			return err
+		}
+		b, err := marshal(response)
+		if err != nil {
+			return err
+		}
+		return send(b)
+	}
+
+	req := c.client.NewRequest(c.service, c.endpoint, request,
+		client.WithContentType(c.codec),
+		client.StreamingRequest(),
+	)
+	stream, err := c.client.Stream(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if err := stream.Send(request); err != nil {
+		return err
+	}
+
+	for {
+		response := c.message(nil)
+		if err := stream.Recv(response); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		b, err := marshal(response)
+		if err != nil {
+			return err
+		}
+		if err := send(b); err != nil {
+			return err
+		}
+	}
+}
+
+// message wraps the data so the codec passes it through as is
+func (c *call) message(data []byte) interface{} {
+	if c.codec == "application/json" {
+		if len(data) == 0 {
+			data = []byte("{}")
+		}
+		msg := json.RawMessage(data)
+		return &msg
+	}
+	return proto.NewMessage(data)
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case *json.RawMessage:
+		return *m, nil
+	case *proto.Message:
+		return m.Marshal()
+	}
+	return nil, errors.InternalServerError("go.micro.api", "unknown message type")
+}
+
+// serveGRPCWeb serves a binary or base64 encoded gRPC-Web request
+func serveGRPCWeb(ctx context.Context, w http.ResponseWriter, r *http.Request, ct string, text bool, c *call, max int64) {
+	var out io.Writer = w
+	if text {
+		out = &textWriter{w}
+	}
+
+	w.Header().Set("Content-Type", ct)
+	w.WriteHeader(http.StatusOK)
+
+	err := func() error {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return errors.BadRequest("go.micro.api", err.Error())
+		}
+		if text {
+			if body, err = decodeText(body); err != nil {
+				return errors.BadRequest("go.micro.api", err.Error())
+			}
+		}
+		_, data, err := readFrame(bytes.NewReader(body), max)
+		if err != nil && err != io.EOF {
+			return errors.BadRequest("go.micro.api", err.Error())
+		}
+
+		return c.do(ctx, data, func(b []byte) error {
+			if err := writeFrame(out, 0, b); err != nil {
+				return err
+			}
+			flush(w)
+			return nil
+		})
+	}()
+
+	code, msg := status(err)
+	if err := writeFrame(out, flagTrailer, trailer(code, msg)); err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Error(err)
+		}
+	}
+}
+
+// serveConnectUnary serves a connect unary request where the body is the message
+func serveConnectUnary(ctx context.Context, w http.ResponseWriter, r *http.Request, ct string, c *call) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeConnectError(w, errors.BadRequest("go.micro.api", err.Error()))
+		return
+	}
+
+	var rsp []byte
+	if err := c.do(ctx, body, func(b []byte) error {
+		rsp = b
+		return nil
+	}); err != nil {
+		writeConnectError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Content-Length", strconv.Itoa(len(rsp)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(rsp); err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Error(err)
+		}
+	}
+}
+
+// serveConnectStream serves a connect streaming request of enveloped messages
+func serveConnectStream(ctx context.Context, w http.ResponseWriter, r *http.Request, ct string, c *call, max int64) {
+	w.Header().Set("Content-Type", ct)
+	w.WriteHeader(http.StatusOK)
+
+	err := func() error {
+		_, data, err := readFrame(r.Body, max)
+		if err != nil && err != io.EOF {
+			return errors.BadRequest("go.micro.api", err.Error())
+		}
+		return c.do(ctx, data, func(b []byte) error {
+			if err := writeFrame(w, 0, b); err != nil {
+				return err
+			}
+			flush(w)
+			return nil
+		})
+	}()
+
+	var end endStream
+	if err != nil {
+		code, msg := status(err)
+		end.Error = &connectError{Code: code.String(), Message: msg}
+	}
+	b, _ := json.Marshal(end)
+	if err := writeFrame(w, flagEndStream, b); err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Error(err)
+		}
+	}
+}
+
+// writeConnectError writes the error as a connect unary error
+func writeConnectError(w http.ResponseWriter, err error) {
+	code, msg := status(err)
+	b, _ := json.Marshal(connectError{Code: code.String(), Message: msg})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code.Status())
+	w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	ce := errors.FromError(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(ce.Code))
+	w.Write([]byte(ce.Error()))
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// NewHandler returns a handler for gRPC-Web and Connect requests
+func NewHandler(opts ...handler.Option) handler.Handler {
+	return &grpcwebHandler{
+		opts:     handler.NewOptions(opts...),
+		resolver: grpc.NewResolver(),
+	}
+}
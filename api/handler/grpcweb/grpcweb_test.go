@@ -0,0 +1,234 @@
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/server"
+)
+
+// TestMessage is a proto message holding raw bytes
+type TestMessage struct {
+	Data []byte
+}
+
+func (m *TestMessage) ProtoMessage()               {}
+func (m *TestMessage) Reset()                      { *m = TestMessage{} }
+func (m *TestMessage) String() string              { return string(m.Data) }
+func (m *TestMessage) Marshal() ([]byte, error)    { return m.Data, nil }
+func (m *TestMessage) Unmarshal(data []byte) error { m.Data = data; return nil }
+
+type Echo struct{}
+
+func (e *Echo) Call(ctx context.Context, req *TestMessage, rsp *TestMessage) error {
+	rsp.Data = append(req.Data, '!')
+	return nil
+}
+
+func (e *Echo) Fail(ctx context.Context, req *TestMessage, rsp *TestMessage) error {
+	return errors.NotFound("test", "not found")
+}
+
+func (e *Echo) Stream(ctx context.Context, stream server.Stream) error {
+	req := new(TestMessage)
+	if err := stream.Recv(req); err != nil {
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(&TestMessage{Data: append(req.Data, byte('0'+i))}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func frame(flags byte, data string) []byte {
+	var b bytes.Buffer
+	writeFrame(&b, flags, []byte(data))
+	return b.Bytes()
+}
+
+func TestGRPCWeb(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := server.NewServer(
+		server.Name("test"),
+		server.Registry(r),
+		server.Address("127.0.0.1:0"),
+	)
+	if err := s.Handle(s.NewHandler(new(Echo))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	h := NewHandler(handler.WithClient(client.NewClient(client.Registry(r))))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	post := func(path, ct string, body []byte) (*http.Response, []byte) {
+		rsp, err := http.Post(srv.URL+path, ct, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rsp.Body.Close()
+		b, err := ioutil.ReadAll(rsp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rsp, b
+	}
+
+	// read the frames of a response
+	frames := func(b []byte) []string {
+		var out []string
+		r := bytes.NewReader(b)
+		for {
+			flags, data, err := readFrame(r, 1024)
+			if err == io.EOF {
+				return out
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if flags&flagTrailer != 0 {
+				out = append(out, "trailer "+strings.TrimSpace(string(data)))
+				continue
+			}
+			out = append(out, string(data))
+		}
+	}
+
+	testData := []struct {
+		name   string
+		path   string
+		ct     string
+		body   []byte
+		text   bool
+		status int
+		frames []string
+		expect string
+	}{
+		{
+			name:   "grpc-web",
+			path:   "/test.Echo/Call",
+			ct:     "application/grpc-web+proto",
+			body:   frame(0, "hello"),
+			status: 200,
+			frames: []string{"hello!", "trailer grpc-status: 0"},
+		},
+		{
+			name:   "grpc-web-text",
+			path:   "/test.Echo/Call",
+			ct:     "application/grpc-web-text",
+			body:   []byte(base64.StdEncoding.EncodeToString(frame(0, "hello"))),
+			text:   true,
+			status: 200,
+			frames: []string{"hello!", "trailer grpc-status: 0"},
+		},
+		{
+			name:   "grpc-web error",
+			path:   "/test.Echo/Fail",
+			ct:     "application/grpc-web",
+			body:   frame(0, "hello"),
+			status: 200,
+			frames: []string{"trailer grpc-status: 5\r\ngrpc-message: not found"},
+		},
+		{
+			name:   "grpc-web stream",
+			path:   "/test.Echo/Stream",
+			ct:     "application/grpc-web",
+			body:   frame(0, "hello"),
+			status: 200,
+			frames: []string{"hello0", "hello1", "trailer grpc-status: 0"},
+		},
+		{
+			name:   "connect unary",
+			path:   "/test.Echo/Call",
+			ct:     "application/proto",
+			body:   []byte("hello"),
+			status: 200,
+			expect: "hello!",
+		},
+		{
+			name:   "connect unary error",
+			path:   "/test.Echo/Fail",
+			ct:     "application/proto",
+			body:   []byte("hello"),
+			status: 404,
+			expect: `{"code":"not_found","message":"not found"}`,
+		},
+		{
+			name:   "connect stream",
+			path:   "/test.Echo/Stream",
+			ct:     "application/connect+proto",
+			body:   frame(0, "hello"),
+			status: 200,
+			expect: string(frame(0, "hello0")) + string(frame(0, "hello1")) + string(frame(flagEndStream, "{}")),
+		},
+		{
+			name:   "unsupported content type",
+			path:   "/test.Echo/Call",
+			ct:     "text/plain",
+			status: 415,
+		},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			rsp, body := post(d.path, d.ct, d.body)
+			if rsp.StatusCode != d.status {
+				t.Fatalf("expected status %d got %d: %s", d.status, rsp.StatusCode, body)
+			}
+			if d.frames != nil {
+				if d.text {
+					var err error
+					if body, err = decodeText(body); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if got := frames(body); strings.Join(got, "|") != strings.Join(d.frames, "|") {
+					t.Fatalf("expected frames %q got %q", d.frames, got)
+				}
+			}
+			if len(d.expect) > 0 && string(body) != d.expect {
+				t.Fatalf("expected %q got %q", d.expect, body)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	testData := []struct {
+		err    error
+		code   Code
+		status int
+	}{
+		{errors.BadRequest("test", "bad"), InvalidArgument, 400},
+		{errors.Unauthorized("test", "unauthorized"), Unauthenticated, 401},
+		{errors.Timeout("test", "timeout"), DeadlineExceeded, 504},
+		{errors.InternalServerError("test", "error"), Internal, 500},
+		{io.ErrUnexpectedEOF, Unknown, 500},
+	}
+
+	for _, d := range testData {
+		code, _ := status(d.err)
+		if code != d.code {
+			t.Fatalf("expected %v got %v for %v", d.code, code, d.err)
+		}
+		if code.Status() != d.status {
+			t.Fatalf("expected status %d got %d for %v", d.status, code.Status(), d.err)
+		}
+	}
+}
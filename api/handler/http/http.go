@@ -2,11 +2,13 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"time"
 
 	"github.com/asim/go-micro/v3/api"
 	"github.com/asim/go-micro/v3/api/handler"
@@ -25,7 +27,7 @@ type httpHandler struct {
 }
 
 func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	service, err := h.getService(r)
+	service, timeout, err := h.getService(r)
 	if err != nil {
 		w.WriteHeader(500)
 		return
@@ -42,11 +44,19 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// apply the endpoint timeout
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
 	httputil.NewSingleHostReverseProxy(rp).ServeHTTP(w, r)
 }
 
 // getService returns the service for this request from the selector
-func (h *httpHandler) getService(r *http.Request) (string, error) {
+// along with the timeout of its endpoint
+func (h *httpHandler) getService(r *http.Request) (string, time.Duration, error) {
 	var service *api.Service
 
 	if h.s != nil {
@@ -56,12 +66,12 @@ func (h *httpHandler) getService(r *http.Request) (string, error) {
 		// try get service from router
 		s, err := h.options.Router.Route(r)
 		if err != nil {
-			return "", err
+			return "", 0, err
 		}
 		service = s
 	} else {
 		// we have no way of routing the request
-		return "", errors.New("no route found")
+		return "", 0, errors.New("no route found")
 	}
 
 	// create a random selector
@@ -70,10 +80,10 @@ func (h *httpHandler) getService(r *http.Request) (string, error) {
 	// get the next node
 	s, err := next()
 	if err != nil {
-		return "", nil
+		return "", 0, nil
 	}
 
-	return fmt.Sprintf("http://%s", s.Address), nil
+	return fmt.Sprintf("http://%s", s.Address), service.Endpoint.Timeout, nil
 }
 
 func (h *httpHandler) String() string {
@@ -0,0 +1,177 @@
+// Package openapi provides a handler which serves an OpenAPI 3 document
+// generated from the api endpoints in the registry
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/registry"
+)
+
+const (
+	Handler = "openapi"
+)
+
+type openapiHandler struct {
+	opts     handler.Options
+	registry registry.Registry
+
+	sync.RWMutex
+	// the generated document, nil when it needs regenerating
+	spec []byte
+	// incremented on registry changes to discard stale documents
+	version uint64
+
+	exit chan bool
+}
+
+// ServeHTTP serves the generated document, usually mounted at /openapi.json
+func (o *openapiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.RLock()
+	spec := o.spec
+	o.RUnlock()
+
+	if spec == nil {
+		var err error
+		if spec, err = o.generate(); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+func (o *openapiHandler) String() string {
+	return "openapi"
+}
+
+// generate the document from the services in the registry
+func (o *openapiHandler) generate() ([]byte, error) {
+	o.RLock()
+	version := o.version
+	o.RUnlock()
+
+	list, err := o.registry.ListServices()
+	if err != nil {
+		return nil, err
+	}
+
+	var services []*registry.Service
+	for _, s := range list {
+		svc, err := o.registry.GetService(s.Name)
+		if err != nil {
+			continue
+		}
+		services = append(services, svc...)
+	}
+
+	spec, err := json.Marshal(Generate(o.opts.Namespace, services))
+	if err != nil {
+		return nil, err
+	}
+
+	o.Lock()
+	if o.version == version {
+		o.spec = spec
+	}
+	o.Unlock()
+
+	return spec, nil
+}
+
+// Close stops watching the registry, as does the context of the options being done
+func (o *openapiHandler) Close() error {
+	o.Lock()
+	defer o.Unlock()
+
+	select {
+	case <-o.exit:
+	default:
+		close(o.exit)
+	}
+	return nil
+}
+
+// watch the registry and regenerate the document on change
+func (o *openapiHandler) watch() {
+	var attempts int
+
+	for {
+		select {
+		case <-o.exit:
+			return
+		case <-o.opts.Context.Done():
+			return
+		default:
+		}
+
+		w, err := o.registry.Watch()
+		if err != nil {
+			attempts++
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("error watching endpoints: %v", err)
+			}
+			select {
+			case <-o.exit:
+				return
+			case <-o.opts.Context.Done():
+				return
+			case <-time.After(time.Duration(attempts) * time.Second):
+			}
+			continue
+		}
+
+		attempts = 0
+
+		// stop the watcher on exit
+		done := make(chan bool)
+		go func() {
+			select {
+			case <-o.exit:
+			case <-o.opts.Context.Done():
+			case <-done:
+			}
+			w.Stop()
+		}()
+
+		for {
+			if _, err := w.Next(); err != nil {
+				break
+			}
+			// regenerate on the next request
+			o.Lock()
+			o.spec = nil
+			o.version++
+			o.Unlock()
+		}
+
+		close(done)
+	}
+}
+
+// NewHandler returns a handler serving the OpenAPI document of the api endpoints
+// registered by services. The registry of the router is used when one is set.
+// The registry is watched until the handler is closed with Close, see io.Closer.
+func NewHandler(opts ...handler.Option) handler.Handler {
+	options := handler.NewOptions(opts...)
+
+	reg := registry.DefaultRegistry
+	if options.Router != nil {
+		reg = options.Router.Options().Registry
+	}
+
+	o := &openapiHandler{
+		opts:     options,
+		registry: reg,
+		exit:     make(chan bool),
+	}
+	go o.watch()
+	return o
+}
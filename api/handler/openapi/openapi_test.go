@@ -0,0 +1,140 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/api"
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/api/router"
+	"github.com/asim/go-micro/v3/api/router/registry"
+	reg "github.com/asim/go-micro/v3/registry"
+)
+
+func testService(name string, path string) *reg.Service {
+	return &reg.Service{
+		Name:    name,
+		Version: "latest",
+		Nodes: []*reg.Node{
+			{Id: name + "-1", Address: "127.0.0.1:8080"},
+		},
+		Endpoints: []*reg.Endpoint{
+			{
+				Name: "Greeter.Hello",
+				Request: &reg.Value{
+					Name: "Request",
+					Type: "Request",
+					Values: []*reg.Value{
+						{Name: "name", Type: "string"},
+						{Name: "tags", Type: "[]string"},
+					},
+				},
+				Response: &reg.Value{
+					Name: "Response",
+					Type: "Response",
+					Values: []*reg.Value{
+						{Name: "msg", Type: "string"},
+					},
+				},
+				Metadata: api.Encode(&api.Endpoint{
+					Name:    "Greeter.Hello",
+					Handler: "rpc",
+					Method:  []string{"GET"},
+					Path:    []string{path},
+				}),
+			},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	doc := Generate("test", []*reg.Service{testService("greeter", "/greeter/{name}")})
+
+	item, ok := doc.Paths["/greeter/{name}"]
+	if !ok {
+		t.Fatalf("expected path /greeter/{name} got %v", doc.Paths)
+	}
+	op, ok := item["get"]
+	if !ok {
+		t.Fatal("expected get operation")
+	}
+	if op.OperationID != "greeter.Greeter.Hello" {
+		t.Fatalf("unexpected operation id %s", op.OperationID)
+	}
+	if len(op.Parameters) != 2 {
+		t.Fatalf("expected 2 parameters got %d", len(op.Parameters))
+	}
+	if p := op.Parameters[0]; p.Name != "name" || p.In != "path" || !p.Required {
+		t.Fatalf("unexpected path parameter %+v", p)
+	}
+	if p := op.Parameters[1]; p.Name != "tags" || p.In != "query" || p.Schema.Type != "array" {
+		t.Fatalf("unexpected query parameter %+v", p)
+	}
+	if ref := op.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/greeter.Response" {
+		t.Fatalf("unexpected response schema %s", ref)
+	}
+	if s, ok := doc.Components.Schemas["greeter.Response"]; !ok || s.Properties["msg"].Type != "string" {
+		t.Fatalf("unexpected response component %+v", s)
+	}
+}
+
+func TestGenerateSharedTypes(t *testing.T) {
+	hello := testService("hello", "/hello/{name}")
+	hello.Endpoints[0].Response.Values = []*reg.Value{{Name: "greeting", Type: "string"}}
+
+	doc := Generate("test", []*reg.Service{testService("greeter", "/greeter/{name}"), hello})
+
+	// services using the same type names each get their own schema
+	ref := doc.Paths["/hello/{name}"]["get"].Responses["200"].Content["application/json"].Schema.Ref
+	if ref != "#/components/schemas/hello.Response" {
+		t.Fatalf("unexpected response schema %s", ref)
+	}
+	if s := doc.Components.Schemas["hello.Response"]; s.Properties["greeting"] == nil {
+		t.Fatalf("unexpected response component %+v", s)
+	}
+	if s := doc.Components.Schemas["greeter.Response"]; s.Properties["msg"] == nil {
+		t.Fatalf("unexpected response component %+v", s)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := reg.NewMemoryRegistry()
+	rt := registry.NewRouter(router.WithRegistry(r))
+	defer rt.Close()
+
+	if err := r.Register(testService("greeter", "/greeter/{name}")); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(handler.WithRouter(rt))
+	defer h.(io.Closer).Close()
+	// wait for the watcher to start
+	time.Sleep(time.Millisecond * 100)
+
+	read := func() *Document {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+		var doc *Document
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	if doc := read(); len(doc.Paths) != 1 {
+		t.Fatalf("expected 1 path got %d", len(doc.Paths))
+	}
+
+	// the document is regenerated when the registry changes
+	if err := r.Register(testService("hello", "/hello/{name}")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 100)
+
+	if doc := read(); len(doc.Paths) != 2 {
+		t.Fatalf("expected 2 paths got %d", len(doc.Paths))
+	}
+}
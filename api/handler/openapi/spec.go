@@ -0,0 +1,311 @@
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/asim/go-micro/v3/api"
+	"github.com/asim/go-micro/v3/api/router/util"
+	"github.com/asim/go-micro/v3/registry"
+)
+
+// Version of the OpenAPI specification generated
+const Version = "3.0.3"
+
+var (
+	// matches path template variables e.g {name} or {name=*}
+	variableRe = regexp.MustCompile(`\{([^=}]+)(=[^}]*)?\}`)
+)
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	Tags       []Tag               `json:"tags,omitempty"`
+}
+
+// Info describes the api
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Tag groups the operations of a service
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem maps http methods to operations
+type PathItem map[string]*Operation
+
+// Operation is a single api endpoint
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody of an operation
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema for a content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas referenced by operations
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is a subset of the JSON schema used by OpenAPI
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+}
+
+// errorSchema is the schema of errors.Error returned by the gateway
+var errorSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"id":     {Type: "string"},
+		"code":   {Type: "integer", Format: "int32"},
+		"detail": {Type: "string"},
+		"status": {Type: "string"},
+	},
+}
+
+// Generate an OpenAPI document from the api endpoints of the services
+func Generate(title string, services []*registry.Service) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:   title,
+			Version: "1.0.0",
+		},
+		Paths: make(map[string]PathItem),
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Error": errorSchema,
+			},
+		},
+	}
+
+	tags := make(map[string]bool)
+
+	for _, service := range services {
+		for _, ep := range service.Endpoints {
+			end := api.Decode(ep.Metadata)
+			if err := api.Validate(end); err != nil {
+				continue
+			}
+			for _, p := range end.Path {
+				// regular expressions can't be described as path templates
+				if strings.HasPrefix(p, "^") {
+					continue
+				}
+				rule, err := util.Parse(p)
+				if err != nil {
+					continue
+				}
+				tpl := rule.Compile()
+				path := variableRe.ReplaceAllString(p, "{$1}")
+
+				item, ok := doc.Paths[path]
+				if !ok {
+					item = make(PathItem)
+					doc.Paths[path] = item
+				}
+
+				methods := end.Method
+				if len(methods) == 0 {
+					methods = []string{"POST"}
+				}
+				for _, method := range methods {
+					item[strings.ToLower(method)] = doc.operation(service.Name, method, end, ep, tpl.Fields)
+				}
+				tags[service.Name] = true
+			}
+		}
+	}
+
+	for name := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: name})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool {
+		return doc.Tags[i].Name < doc.Tags[j].Name
+	})
+
+	return doc
+}
+
+func (d *Document) operation(service, method string, end *api.Endpoint, ep *registry.Endpoint, fields []string) *Operation {
+	op := &Operation{
+		OperationID: fmt.Sprintf("%s.%s", service, end.Name),
+		Summary:     end.Description,
+		Tags:        []string{service},
+		Responses: map[string]Response{
+			"200": {
+				Description: "OK",
+				Content: map[string]MediaType{
+					"application/json": {Schema: d.schema(service, ep.Response)},
+				},
+			},
+			"default": {
+				Description: "Error",
+				Content: map[string]MediaType{
+					"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}},
+				},
+			},
+		},
+	}
+
+	bound := make(map[string]bool)
+	for _, field := range fields {
+		bound[field] = true
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     field,
+			In:       "path",
+			Required: true,
+			Schema:   d.schema(service, lookup(ep.Request, field)),
+		})
+	}
+
+	switch method {
+	case "GET", "DELETE", "HEAD":
+		// the request fields not bound to the path are read from the query
+		if ep.Request == nil {
+			break
+		}
+		for _, v := range ep.Request.Values {
+			if bound[v.Name] {
+				continue
+			}
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:   v.Name,
+				In:     "query",
+				Schema: d.schema(service, v),
+			})
+		}
+	default:
+		body := ep.Request
+		if len(end.Body) > 0 && end.Body != "*" {
+			body = lookup(ep.Request, end.Body)
+		}
+		op.RequestBody = &RequestBody{
+			Content: map[string]MediaType{
+				"application/json": {Schema: d.schema(service, body)},
+			},
+		}
+	}
+
+	return op
+}
+
+// schema returns the schema for a value, adding message types to the components
+// keyed by service and type since services commonly share type names
+func (d *Document) schema(service string, v *registry.Value) *Schema {
+	if v == nil {
+		return &Schema{Type: "object"}
+	}
+
+	if strings.HasPrefix(v.Type, "[]") {
+		// bytes are encoded as base64 strings
+		if v.Type == "[]uint8" || v.Type == "[]byte" {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		items := &registry.Value{Type: strings.TrimPrefix(v.Type, "[]")}
+		return &Schema{Type: "array", Items: d.schema(service, items)}
+	}
+
+	if s := primitive(v.Type); s != nil {
+		return s
+	}
+
+	name := service + "." + v.Type
+
+	// anonymous types or messages beyond the extracted depth
+	if len(v.Type) == 0 || len(v.Values) == 0 {
+		if _, ok := d.Components.Schemas[name]; ok && len(v.Type) > 0 {
+			return &Schema{Ref: "#/components/schemas/" + name}
+		}
+		return &Schema{Type: "object"}
+	}
+
+	if _, ok := d.Components.Schemas[name]; !ok {
+		s := &Schema{
+			Type:       "object",
+			Properties: make(map[string]*Schema),
+		}
+		// set before descending in case the message is recursive
+		d.Components.Schemas[name] = s
+		for _, f := range v.Values {
+			s.Properties[f.Name] = d.schema(service, f)
+		}
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// lookup a nested field by its dotted path
+func lookup(v *registry.Value, path string) *registry.Value {
+	for _, name := range strings.Split(path, ".") {
+		if v == nil {
+			return nil
+		}
+		var next *registry.Value
+		for _, f := range v.Values {
+			if f.Name == name {
+				next = f
+				break
+			}
+		}
+		v = next
+	}
+	return v
+}
+
+func primitive(typ string) *Schema {
+	switch typ {
+	case "string":
+		return &Schema{Type: "string"}
+	case "bool":
+		return &Schema{Type: "boolean"}
+	case "int", "int8", "int16", "int32", "uint8", "uint16", "uint32":
+		return &Schema{Type: "integer", Format: "int32"}
+	case "int64", "uint", "uint64":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "float32":
+		return &Schema{Type: "number", Format: "float"}
+	case "float64":
+		return &Schema{Type: "number", Format: "double"}
+	}
+	return nil
+}
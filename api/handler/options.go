@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"context"
+
 	"github.com/asim/go-micro/v3/api/router"
 	"github.com/asim/go-micro/v3/client"
 )
@@ -14,6 +16,10 @@ type Options struct {
 	Namespace   string
 	Router      router.Router
 	Client      client.Client
+
+	// Other options for implementations of the handler
+	// can be stored in a context
+	Context context.Context
 }
 
 type Option func(o *Options)
@@ -38,6 +44,10 @@ func NewOptions(opts ...Option) Options {
 		options.MaxRecvSize = DefaultMaxRecvSize
 	}
 
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
 	return options
 }
 
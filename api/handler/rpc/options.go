@@ -0,0 +1,29 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/asim/go-micro/v3/api/handler"
+)
+
+type validateKey struct{}
+
+// Validate json requests against the request schema the service registered
+// for the endpoint before they're sent on. Unknown fields, fields of the wrong
+// type and missing required fields are answered with a 400. Requests opening a
+// stream, as server-sent events or a websocket, are validated too. Requests
+// encoded as protobuf and the messages sent over a websocket once it's open
+// are not.
+func Validate(b bool) handler.Option {
+	return setOption(validateKey{}, b)
+}
+
+// setOption returns a function to setup a context with given value
+func setOption(k, v interface{}) handler.Option {
+	return func(o *handler.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -84,6 +84,9 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	var service *api.Service
 
+	// the request context is replaced below, keep track of client disconnects
+	done := r.Context().Done()
+
 	if h.s != nil {
 		// we were given the service
 		service = h.s
@@ -137,12 +140,28 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// drop older context as it can have timeouts and create new
 		//		md, _ := metadata.FromContext(cx)
 		//serveWebsocket(context.TODO(), w, r, service, c)
-		serveWebsocket(cx, w, r, service, c)
+		serveWebsocket(cx, w, r, service, c, h.validate)
+		return
+	}
+
+	// stream as server-sent events if the client accepts them
+	if isEventStream(r) && isStreamEndpoint(service) {
+		serveEventStream(cx, done, w, r, service, c, h.validate)
 		return
 	}
 
 	// create strategy
 	so := selector.WithStrategy(strategy(service.Services))
+	callOpts := []client.CallOption{client.WithSelectOption(so)}
+
+	// apply the endpoint timeout
+	if service.Endpoint.Timeout > 0 {
+		callOpts = append(callOpts, client.WithRequestTimeout(service.Endpoint.Timeout))
+	}
+
+	// metadata returned by the service is written as response headers
+	var rmd metadata.Metadata
+	callOpts = append(callOpts, client.WithResponseMetadata(&rmd))
 
 	// walk the standard call path
 	// get payload
@@ -174,7 +193,7 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		)
 
 		// make the call
-		if err := c.Call(cx, req, response, client.WithSelectOption(so)); err != nil {
+		if err := c.Call(cx, req, response, callOpts...); err != nil {
 			writeError(w, r, err)
 			return
 		}
@@ -192,6 +211,12 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			ct = "application/json"
 		}
 
+		// validate the request against the registered schema
+		if err := h.validate(service, br); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
 		// default to trying json
 		var request json.RawMessage
 		// if the extracted payload isn't empty lets use it
@@ -209,7 +234,7 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			client.WithContentType(ct),
 		)
 		// make the call
-		if err := c.Call(cx, req, &response, client.WithSelectOption(so)); err != nil {
+		if err := c.Call(cx, req, &response, callOpts...); err != nil {
 			writeError(w, r, err)
 			return
 		}
@@ -222,10 +247,22 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	for k, v := range rmd {
+		w.Header().Set(k, v)
+	}
+
 	// write the response
 	writeResponse(w, r, rsp)
 }
 
+// validate the json request against the registered schema when enabled
+func (h *rpcHandler) validate(service *api.Service, b []byte) error {
+	if v, _ := h.opts.Context.Value(validateKey{}).(bool); !v {
+		return nil
+	}
+	return validateRequest(service, b)
+}
+
 func (rh *rpcHandler) String() string {
 	return "rpc"
 }
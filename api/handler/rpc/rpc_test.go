@@ -2,14 +2,237 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/asim/go-micro/v3/api"
+	"github.com/asim/go-micro/v3/api/handler"
 	go_api "github.com/asim/go-micro/v3/api/proto"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/server"
 	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+type TestCacheable struct{}
+
+func (t *TestCacheable) Get(ctx context.Context, req map[string]interface{}, rsp *map[string]interface{}) error {
+	server.SetResponseMetadata(ctx, "Cache-Control", "max-age=60")
+	*rsp = map[string]interface{}{"ok": true}
+	return nil
+}
+
+func TestResponseMetadata(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := server.NewServer(
+		server.Name("test.cacheable"),
+		server.Registry(r),
+		server.Address("127.0.0.1:0"),
+	)
+	if err := s.Handle(s.NewHandler(new(TestCacheable))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	services, err := r.GetService("test.cacheable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := WithService(&api.Service{
+		Name:     "test.cacheable",
+		Endpoint: &api.Endpoint{Name: "TestCacheable.Get"},
+		Services: services,
+	}, handler.WithClient(client.NewClient(client.Registry(r))))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Request-Header", "leak")
+	h.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected 200 got %d: %s", w.Code, w.Body)
+	}
+	if v := w.Header().Get("Cache-Control"); v != "max-age=60" {
+		t.Fatalf("expected the Cache-Control set by the service got %q", v)
+	}
+
+	// only the metadata set by the service is sent back
+	for k := range w.Header() {
+		switch k {
+		case "Cache-Control", "Content-Type", "Content-Length":
+		default:
+			t.Fatalf("unexpected header %s in %v", k, w.Header())
+		}
+	}
+}
+
+type TestAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type TestValidateRequest struct {
+	Name    string       `json:"name" validate:"required"`
+	Age     int32        `json:"age"`
+	Tags    []string     `json:"tags"`
+	Address *TestAddress `json:"address"`
+}
+
+type TestValidator struct{}
+
+func (t *TestValidator) Create(ctx context.Context, req *TestValidateRequest, rsp *map[string]interface{}) error {
+	*rsp = map[string]interface{}{"name": req.Name}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := server.NewServer(
+		server.Name("test.validator"),
+		server.Registry(r),
+		server.Address("127.0.0.1:0"),
+	)
+	if err := s.Handle(s.NewHandler(new(TestValidator))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	services, err := r.GetService("test.validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := WithService(&api.Service{
+		Name:     "test.validator",
+		Endpoint: &api.Endpoint{Name: "TestValidator.Create"},
+		Services: services,
+	}, handler.WithClient(client.NewClient(client.Registry(r))), Validate(true))
+
+	testData := []struct {
+		body   string
+		code   int
+		detail []string
+	}{
+		{`{"name": "john", "age": 30, "tags": ["a"], "address": {"city": "london"}}`, 200, nil},
+		{`{"age": 30}`, 400, []string{"name: required"}},
+		{`{"name": "john", "foo": 1}`, 400, []string{"foo: unknown field"}},
+		{`{"name": "john", "age": "thirty"}`, 400, []string{"age: expected int32"}},
+		{`{"name": "john", "age": 1.5, "tags": [1]}`, 400, []string{"age: expected int32", "tags[0]: expected string"}},
+		{`{"name": "john", "address": {"street": "x"}}`, 400, []string{"address.city: required", "address.street: unknown field"}},
+		{`[1]`, 400, []string{"expected an object"}},
+	}
+
+	for _, d := range testData {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", bytes.NewBufferString(d.body))
+		req.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, req)
+
+		if w.Code != d.code {
+			t.Fatalf("%s: expected %d got %d: %s", d.body, d.code, w.Code, w.Body)
+		}
+		for _, v := range d.detail {
+			if !strings.Contains(w.Body.String(), v) {
+				t.Fatalf("%s: expected %q in %s", d.body, v, w.Body)
+			}
+		}
+	}
+
+	// requests opening a stream are validated too
+	for _, ep := range services[0].Endpoints {
+		ep.Metadata["stream"] = "true"
+	}
+
+	streams := map[string]map[string]string{
+		"events":    {"Accept": "text/event-stream"},
+		"websocket": {"Connection": "Upgrade", "Upgrade": "websocket"},
+	}
+	for name, hdr := range streams {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"age": 30}`))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		h.ServeHTTP(w, req)
+
+		if w.Code != 400 || !strings.Contains(w.Body.String(), "name: required") {
+			t.Fatalf("%s: expected 400 got %d: %s", name, w.Code, w.Body)
+		}
+	}
+}
+
+type TestOneof struct{}
+
+func (t *TestOneof) Set(ctx context.Context, req *structpb.Value, rsp *map[string]interface{}) error {
+	*rsp = map[string]interface{}{"ok": true}
+	return nil
+}
+
+func TestValidateOneof(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := server.NewServer(
+		server.Name("test.oneof"),
+		server.Registry(r),
+		server.Address("127.0.0.1:0"),
+	)
+	if err := s.Handle(s.NewHandler(new(TestOneof))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	services, err := r.GetService("test.oneof")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := WithService(&api.Service{
+		Name:     "test.oneof",
+		Endpoint: &api.Endpoint{Name: "TestOneof.Set"},
+		Services: services,
+	}, handler.WithClient(client.NewClient(client.Registry(r))), Validate(true))
+
+	// the members of the oneof are the fields, not the oneof or proto state
+	testData := []struct {
+		body   string
+		code   int
+		detail string
+	}{
+		{`{"stringValue": "foo"}`, 200, ""},
+		{`{"number_value": 1.5}`, 200, ""},
+		{`{"numberValue": "one"}`, 400, "numberValue: expected float64"},
+		{`{"Kind": {"stringValue": "foo"}}`, 400, "Kind: unknown field"},
+		{`{"state": {}}`, 400, "state: unknown field"},
+	}
+
+	for _, d := range testData {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", bytes.NewBufferString(d.body))
+		req.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, req)
+
+		if w.Code != d.code || !strings.Contains(w.Body.String(), d.detail) {
+			t.Fatalf("%s: expected %d %q got %d: %s", d.body, d.code, d.detail, w.Code, w.Body)
+		}
+	}
+}
+
 func TestRequestPayloadFromRequest(t *testing.T) {
 
 	// our test event so that we can validate serialising / deserializing of true protos works
@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asim/go-micro/v3/api"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/selector"
+)
+
+var (
+	// DefaultHeartbeat is the interval of the comments sent to keep an event stream open
+	DefaultHeartbeat = time.Second * 15
+)
+
+// serveEventStream will stream rpc responses back as server-sent events assuming json.
+// The done channel is closed when the client disconnects.
+func serveEventStream(ctx context.Context, done <-chan struct{}, w http.ResponseWriter, r *http.Request, service *api.Service, c client.Client, validate func(*api.Service, []byte) error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errors.InternalServerError("go.micro.api", "streaming unsupported"))
+		return
+	}
+
+	payload, err := requestPayload(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(payload) == 0 {
+		payload = []byte(`{}`)
+	}
+	if err := validate(service, payload); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// resume the event ids from the last one the client saw,
+	// the header is also passed on to the service as metadata
+	var id uint64
+	if v := r.Header.Get("Last-Event-ID"); len(v) > 0 {
+		id, _ = strconv.ParseUint(v, 10, 64)
+	}
+
+	request := json.RawMessage(payload)
+	req := c.NewRequest(
+		service.Name,
+		service.Endpoint.Name,
+		&request,
+		client.WithContentType("application/json"),
+		client.StreamingRequest(),
+	)
+
+	so := selector.WithStrategy(strategy(service.Services))
+	// create a new stream
+	stream, err := c.Stream(ctx, req, client.WithSelectOption(so))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer stream.Close()
+
+	if err := stream.Send(&request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable response buffering in proxies such as nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	type result struct {
+		data json.RawMessage
+		err  error
+	}
+
+	// receive from the stream in the background so heartbeats can be sent
+	ch := make(chan result)
+	exit := make(chan bool)
+	defer close(exit)
+
+	go func() {
+		defer close(ch)
+		for {
+			var rsp json.RawMessage
+			err := stream.Recv(&rsp)
+			select {
+			case ch <- result{rsp, err}:
+			case <-exit:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(DefaultHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case res, ok := <-ch:
+			if !ok {
+				return
+			}
+			if res.err == io.EOF {
+				return
+			}
+			if res.err != nil {
+				writeEvent(w, "error", "", eventError(res.err))
+				flusher.Flush()
+				return
+			}
+			id++
+			if err := writeEvent(w, "", strconv.FormatUint(id, 10), res.data); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Error(err)
+				}
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a single server-sent event, a blank event is a message
+func writeEvent(w io.Writer, event, id string, data []byte) error {
+	var b strings.Builder
+	if len(event) > 0 {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	if len(id) > 0 {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	// every line of the data needs its own field
+	for _, line := range strings.Split(string(data), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// eventError encodes an error as the json of an errors.Error
+func eventError(err error) []byte {
+	ce := errors.Parse(err.Error())
+	if ce.Code == 0 {
+		ce.Code = 500
+		ce.Id = "go.micro.api"
+		ce.Status = http.StatusText(500)
+		ce.Detail = "error during request: " + ce.Detail
+	}
+	b, _ := json.Marshal(ce)
+	return b
+}
+
+func isEventStream(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		if idx := strings.IndexRune(v, ';'); idx >= 0 {
+			v = v[:idx]
+		}
+		if strings.TrimSpace(v) == "text/event-stream" {
+			return true
+		}
+	}
+	return false
+}
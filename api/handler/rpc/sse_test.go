@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/asim/go-micro/v3/api"
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/server"
+)
+
+type TestStreamer struct{}
+
+func (t *TestStreamer) Count(ctx context.Context, stream server.Stream) error {
+	var req map[string]interface{}
+	if err := stream.Recv(&req); err != nil {
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(map[string]interface{}{"n": i}); err != nil {
+			return err
+		}
+	}
+	if req["fail"] == true {
+		return errors.BadRequest("test.streamer", "failed")
+	}
+	return nil
+}
+
+func TestServeEventStream(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := server.NewServer(
+		server.Name("test.streamer"),
+		server.Registry(r),
+		server.Address("127.0.0.1:0"),
+	)
+	if err := s.Handle(s.NewHandler(new(TestStreamer))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	services, err := r.GetService("test.streamer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := WithService(&api.Service{
+		Name: "test.streamer",
+		Endpoint: &api.Endpoint{
+			Name: "TestStreamer.Count",
+		},
+		Services: services,
+	}, handler.WithClient(client.NewClient(client.Registry(r))))
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	get := func(query, lastID string) (*http.Response, string) {
+		req, err := http.NewRequest("GET", srv.URL+"/?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		if len(lastID) > 0 {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rsp.Body.Close()
+		b, err := ioutil.ReadAll(rsp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rsp, string(b)
+	}
+
+	rsp, body := get("count=2", "")
+	if ct := rsp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream got %s", ct)
+	}
+	expected := "id: 1\ndata: {\"n\":0}\n\nid: 2\ndata: {\"n\":1}\n\n"
+	if body != expected {
+		t.Fatalf("expected %q got %q", expected, body)
+	}
+
+	// ids continue from the last event seen
+	_, body = get("count=2", "5")
+	if !strings.HasPrefix(body, "id: 6\n") {
+		t.Fatalf("expected ids to resume from 6 got %q", body)
+	}
+
+	// errors are sent as an error event
+	_, body = get("fail=true", "")
+	if !strings.Contains(body, "event: error\ndata: {\"id\":\"test.streamer\",\"code\":400,\"detail\":\"failed\"") {
+		t.Fatalf("expected an error event got %q", body)
+	}
+}
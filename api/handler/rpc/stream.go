@@ -20,7 +20,7 @@ import (
 )
 
 // serveWebsocket will stream rpc back over websockets assuming json
-func serveWebsocket(ctx context.Context, w http.ResponseWriter, r *http.Request, service *api.Service, c client.Client) {
+func serveWebsocket(ctx context.Context, w http.ResponseWriter, r *http.Request, service *api.Service, c client.Client, validate func(*api.Service, []byte) error) {
 	var op ws.OpCode
 
 	ct := r.Header.Get("Content-Type")
@@ -55,6 +55,14 @@ func serveWebsocket(ctx context.Context, w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	// validate the json request the stream is opened with
+	if ct == "application/json" || ct == "" {
+		if err := validate(service, payload); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
 	upgrader := ws.HTTPUpgrader{Timeout: 5 * time.Second,
 		Protocol: func(proto string) bool {
 			if strings.Contains(proto, "binary") {
@@ -224,7 +232,11 @@ func isStream(r *http.Request, srv *api.Service) bool {
 	if !isWebSocket(r) {
 		return false
 	}
-	// check if the endpoint supports streaming
+	return isStreamEndpoint(srv)
+}
+
+// isStreamEndpoint checks if the endpoint supports streaming
+func isStreamEndpoint(srv *api.Service) bool {
 	for _, service := range srv.Services {
 		for _, ep := range service.Endpoints {
 			// skip if it doesn't match the name
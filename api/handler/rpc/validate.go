@@ -0,0 +1,205 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asim/go-micro/v3/api"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/registry"
+)
+
+// requestSchema finds the registered request value and metadata of the endpoint
+func requestSchema(service *api.Service) *registry.Endpoint {
+	for _, s := range service.Services {
+		for _, ep := range s.Endpoints {
+			if ep.Name == service.Endpoint.Name && ep.Request != nil {
+				return ep
+			}
+		}
+	}
+	return nil
+}
+
+// validateRequest checks the json payload against the request schema of
+// the endpoint. Endpoints with no known schema are not validated.
+func validateRequest(service *api.Service, b []byte) error {
+	ep := requestSchema(service)
+	if ep == nil || len(ep.Request.Values) == 0 {
+		return nil
+	}
+
+	if len(bytes.TrimSpace(b)) == 0 {
+		b = []byte("{}")
+	}
+
+	var req interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		return errors.BadRequest("go.micro.api", "invalid request body: %v", err)
+	}
+
+	obj, ok := req.(map[string]interface{})
+	if !ok {
+		return errors.BadRequest("go.micro.api", "invalid request body: expected an object")
+	}
+
+	var invalid []string
+	validateObject(ep.Request, obj, "", &invalid)
+
+	for _, path := range strings.Split(ep.Metadata["required"], ",") {
+		if len(path) > 0 && missing(obj, strings.Split(path, ".")) {
+			invalid = append(invalid, path+": required")
+		}
+	}
+
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	sort.Strings(invalid)
+	return errors.BadRequest("go.micro.api", "invalid fields: %s", strings.Join(invalid, "; "))
+}
+
+// validateObject checks the fields of the object against the values of the schema
+func validateObject(schema *registry.Value, obj map[string]interface{}, prefix string, invalid *[]string) {
+	fields := make(map[string]*registry.Value, len(schema.Values)*2)
+	for _, v := range schema.Values {
+		fields[v.Name] = v
+		fields[lowerCamel(v.Name)] = v
+	}
+
+	for k, v := range obj {
+		f, ok := fields[k]
+		if !ok {
+			*invalid = append(*invalid, prefix+k+": unknown field")
+			continue
+		}
+		validateValue(f, v, prefix+k, invalid)
+	}
+}
+
+// validateValue checks the type of a field
+func validateValue(f *registry.Value, v interface{}, path string, invalid *[]string) {
+	if v == nil {
+		return
+	}
+
+	switch {
+	// nested message, other encodings such as timestamps are let through
+	case len(f.Values) > 0:
+		if obj, ok := v.(map[string]interface{}); ok {
+			validateObject(f, obj, path+".", invalid)
+		}
+	// bytes are base64 encoded
+	case f.Type == "[]uint8" || f.Type == "[]byte":
+		if _, ok := v.(string); !ok {
+			*invalid = append(*invalid, path+": expected base64 string")
+		}
+	case strings.HasPrefix(f.Type, "[]"):
+		elems, ok := v.([]interface{})
+		if !ok {
+			*invalid = append(*invalid, path+": expected array")
+			return
+		}
+		typ := strings.TrimPrefix(f.Type, "[]")
+		for i, e := range elems {
+			if !isType(typ, e) {
+				*invalid = append(*invalid, fmt.Sprintf("%s[%d]: expected %s", path, i, typ))
+			}
+		}
+	default:
+		if !isType(f.Type, v) {
+			*invalid = append(*invalid, path+": expected "+f.Type)
+		}
+	}
+}
+
+// isType checks a json value can be decoded into the go type. Numbers and
+// bools are accepted as strings since path and query fields always are.
+func isType(typ string, v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	var s string
+	switch t := v.(type) {
+	case json.Number:
+		s = t.String()
+	case string:
+		s = t
+	case bool:
+		return typ == "bool"
+	default:
+		// objects and arrays only match types we don't know about
+		switch typ {
+		case "string", "bool", "int", "int8", "int16", "int32", "int64",
+			"uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+			return false
+		}
+		return true
+	}
+
+	_, isNumber := v.(json.Number)
+
+	var err error
+	switch typ {
+	case "string":
+		return !isNumber
+	case "bool":
+		_, err = strconv.ParseBool(s)
+		return !isNumber && err == nil
+	case "int", "int64":
+		_, err = strconv.ParseInt(s, 10, 64)
+	case "int8", "int16", "int32":
+		_, err = strconv.ParseInt(s, 10, bitSize(typ))
+	case "uint", "uint64":
+		_, err = strconv.ParseUint(s, 10, 64)
+	case "uint8", "uint16", "uint32":
+		_, err = strconv.ParseUint(s, 10, bitSize(typ))
+	case "float32", "float64":
+		_, err = strconv.ParseFloat(s, bitSize(typ))
+	}
+	return err == nil
+}
+
+func bitSize(typ string) int {
+	n, _ := strconv.Atoi(strings.TrimLeft(typ, "intufloat"))
+	return n
+}
+
+// missing checks whether the field at the path is missing. Fields nested
+// in an object which isn't set are not missing, the object is.
+func missing(obj map[string]interface{}, path []string) bool {
+	v, ok := obj[path[0]]
+	if !ok {
+		v, ok = obj[lowerCamel(path[0])]
+	}
+	if len(path) == 1 {
+		return !ok || v == nil
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return missing(m, path[1:])
+	}
+	return false
+}
+
+// lowerCamel converts a snake case name to the lower camel case used by
+// the proto json mapping e.g. user_id to userId
+func lowerCamel(s string) string {
+	if !strings.Contains(s, "_") {
+		return s
+	}
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) > 0 {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
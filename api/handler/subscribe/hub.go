@@ -0,0 +1,171 @@
+package subscribe
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/asim/go-micro/v3/broker"
+	"github.com/asim/go-micro/v3/logger"
+	util "github.com/asim/go-micro/v3/util/broker"
+)
+
+// Message is the event written to a connection
+type Message struct {
+	Topic  string            `json:"topic"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   json.RawMessage   `json:"body,omitempty"`
+}
+
+// conn is a single client connection receiving messages
+type conn struct {
+	// header values a message must match
+	filters map[string]string
+	// queued messages
+	ch chan *Message
+	// closed when the connection is disconnected for being slow
+	exit chan bool
+	once sync.Once
+}
+
+func newConn(filters map[string]string, size int) *conn {
+	return &conn{
+		filters: filters,
+		ch:      make(chan *Message, size),
+		exit:    make(chan bool),
+	}
+}
+
+func (c *conn) match(hdr map[string]string) bool {
+	for k, v := range c.filters {
+		if hdr[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *conn) close() {
+	c.once.Do(func() {
+		close(c.exit)
+	})
+}
+
+// topic is a broker subscription shared by many connections
+type topic struct {
+	sub   broker.Subscriber
+	conns map[*conn]bool
+}
+
+// hub fans out a single broker subscription per topic to the connections
+type hub struct {
+	broker broker.Broker
+	policy Policy
+
+	sync.RWMutex
+	topics map[string]*topic
+}
+
+func newHub(b broker.Broker, p Policy) *hub {
+	return &hub{
+		broker: b,
+		policy: p,
+		topics: make(map[string]*topic),
+	}
+}
+
+// join subscribes the connection to the topics, subscribing to the broker on first use
+func (h *hub) join(c *conn, topics []string) error {
+	h.Lock()
+	for i, name := range topics {
+		t, ok := h.topics[name]
+		if !ok {
+			sub, err := h.broker.Subscribe(name, h.handle)
+			if err != nil {
+				subs := h.leaveLocked(c, topics[:i])
+				h.Unlock()
+				unsubscribe(subs)
+				return err
+			}
+			t = &topic{sub: sub, conns: make(map[*conn]bool)}
+			h.topics[name] = t
+		}
+		t.conns[c] = true
+	}
+	h.Unlock()
+
+	return nil
+}
+
+// leave removes the connection, unsubscribing when no connections remain
+func (h *hub) leave(c *conn, topics []string) {
+	h.Lock()
+	subs := h.leaveLocked(c, topics)
+	h.Unlock()
+
+	unsubscribe(subs)
+}
+
+// leaveLocked removes the connection and returns the subscriptions of the
+// topics left without connections, to unsubscribe once the lock is released
+// as unsubscribing may call the registry
+func (h *hub) leaveLocked(c *conn, topics []string) map[string]broker.Subscriber {
+	subs := make(map[string]broker.Subscriber)
+	for _, name := range topics {
+		t, ok := h.topics[name]
+		if !ok {
+			continue
+		}
+		delete(t.conns, c)
+		if len(t.conns) > 0 {
+			continue
+		}
+		subs[name] = t.sub
+		delete(h.topics, name)
+	}
+	return subs
+}
+
+// unsubscribe from the topics
+func unsubscribe(subs map[string]broker.Subscriber) {
+	for name, sub := range subs {
+		if err := sub.Unsubscribe(); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("unable to unsubscribe from %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// handle a message from the broker by queueing it on each matching connection
+func (h *hub) handle(e broker.Event) error {
+	m := e.Message()
+	msg := &Message{
+		Topic:  e.Topic(),
+		Header: m.Header,
+		Body:   util.Body(m),
+	}
+
+	h.RLock()
+	defer h.RUnlock()
+
+	t, ok := h.topics[e.Topic()]
+	if !ok {
+		return nil
+	}
+
+	for c := range t.conns {
+		if !c.match(m.Header) {
+			continue
+		}
+		select {
+		case c.ch <- msg:
+		default:
+			// the connection can't keep up
+			if h.policy == Disconnect {
+				c.close()
+			}
+		}
+	}
+
+	return nil
+}
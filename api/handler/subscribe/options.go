@@ -0,0 +1,66 @@
+package subscribe
+
+import (
+	"context"
+
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/auth"
+)
+
+// Policy determines what happens to a connection which can't keep up
+type Policy int
+
+const (
+	// Drop messages for a slow connection
+	Drop Policy = iota
+	// Disconnect a slow connection
+	Disconnect
+)
+
+var (
+	// DefaultBufferSize is the number of messages queued per connection
+	DefaultBufferSize = 64
+)
+
+type topicsKey struct{}
+type authKey struct{}
+type rulesKey struct{}
+type bufferSizeKey struct{}
+type policyKey struct{}
+
+// Topics which may be subscribed to. A trailing * matches any topic with the prefix.
+// No topics can be subscribed to unless they're allowed.
+func Topics(topics ...string) handler.Option {
+	return setOption(topicsKey{}, topics)
+}
+
+// Auth used to inspect the bearer token of a request
+func Auth(a auth.Auth) handler.Option {
+	return setOption(authKey{}, a)
+}
+
+// Rules used to verify the account has access to a topic. The resource
+// verified is of type "topic" with the topic as the name.
+func Rules(r auth.Rules) handler.Option {
+	return setOption(rulesKey{}, r)
+}
+
+// BufferSize is the number of messages queued for each connection
+func BufferSize(n int) handler.Option {
+	return setOption(bufferSizeKey{}, n)
+}
+
+// SlowConsumer sets the policy applied when a connection's buffer is full
+func SlowConsumer(p Policy) handler.Option {
+	return setOption(policyKey{}, p)
+}
+
+// setOption returns a function to setup a context with given value
+func setOption(k, v interface{}) handler.Option {
+	return func(o *handler.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,271 @@
+// Package subscribe provides a handler which streams broker messages to
+// browsers over a websocket or as server-sent events
+package subscribe
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/auth"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+const (
+	Handler = "subscribe"
+)
+
+var (
+	// DefaultHeartbeat is the interval of the comments sent to keep an event stream open
+	DefaultHeartbeat = time.Second * 15
+)
+
+type subscribeHandler struct {
+	opts   handler.Options
+	hub    *hub
+	topics []string
+	auth   auth.Auth
+	rules  auth.Rules
+	size   int
+}
+
+// ServeHTTP subscribes the connection to the topics in the query, e.g.
+// /subscribe?topic=orders&filter=Region:eu
+func (s *subscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var topics []string
+	for _, v := range r.URL.Query()["topic"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); len(t) > 0 {
+				topics = append(topics, t)
+			}
+		}
+	}
+	if len(topics) == 0 {
+		writeError(w, errors.BadRequest("go.micro.api", "topic required"))
+		return
+	}
+
+	if err := s.verify(r, topics); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	filters := make(map[string]string)
+	for _, v := range r.URL.Query()["filter"] {
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 {
+			writeError(w, errors.BadRequest("go.micro.api", "invalid filter %s", v))
+			return
+		}
+		filters[parts[0]] = parts[1]
+	}
+
+	c := newConn(filters, s.size)
+	if err := s.hub.join(c, topics); err != nil {
+		writeError(w, errors.InternalServerError("go.micro.api", "%v", err))
+		return
+	}
+	defer s.hub.leave(c, topics)
+
+	if isWebSocket(r) {
+		serveWebsocket(w, r, c)
+		return
+	}
+	serveEventStream(w, r, c)
+}
+
+func (s *subscribeHandler) String() string {
+	return "subscribe"
+}
+
+// verify the topics are allowed and the account has access to them
+func (s *subscribeHandler) verify(r *http.Request, topics []string) error {
+	for _, t := range topics {
+		if !allowed(s.topics, t) {
+			return errors.Forbidden("go.micro.api", "topic %s is not allowed", t)
+		}
+	}
+
+	if s.rules == nil {
+		return nil
+	}
+
+	acc, _ := auth.AccountFromContext(r.Context())
+	if h := r.Header.Get("Authorization"); acc == nil && s.auth != nil && strings.HasPrefix(h, auth.BearerScheme) {
+		a, err := s.auth.Inspect(strings.TrimPrefix(h, auth.BearerScheme))
+		if err != nil {
+			return errors.Unauthorized("go.micro.api", "%v", err)
+		}
+		acc = a
+	}
+
+	for _, t := range topics {
+		res := &auth.Resource{Type: "topic", Name: t, Endpoint: t}
+		if err := s.rules.Verify(acc, res); err != nil {
+			if acc == nil {
+				return errors.Unauthorized("go.micro.api", "unauthorized to subscribe to %s", t)
+			}
+			return errors.Forbidden("go.micro.api", "forbidden to subscribe to %s", t)
+		}
+	}
+
+	return nil
+}
+
+// serveEventStream writes messages as server-sent events
+func serveEventStream(w http.ResponseWriter, r *http.Request, c *conn) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, errors.InternalServerError("go.micro.api", "streaming unsupported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(DefaultHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.exit:
+			b, _ := json.Marshal(errors.New("go.micro.api", "slow consumer disconnected", 503))
+			w.Write([]byte("event: error\ndata: " + string(b) + "\n\n"))
+			flusher.Flush()
+			return
+		case <-heartbeat.C:
+			if _, err := w.Write([]byte(": heartbeat\n\n")); err != nil {
+				return
+			}
+		case msg := <-c.ch:
+			b, err := json.Marshal(msg)
+			if err != nil {
+				continue
+			}
+			if _, err := w.Write([]byte("data: " + string(b) + "\n\n")); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// serveWebsocket writes messages as websocket text frames
+func serveWebsocket(w http.ResponseWriter, r *http.Request, c *conn) {
+	nc, rw, _, err := ws.UpgradeHTTP(r, w)
+	if err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Error(err)
+		}
+		return
+	}
+	defer nc.Close()
+
+	// read until the client goes away, discarding anything it sends
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := wsutil.ReadClientData(nc); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-c.exit:
+			frame := ws.NewCloseFrame(ws.NewCloseFrameBody(ws.StatusPolicyViolation, "slow consumer"))
+			ws.WriteFrame(rw, frame)
+			rw.Flush()
+			return
+		case msg := <-c.ch:
+			b, err := json.Marshal(msg)
+			if err != nil {
+				continue
+			}
+			if err := wsutil.WriteServerMessage(rw, ws.OpText, b); err != nil {
+				return
+			}
+			if err := rw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// allowed checks the topic against the allow list
+func allowed(topics []string, topic string) bool {
+	for _, t := range topics {
+		if t == "*" || t == topic {
+			return true
+		}
+		if strings.HasSuffix(t, "*") && strings.HasPrefix(topic, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWebSocket(r *http.Request) bool {
+	contains := func(key, val string) bool {
+		for _, v := range strings.Split(r.Header.Get(key), ",") {
+			if val == strings.ToLower(strings.TrimSpace(v)) {
+				return true
+			}
+		}
+		return false
+	}
+	return contains("Connection", "upgrade") && contains("Upgrade", "websocket")
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	ce := errors.FromError(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(ce.Code))
+	w.Write([]byte(ce.Error()))
+}
+
+// NewHandler returns a handler which subscribes to broker topics on behalf of
+// http clients. Topics must be allowed using the Topics option.
+func NewHandler(opts ...handler.Option) handler.Handler {
+	options := handler.NewOptions(opts...)
+
+	s := &subscribeHandler{
+		opts: options,
+		size: DefaultBufferSize,
+	}
+
+	policy := Drop
+	if v, ok := options.Context.Value(topicsKey{}).([]string); ok {
+		s.topics = v
+	}
+	if v, ok := options.Context.Value(authKey{}).(auth.Auth); ok {
+		s.auth = v
+	}
+	if v, ok := options.Context.Value(rulesKey{}).(auth.Rules); ok {
+		s.rules = v
+	}
+	if v, ok := options.Context.Value(bufferSizeKey{}).(int); ok && v > 0 {
+		s.size = v
+	}
+	if v, ok := options.Context.Value(policyKey{}).(Policy); ok {
+		policy = v
+	}
+
+	s.hub = newHub(options.Client.Options().Broker, policy)
+	return s
+}
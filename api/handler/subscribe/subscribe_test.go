@@ -0,0 +1,161 @@
+package subscribe
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/api/handler"
+	"github.com/asim/go-micro/v3/broker"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/registry"
+)
+
+type testEvent struct {
+	topic string
+	msg   *broker.Message
+}
+
+func (t *testEvent) Topic() string            { return t.topic }
+func (t *testEvent) Message() *broker.Message { return t.msg }
+func (t *testEvent) Ack() error               { return nil }
+func (t *testEvent) Error() error             { return nil }
+
+// testSub calls fn to unsubscribe
+type testSub struct {
+	broker.Subscriber
+	fn func() error
+}
+
+func (t *testSub) Unsubscribe() error { return t.fn() }
+
+func TestSubscribe(t *testing.T) {
+	b := broker.NewBroker(broker.Registry(registry.NewMemoryRegistry()))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	h := NewHandler(
+		handler.WithClient(client.NewClient(client.Broker(b))),
+		Topics("orders.*"),
+	)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	// topics not in the allow list are forbidden
+	rsp, err := http.Get(srv.URL + "/?topic=users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != 403 {
+		t.Fatalf("expected 403 got %d", rsp.StatusCode)
+	}
+
+	rsp, err = http.Get(srv.URL + "/?topic=orders.created&filter=Region:eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	if ct := rsp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream got %s", ct)
+	}
+
+	// the message not matching the filter is skipped
+	for _, region := range []string{"us", "eu"} {
+		if err := b.Publish("orders.created", &broker.Message{
+			Header: map[string]string{"Region": region, "Content-Type": "application/json"},
+			Body:   []byte(`{"region":"` + region + `"}`),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	line := make(chan string)
+	go func() {
+		r := bufio.NewReader(rsp.Body)
+		for {
+			l, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(l, "data: ") {
+				line <- strings.TrimPrefix(l, "data: ")
+				return
+			}
+		}
+	}()
+
+	select {
+	case l := <-line:
+		var msg Message
+		if err := json.Unmarshal([]byte(l), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Topic != "orders.created" || string(msg.Body) != `{"region":"eu"}` {
+			t.Fatalf("unexpected message %+v", msg)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func TestSlowConsumer(t *testing.T) {
+	h := newHub(nil, Disconnect)
+	c := newConn(nil, 1)
+	h.topics["foo"] = &topic{conns: map[*conn]bool{c: true}}
+
+	ev := &testEvent{topic: "foo", msg: &broker.Message{Body: []byte("bar")}}
+	h.handle(ev)
+
+	select {
+	case <-c.exit:
+		t.Fatal("expected connection to be open")
+	default:
+	}
+
+	// the buffer is full so the connection is closed
+	h.handle(ev)
+
+	select {
+	case <-c.exit:
+	default:
+		t.Fatal("expected connection to be closed")
+	}
+
+	if msg := <-c.ch; string(msg.Body) != `"YmFy"` {
+		t.Fatalf("expected base64 body got %s", msg.Body)
+	}
+}
+
+func TestLeaveUnlocked(t *testing.T) {
+	h := newHub(nil, Disconnect)
+	c := newConn(nil, 1)
+
+	// messages are still handled while unsubscribing
+	sub := &testSub{fn: func() error {
+		return h.handle(&testEvent{topic: "foo", msg: &broker.Message{}})
+	}}
+	h.topics["foo"] = &topic{sub: sub, conns: map[*conn]bool{c: true}}
+
+	done := make(chan bool)
+	go func() {
+		h.leave(c, []string{"foo"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("unsubscribing with the hub locked")
+	}
+
+	if _, ok := h.topics["foo"]; ok {
+		t.Fatal("expected the topic to be removed")
+	}
+}
@@ -2,6 +2,7 @@
 package web
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/asim/go-micro/v3/api"
 	"github.com/asim/go-micro/v3/api/handler"
@@ -26,7 +28,7 @@ type webHandler struct {
 }
 
 func (wh *webHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	service, err := wh.getService(r)
+	service, timeout, err := wh.getService(r)
 	if err != nil {
 		w.WriteHeader(500)
 		return
@@ -48,11 +50,19 @@ func (wh *webHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// apply the endpoint timeout
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
 	httputil.NewSingleHostReverseProxy(rp).ServeHTTP(w, r)
 }
 
 // getService returns the service for this request from the selector
-func (wh *webHandler) getService(r *http.Request) (string, error) {
+// along with the timeout of its endpoint
+func (wh *webHandler) getService(r *http.Request) (string, time.Duration, error) {
 	var service *api.Service
 
 	if wh.s != nil {
@@ -62,12 +72,12 @@ func (wh *webHandler) getService(r *http.Request) (string, error) {
 		// try get service from router
 		s, err := wh.opts.Router.Route(r)
 		if err != nil {
-			return "", err
+			return "", 0, err
 		}
 		service = s
 	} else {
 		// we have no way of routing the request
-		return "", errors.New("no route found")
+		return "", 0, errors.New("no route found")
 	}
 
 	// create a random selector
@@ -76,10 +86,10 @@ func (wh *webHandler) getService(r *http.Request) (string, error) {
 	// get the next node
 	s, err := next()
 	if err != nil {
-		return "", nil
+		return "", 0, nil
 	}
 
-	return fmt.Sprintf("http://%s", s.Address), nil
+	return fmt.Sprintf("http://%s", s.Address), service.Endpoint.Timeout, nil
 }
 
 // serveWebSocket used to serve a web socket proxied connection
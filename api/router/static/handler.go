@@ -0,0 +1,55 @@
+package static
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/asim/go-micro/v3/api/router"
+	"github.com/asim/go-micro/v3/errors"
+)
+
+// handler serves requests with the handler of the type of their route
+type handler struct {
+	router   router.Router
+	handlers map[string]http.Handler
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	service, err := h.router.Route(r)
+	if err != nil {
+		writeError(w, errors.InternalServerError("go.micro.api", err.Error()))
+		return
+	}
+
+	hd, ok := h.handlers[service.Endpoint.Handler]
+	if !ok {
+		writeError(w, errors.InternalServerError("go.micro.api", "no %s handler", service.Endpoint.Handler))
+		return
+	}
+
+	// the timeout of the route applies to handlers of every type
+	if t := service.Endpoint.Timeout; t > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), t)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	hd.ServeHTTP(w, r)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	ce := errors.Parse(err.Error())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(ce.Code))
+	w.Write([]byte(ce.Error()))
+}
+
+// NewHandler returns a handler serving each request with the handler of the
+// type of the route it matches, e.g. rpc, http, web, event or aggregate, of
+// the handlers given by type. The handlers are expected to use the router.
+func NewHandler(r router.Router, handlers map[string]http.Handler) http.Handler {
+	return &handler{
+		router:   r,
+		handlers: handlers,
+	}
+}
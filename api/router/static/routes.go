@@ -0,0 +1,266 @@
+package static
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/asim/go-micro/v3/api"
+	"github.com/asim/go-micro/v3/config"
+	"github.com/asim/go-micro/v3/logger"
+)
+
+// Route is a declarative route as defined in a config source, e.g.
+//
+//	routes:
+//	- name: greeter
+//	  method: [POST]
+//	  path: [/greeter/hello]
+//	  handler: rpc
+//	  service: go.micro.srv.greeter
+//	  endpoint: Greeter.Hello
+//	  timeout: 5s
+type Route struct {
+	// Name of the route, used when reporting errors
+	Name string `json:"name"`
+	// Host, Method and Path patterns matched against the request
+	Host   []string `json:"host"`
+	Method []string `json:"method"`
+	Path   []string `json:"path"`
+	// Handler type, one of rpc, api, http, web, event or aggregate
+	Handler string `json:"handler"`
+	// Service and endpoint the request is routed to
+	Service  string `json:"service"`
+	Endpoint string `json:"endpoint"`
+	// Body field the request body is mapped to
+	Body string `json:"body"`
+	// Stream flag
+	Stream bool `json:"stream"`
+	// Timeout of the request e.g. 5s
+	Timeout string `json:"timeout"`
+	// Cache responses for the duration e.g. 30s
+	Cache string `json:"cache"`
+	// Aggregate calls made by the aggregate handler
+	Aggregate []*api.Call `json:"aggregate"`
+}
+
+var (
+	// handlers a route may use
+	handlers = map[string]bool{
+		"rpc":   true,
+		"api":   true,
+		"http":  true,
+		"web":   true,
+		"event": true,
+		// aggregate routes call the services of the aggregate calls
+		"aggregate": true,
+	}
+)
+
+// compileRoute validates the route and compiles it to an endpoint
+func compileRoute(r *Route) (*endpoint, error) {
+	name := r.Name
+	if len(name) == 0 {
+		name = r.Service + " " + r.Endpoint
+	}
+
+	if r.Handler == "aggregate" {
+		if len(r.Aggregate) == 0 {
+			return nil, fmt.Errorf("route %s: aggregate calls required", name)
+		}
+	} else if len(r.Service) == 0 {
+		return nil, fmt.Errorf("route %s: service required", name)
+	}
+	if !handlers[r.Handler] {
+		return nil, fmt.Errorf("route %s: invalid handler %q", name, r.Handler)
+	}
+	if (r.Handler == "rpc" || r.Handler == "api") && len(r.Endpoint) == 0 {
+		return nil, fmt.Errorf("route %s: endpoint required", name)
+	}
+	if len(r.Method) == 0 {
+		return nil, fmt.Errorf("route %s: method required", name)
+	}
+	if len(r.Path) == 0 {
+		return nil, fmt.Errorf("route %s: path required", name)
+	}
+	for _, p := range r.Path {
+		if len(p) == 0 {
+			return nil, fmt.Errorf("route %s: empty path", name)
+		}
+	}
+
+	var timeout time.Duration
+	if len(r.Timeout) > 0 {
+		t, err := time.ParseDuration(r.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: invalid timeout: %v", name, err)
+		}
+		timeout = t
+	}
+
+	var cache time.Duration
+	if len(r.Cache) > 0 {
+		c, err := time.ParseDuration(r.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: invalid cache: %v", name, err)
+		}
+		cache = c
+	}
+
+	ep := &api.Endpoint{
+		Name:    r.Endpoint,
+		Handler: r.Handler,
+		Host:    r.Host,
+		Method:  r.Method,
+		Path:    r.Path,
+		Body:    r.Body,
+		Stream:  r.Stream,
+		Timeout: timeout,
+		Cache:   cache,
+
+		Aggregate: r.Aggregate,
+	}
+	// http, web and event routes target the service as a whole
+	if len(ep.Name) == 0 {
+		ep.Name = r.Service
+	}
+	if len(ep.Name) == 0 {
+		ep.Name = name
+	}
+
+	e, err := compile(ep)
+	if err != nil {
+		return nil, fmt.Errorf("route %s: %v", name, err)
+	}
+	e.service = r.Service
+	return e, nil
+}
+
+// Load replaces the routes loaded from config. Every route is validated
+// before any are swapped in so an invalid table leaves the current one in place.
+// Routes of every type are served by the handler of NewHandler, routers with
+// a handler other than the default meta only take routes of their handler.
+func (r *staticRouter) Load(routes []*Route) error {
+	eps := make([]*endpoint, 0, len(routes))
+	for _, route := range routes {
+		ep, err := compileRoute(route)
+		if err != nil {
+			return err
+		}
+		if h := r.opts.Handler; h != "meta" && h != route.Handler {
+			return fmt.Errorf("route %s: handler %q isn't served by the %s handler", ep.apiep.Name, route.Handler, h)
+		}
+		eps = append(eps, ep)
+	}
+
+	r.Lock()
+	r.routes = eps
+	r.Unlock()
+	return nil
+}
+
+// LoadConfig loads the routes at the given path of the config and reloads
+// them whenever they change until the router is closed, or the routes of
+// another config are loaded.
+func (r *staticRouter) LoadConfig(c config.Config, path ...string) error {
+	var routes []*Route
+	if err := c.Get(path...).Scan(&routes); err != nil {
+		return err
+	}
+	if err := r.Load(routes); err != nil {
+		return err
+	}
+
+	// stop watching the config loaded before
+	stop := make(chan bool)
+	r.Lock()
+	if r.stop != nil {
+		close(r.stop)
+	}
+	r.stop = stop
+	r.Unlock()
+
+	go r.watch(c, stop, path...)
+	return nil
+}
+
+// watch the config for route changes until stopped
+func (r *staticRouter) watch(c config.Config, stop chan bool, path ...string) {
+	var attempts int
+
+	for {
+		select {
+		case <-r.exit:
+			return
+		case <-stop:
+			return
+		default:
+		}
+
+		// watch for changes
+		w, err := c.Watch(path...)
+		if err != nil {
+			attempts++
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("error watching routes: %v", err)
+			}
+			select {
+			case <-time.After(time.Duration(attempts) * time.Second):
+				continue
+			case <-r.exit:
+				return
+			case <-stop:
+				return
+			}
+		}
+
+		ch := make(chan bool)
+
+		go func() {
+			select {
+			case <-ch:
+			case <-r.exit:
+			case <-stop:
+			}
+			w.Stop()
+		}()
+
+		// reset if we get here
+		attempts = 0
+
+		for {
+			// process next change
+			v, err := w.Next()
+			if err != nil {
+				close(ch)
+				select {
+				case <-r.exit:
+					return
+				case <-stop:
+					return
+				default:
+				}
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("error getting next routes: %v", err)
+				}
+				break
+			}
+
+			var routes []*Route
+			if err := v.Scan(&routes); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("error reading routes: %v", err)
+				}
+				continue
+			}
+			if err := r.Load(routes); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("error loading routes, keeping current routes: %v", err)
+				}
+				continue
+			}
+			if logger.V(logger.InfoLevel, logger.DefaultLogger) {
+				logger.Infof("loaded %d routes", len(routes))
+			}
+		}
+	}
+}
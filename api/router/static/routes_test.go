@@ -0,0 +1,276 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/api/router"
+	"github.com/asim/go-micro/v3/config"
+	"github.com/asim/go-micro/v3/config/source"
+	"github.com/asim/go-micro/v3/config/source/memory"
+	"github.com/asim/go-micro/v3/registry"
+)
+
+func TestLoadConfig(t *testing.T) {
+	reg := registry.NewMemoryRegistry()
+	if err := reg.Register(&registry.Service{
+		Name:  "go.micro.srv.greeter",
+		Nodes: []*registry.Node{{Id: "1", Address: "127.0.0.1:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	src := memory.NewSource(memory.WithJSON([]byte(`{"routes": [{
+		"name": "hello",
+		"method": ["POST"],
+		"path": ["/greeter/hello"],
+		"handler": "rpc",
+		"service": "go.micro.srv.greeter",
+		"endpoint": "Greeter.Hello",
+		"timeout": "5s"
+	}]}`)))
+
+	c, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load(src); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	r := NewRouter(router.WithRegistry(reg))
+	defer r.Close()
+
+	if err := r.LoadConfig(c, "routes"); err != nil {
+		t.Fatal(err)
+	}
+
+	route := func(path string) error {
+		req, err := http.NewRequest("POST", "http://localhost"+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		svc, err := r.Route(req)
+		if err != nil {
+			return err
+		}
+		if svc.Name != "go.micro.srv.greeter" || svc.Endpoint.Name != "Greeter.Hello" {
+			t.Fatalf("unexpected service %s endpoint %s", svc.Name, svc.Endpoint.Name)
+		}
+		if svc.Endpoint.Handler != "rpc" || svc.Endpoint.Timeout != time.Second*5 {
+			t.Fatalf("unexpected handler %s timeout %v", svc.Endpoint.Handler, svc.Endpoint.Timeout)
+		}
+		return nil
+	}
+
+	if err := route("/greeter/hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(data string) {
+		if err := src.Write(&source.ChangeSet{Data: []byte(data), Format: "json"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// an invalid table keeps the current routes
+	update(`{"routes": [{
+		"name": "hello",
+		"method": ["POST"],
+		"path": ["/greeter/hi"],
+		"handler": "rpc",
+		"service": "go.micro.srv.greeter",
+		"endpoint": "Greeter.Hello"
+	}, {
+		"name": "broken",
+		"method": ["GET"],
+		"path": ["/broken"],
+		"handler": "unknown",
+		"service": "go.micro.srv.greeter"
+	}]}`)
+	time.Sleep(time.Millisecond * 100)
+	if err := route("/greeter/hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	update(`{"routes": [{
+		"name": "hello",
+		"method": ["POST"],
+		"path": ["/greeter/hi"],
+		"handler": "rpc",
+		"service": "go.micro.srv.greeter",
+		"endpoint": "Greeter.Hello",
+		"timeout": "5s"
+	}]}`)
+
+	var err2 error
+	for i := 0; i < 50; i++ {
+		if err2 = route("/greeter/hi"); err2 == nil {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if err2 != nil {
+		t.Fatal(err2)
+	}
+	if err := route("/greeter/hello"); err == nil {
+		t.Fatal("expected the old route to be removed")
+	}
+}
+
+func TestLoadConfigTwice(t *testing.T) {
+	reg := registry.NewMemoryRegistry()
+	if err := reg.Register(&registry.Service{
+		Name:  "go.micro.srv.greeter",
+		Nodes: []*registry.Node{{Id: "1", Address: "127.0.0.1:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	routes := func(path string) []byte {
+		return []byte(`{"routes": [{
+			"method": ["POST"],
+			"path": ["` + path + `"],
+			"handler": "rpc",
+			"service": "go.micro.srv.greeter",
+			"endpoint": "Greeter.Hello"
+		}]}`)
+	}
+
+	load := func(src source.Source) config.Config {
+		c, err := config.NewConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Load(src); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	first := memory.NewSource(memory.WithJSON(routes("/first")))
+	second := memory.NewSource(memory.WithJSON(routes("/second")))
+	c1, c2 := load(first), load(second)
+	defer c1.Close()
+	defer c2.Close()
+
+	r := NewRouter(router.WithRegistry(reg))
+	defer r.Close()
+
+	if err := r.LoadConfig(c1, "routes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadConfig(c2, "routes"); err != nil {
+		t.Fatal(err)
+	}
+
+	// changes to the config loaded before are no longer watched
+	if err := first.Write(&source.ChangeSet{Data: routes("/changed"), Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 100)
+
+	req, _ := http.NewRequest("POST", "http://localhost/second", nil)
+	if _, err := r.Route(req); err != nil {
+		t.Fatalf("expected the routes of the last config, got %v", err)
+	}
+}
+
+func TestLoadHandler(t *testing.T) {
+	route := &Route{
+		Method:  []string{"GET"},
+		Path:    []string{"/greeter"},
+		Handler: "http",
+		Service: "go.micro.web.greeter",
+	}
+
+	// routes of another type than that of the router's handler are rejected
+	r := NewRouter(router.WithHandler("rpc"))
+	defer r.Close()
+	if err := r.Load([]*Route{route}); err == nil {
+		t.Fatal("expected the http route to be rejected by an rpc router")
+	}
+
+	r = NewRouter(router.WithHandler("http"))
+	defer r.Close()
+	if err := r.Load([]*Route{route}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	reg := registry.NewMemoryRegistry()
+	for _, name := range []string{"go.micro.srv.greeter", "go.micro.web.greeter"} {
+		if err := reg.Register(&registry.Service{
+			Name:  name,
+			Nodes: []*registry.Node{{Id: "1", Address: "127.0.0.1:8080"}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewRouter(router.WithRegistry(reg))
+	defer r.Close()
+
+	if err := r.Load([]*Route{
+		{
+			Method:   []string{"POST"},
+			Path:     []string{"/rpc"},
+			Handler:  "rpc",
+			Service:  "go.micro.srv.greeter",
+			Endpoint: "Greeter.Hello",
+		},
+		{
+			Method:  []string{"GET"},
+			Path:    []string{"/web"},
+			Handler: "http",
+			Service: "go.micro.web.greeter",
+			Timeout: "5s",
+		},
+		{
+			Method:  []string{"GET"},
+			Path:    []string{"/event"},
+			Handler: "event",
+			Service: "go.micro.srv.greeter",
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var served string
+	var deadline bool
+	serve := func(typ string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			served = typ
+			_, deadline = req.Context().Deadline()
+		})
+	}
+	h := NewHandler(r, map[string]http.Handler{
+		"rpc":  serve("rpc"),
+		"http": serve("http"),
+	})
+
+	testData := []struct {
+		method, path, handler string
+		deadline              bool
+		code                  int
+	}{
+		{"POST", "/rpc", "rpc", false, 200},
+		{"GET", "/web", "http", true, 200},
+		{"GET", "/event", "", false, 500},
+		{"GET", "/none", "", false, 500},
+	}
+
+	for _, d := range testData {
+		served, deadline = "", false
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(d.method, d.path, nil))
+		if w.Code != d.code || served != d.handler || deadline != d.deadline {
+			t.Fatalf("%s %s: expected %d served by %q with deadline %v, got %d by %q with deadline %v",
+				d.method, d.path, d.code, d.handler, d.deadline, w.Code, served, deadline)
+		}
+	}
+}
@@ -18,7 +18,9 @@ import (
 )
 
 type endpoint struct {
-	apiep    *api.Endpoint
+	apiep *api.Endpoint
+	// service the endpoint belongs to, set for routes loaded from config
+	service  string
 	hostregs []*regexp.Regexp
 	pathregs []util.Pattern
 	pcreregs []*regexp.Regexp
@@ -30,6 +32,10 @@ type staticRouter struct {
 	opts router.Options
 	sync.RWMutex
 	eps map[string]*endpoint
+	// routes loaded from config, swapped as a whole on reload
+	routes []*endpoint
+	// stops watching the config of the routes
+	stop chan bool
 }
 
 func (r *staticRouter) isClosed() bool {
@@ -89,10 +95,23 @@ func (r *staticRouter) watch() {
 */
 
 func (r *staticRouter) Register(ep *api.Endpoint) error {
-	if err := api.Validate(ep); err != nil {
+	e, err := compile(ep)
+	if err != nil {
 		return err
 	}
 
+	r.Lock()
+	r.eps[ep.Name] = e
+	r.Unlock()
+	return nil
+}
+
+// compile validates the endpoint and compiles its host and path patterns
+func compile(ep *api.Endpoint) (*endpoint, error) {
+	if err := api.Validate(ep); err != nil {
+		return nil, err
+	}
+
 	var pathregs []util.Pattern
 	var hostregs []*regexp.Regexp
 	var pcreregs []*regexp.Regexp
@@ -103,7 +122,7 @@ func (r *staticRouter) Register(ep *api.Endpoint) error {
 		}
 		hostreg, err := regexp.CompilePOSIX(h)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		hostregs = append(hostregs, hostreg)
 	}
@@ -122,7 +141,7 @@ func (r *staticRouter) Register(ep *api.Endpoint) error {
 
 		rule, err := util.Parse(p)
 		if err != nil && !pcreok {
-			return err
+			return nil, err
 		} else if err != nil && pcreok {
 			continue
 		}
@@ -130,20 +149,17 @@ func (r *staticRouter) Register(ep *api.Endpoint) error {
 		tpl := rule.Compile()
 		pathreg, err := util.NewPattern(tpl.Version, tpl.OpCodes, tpl.Pool, "")
 		if err != nil {
-			return err
+			return nil, err
 		}
 		pathregs = append(pathregs, pathreg)
 	}
 
-	r.Lock()
-	r.eps[ep.Name] = &endpoint{
+	return &endpoint{
 		apiep:    ep,
 		pcreregs: pcreregs,
 		pathregs: pathregs,
 		hostregs: hostregs,
-	}
-	r.Unlock()
-	return nil
+	}, nil
 }
 
 func (r *staticRouter) Deregister(ep *api.Endpoint) error {
@@ -176,8 +192,21 @@ func (r *staticRouter) Endpoint(req *http.Request) (*api.Service, error) {
 		return nil, err
 	}
 
-	epf := strings.Split(ep.apiep.Name, ".")
-	services, err := r.opts.Registry.GetService(epf[0])
+	// aggregate endpoints call the services of each call
+	if ep.apiep.Handler == "aggregate" {
+		return &api.Service{
+			Name:     ep.service,
+			Endpoint: ep.apiep,
+		}, nil
+	}
+
+	name, endpoint, handler := ep.service, ep.apiep.Name, ep.apiep.Handler
+	if len(name) == 0 {
+		epf := strings.Split(ep.apiep.Name, ".")
+		name, endpoint, handler = epf[0], strings.Join(epf[1:], "."), "rpc"
+	}
+
+	services, err := r.opts.Registry.GetService(name)
 	if err != nil {
 		return nil, err
 	}
@@ -188,13 +217,13 @@ func (r *staticRouter) Endpoint(req *http.Request) (*api.Service, error) {
 		for _, svc := range svcs {
 			if len(svc.Endpoints) == 0 {
 				e := &registry.Endpoint{}
-				e.Name = strings.Join(epf[1:], ".")
+				e.Name = endpoint
 				e.Metadata = make(map[string]string)
 				e.Metadata["stream"] = "true"
 				svc.Endpoints = append(svc.Endpoints, e)
 			}
 			for _, e := range svc.Endpoints {
-				e.Name = strings.Join(epf[1:], ".")
+				e.Name = endpoint
 				e.Metadata = make(map[string]string)
 				e.Metadata["stream"] = "true"
 			}
@@ -204,15 +233,19 @@ func (r *staticRouter) Endpoint(req *http.Request) (*api.Service, error) {
 	}
 
 	svc := &api.Service{
-		Name: epf[0],
+		Name: name,
 		Endpoint: &api.Endpoint{
-			Name:    strings.Join(epf[1:], "."),
-			Handler: "rpc",
+			Name:    endpoint,
+			Handler: handler,
 			Host:    ep.apiep.Host,
 			Method:  ep.apiep.Method,
 			Path:    ep.apiep.Path,
 			Body:    ep.apiep.Body,
 			Stream:  ep.apiep.Stream,
+			Timeout: ep.apiep.Timeout,
+			Cache:   ep.apiep.Cache,
+
+			Aggregate: ep.apiep.Aggregate,
 		},
 		Services: services,
 	}
@@ -237,96 +270,110 @@ func (r *staticRouter) endpoint(req *http.Request) (*endpoint, error) {
 	// TODO: weighted matching
 
 	for _, ep := range r.eps {
-		var mMatch, hMatch, pMatch bool
-
-		// 1. try method
-		for _, m := range ep.apiep.Method {
-			if m == req.Method {
-				mMatch = true
-				break
-			}
+		if r.match(ep, req, path) {
+			return ep, nil
 		}
-		if !mMatch {
-			continue
+	}
+
+	// routes loaded from config are tried in order
+	for _, ep := range r.routes {
+		if r.match(ep, req, path) {
+			return ep, nil
 		}
-		if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-			logger.Debugf("api method match %s", req.Method)
+	}
+
+	// no match
+	return nil, fmt.Errorf("endpoint not found for %v", req.URL)
+}
+
+// match checks the method, host and path of the request against the endpoint
+func (r *staticRouter) match(ep *endpoint, req *http.Request, path []string) bool {
+	var mMatch, hMatch, pMatch bool
+
+	// 1. try method
+	for _, m := range ep.apiep.Method {
+		if m == req.Method {
+			mMatch = true
+			break
 		}
+	}
+	if !mMatch {
+		return false
+	}
+	if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+		logger.Debugf("api method match %s", req.Method)
+	}
 
-		// 2. try host
-		if len(ep.apiep.Host) == 0 {
-			hMatch = true
-		} else {
-			for idx, h := range ep.apiep.Host {
-				if h == "" || h == "*" {
+	// 2. try host
+	if len(ep.apiep.Host) == 0 {
+		hMatch = true
+	} else {
+		for idx, h := range ep.apiep.Host {
+			if h == "" || h == "*" {
+				hMatch = true
+				break
+			} else {
+				if ep.hostregs[idx].MatchString(req.URL.Host) {
 					hMatch = true
 					break
-				} else {
-					if ep.hostregs[idx].MatchString(req.URL.Host) {
-						hMatch = true
-						break
-					}
 				}
 			}
 		}
-		if !hMatch {
+	}
+	if !hMatch {
+		return false
+	}
+	if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+		logger.Debugf("api host match %s", req.URL.Host)
+	}
+
+	// 3. try google.api path
+	for _, pathreg := range ep.pathregs {
+		matches, err := pathreg.Match(path, "")
+		if err != nil {
+			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+				logger.Debugf("api gpath not match %s != %v", path, pathreg)
+			}
 			continue
 		}
 		if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-			logger.Debugf("api host match %s", req.URL.Host)
+			logger.Debugf("api gpath match %s = %v", path, pathreg)
+		}
+		pMatch = true
+		ctx := req.Context()
+		md, ok := metadata.FromContext(ctx)
+		if !ok {
+			md = make(metadata.Metadata)
 		}
+		for k, v := range matches {
+			md[fmt.Sprintf("x-api-field-%s", k)] = v
+		}
+		md["x-api-body"] = ep.apiep.Body
+		*req = *req.Clone(metadata.NewContext(ctx, md))
+		break
+	}
 
-		// 3. try google.api path
-		for _, pathreg := range ep.pathregs {
-			matches, err := pathreg.Match(path, "")
-			if err != nil {
+	if !pMatch {
+		// 4. try path via pcre path matching
+		for _, pathreg := range ep.pcreregs {
+			if !pathreg.MatchString(req.URL.Path) {
 				if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-					logger.Debugf("api gpath not match %s != %v", path, pathreg)
+					logger.Debugf("api pcre path not match %s != %v", req.URL.Path, pathreg)
 				}
 				continue
 			}
-			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-				logger.Debugf("api gpath match %s = %v", path, pathreg)
-			}
 			pMatch = true
-			ctx := req.Context()
-			md, ok := metadata.FromContext(ctx)
-			if !ok {
-				md = make(metadata.Metadata)
-			}
-			for k, v := range matches {
-				md[fmt.Sprintf("x-api-field-%s", k)] = v
-			}
-			md["x-api-body"] = ep.apiep.Body
-			*req = *req.Clone(metadata.NewContext(ctx, md))
 			break
 		}
+	}
 
-		if !pMatch {
-			// 4. try path via pcre path matching
-			for _, pathreg := range ep.pcreregs {
-				if !pathreg.MatchString(req.URL.Path) {
-					if logger.V(logger.DebugLevel, logger.DefaultLogger) {
-						logger.Debugf("api pcre path not match %s != %v", req.URL.Path, pathreg)
-					}
-					continue
-				}
-				pMatch = true
-				break
-			}
-		}
-
-		if !pMatch {
-			continue
-		}
-		// TODO: Percentage traffic
-
-		// we got here, so its a match
-		return ep, nil
+	if !pMatch {
+		return false
 	}
+	// TODO: Percentage traffic
 
-	// no match
-	return nil, fmt.Errorf("endpoint not found for %v", req.URL)
+	// we got here, so its a match
+	return true
 }
 
 func (r *staticRouter) Route(req *http.Request) (*api.Service, error) {
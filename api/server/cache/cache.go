@@ -0,0 +1,324 @@
+// Package cache provides an api gateway wrapper which caches responses to
+// GET requests and answers conditional requests using ETags
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asim/go-micro/v3/broker"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/logger"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// entry is a cached response
+type entry struct {
+	path   string
+	status int
+	header http.Header
+	body   []byte
+	etag   string
+}
+
+// Cache of responses
+type Cache struct {
+	opts  Options
+	cache *gocache.Cache
+}
+
+// recorder buffers the response of a handler
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+}
+
+// Wrap the handler, it can be passed to server.WrapHandler
+func (c *Cache) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cacheable(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		key := c.key(r)
+
+		// the client may ask for a fresh response
+		if !directives(r.Header)["no-cache"] {
+			if v, ok := c.cache.Get(key); ok {
+				write(w, r, v.(*entry), "HIT")
+				return
+			}
+		}
+
+		rec := &recorder{header: make(http.Header)}
+		h.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		e := &entry{
+			path:   r.URL.Path,
+			status: rec.status,
+			header: rec.header,
+			body:   rec.body.Bytes(),
+			etag:   rec.header.Get("ETag"),
+		}
+		if len(e.etag) == 0 && e.status == http.StatusOK {
+			sum := sha256.Sum256(e.body)
+			e.etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		}
+
+		if e.status == http.StatusOK && (!credentials(r) || shared(rec.header)) && c.storable(rec.header) {
+			if ttl := c.ttl(r, rec.header); ttl > 0 {
+				c.cache.Set(key, e, ttl)
+			}
+		}
+
+		write(w, r, e, "MISS")
+	})
+}
+
+// Purge the responses for paths with the prefix, an empty prefix
+// purges everything. The number of responses purged is returned.
+func (c *Cache) Purge(prefix string) int {
+	var n int
+	for k, v := range c.cache.Items() {
+		if e, ok := v.Object.(*entry); ok && strings.HasPrefix(e.path, prefix) {
+			c.cache.Delete(k)
+			n++
+		}
+	}
+	return n
+}
+
+// ServeHTTP purges the responses for the path prefix in the query, e.g.
+// DELETE /cache?path=/greeter with the PurgeToken as a bearer token.
+// Purging is forbidden unless the token is set.
+func (c *Cache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if len(c.opts.PurgeToken) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(c.opts.PurgeToken)) != 1 {
+		ce := errors.Forbidden("go.micro.api", "not allowed to purge the cache")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(ce.Error()))
+		return
+	}
+	if r.Method != "DELETE" && r.Method != "POST" {
+		w.Header().Set("Allow", "DELETE, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	n := c.Purge(r.URL.Query().Get("path"))
+	b, _ := json.Marshal(map[string]int{"purged": n})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// key of the request made up of the route, query, selected headers and credentials
+func (c *Cache) key(r *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.Host + r.URL.Path + "\n"))
+	// encode sorts the query by key
+	h.Write([]byte(r.URL.Query().Encode() + "\n"))
+	for _, k := range c.opts.Headers {
+		h.Write([]byte(k + ": " + r.Header.Get(k) + "\n"))
+	}
+	// responses to requests with credentials are never shared between them
+	for _, k := range []string{"Authorization", "Cookie"} {
+		h.Write([]byte(k + ": " + strings.Join(r.Header.Values(k), "; ") + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// credentials checks whether the request carries credentials
+func credentials(r *http.Request) bool {
+	return len(r.Header.Get("Authorization")) > 0 || len(r.Header.Get("Cookie")) > 0
+}
+
+// shared checks whether the response may be cached for requests with
+// credentials, which it must explicitly allow as in RFC 7234 section 3.2
+func shared(hdr http.Header) bool {
+	d := directives(hdr)
+	return d["public"] || d["s-maxage"]
+}
+
+// storable checks the response can be replayed to other requests with the
+// same key, it mustn't set cookies or vary by headers which aren't keyed
+func (c *Cache) storable(hdr http.Header) bool {
+	if len(hdr.Values("Set-Cookie")) > 0 {
+		return false
+	}
+
+	keyed := map[string]bool{"Authorization": true, "Cookie": true}
+	for _, k := range c.opts.Headers {
+		keyed[http.CanonicalHeaderKey(k)] = true
+	}
+	for _, v := range hdr.Values("Vary") {
+		for _, k := range strings.Split(v, ",") {
+			k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+			if len(k) > 0 && !keyed[k] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ttl of a response, from its Cache-Control header, the route or the default
+func (c *Cache) ttl(r *http.Request, hdr http.Header) time.Duration {
+	if len(hdr.Get("Cache-Control")) > 0 {
+		d := directives(hdr)
+		if d["no-store"] || d["no-cache"] || d["private"] {
+			return 0
+		}
+		for _, k := range []string{"s-maxage", "max-age"} {
+			if v, ok := maxAge(hdr, k); ok {
+				return v
+			}
+		}
+	}
+
+	if c.opts.Router != nil {
+		if svc, err := c.opts.Router.Route(r.Clone(r.Context())); err == nil && svc.Endpoint.Cache > 0 {
+			return svc.Endpoint.Cache
+		}
+	}
+
+	return c.opts.TTL
+}
+
+// cacheable checks the request is a GET which isn't for a stream
+func cacheable(r *http.Request) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	if strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+		return false
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return false
+	}
+	return !directives(r.Header)["no-store"]
+}
+
+// directives of the Cache-Control header
+func directives(hdr http.Header) map[string]bool {
+	d := make(map[string]bool)
+	for _, v := range strings.Split(hdr.Get("Cache-Control"), ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); len(v) > 0 {
+			d[strings.SplitN(v, "=", 2)[0]] = true
+		}
+	}
+	return d
+}
+
+// maxAge parses a Cache-Control directive like max-age=60
+func maxAge(hdr http.Header, name string) (time.Duration, bool) {
+	for _, v := range strings.Split(hdr.Get("Cache-Control"), ",") {
+		parts := strings.SplitN(strings.TrimSpace(v), "=", 2)
+		if len(parts) != 2 || strings.ToLower(parts[0]) != name {
+			continue
+		}
+		n, err := strconv.Atoi(strings.Trim(parts[1], `"`))
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(n) * time.Second, true
+	}
+	return 0, false
+}
+
+// match checks the If-None-Match header against the etag
+func match(r *http.Request, etag string) bool {
+	if len(etag) == 0 {
+		return false
+	}
+	for _, v := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// write the response, answering conditional requests with 304
+func write(w http.ResponseWriter, r *http.Request, e *entry, status string) {
+	for k, v := range e.header {
+		w.Header()[k] = v
+	}
+	if len(e.etag) > 0 {
+		w.Header().Set("ETag", e.etag)
+	}
+	w.Header().Set("X-Cache", status)
+
+	if e.status == http.StatusOK && match(r, e.etag) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(e.status)
+	if r.Method == "HEAD" {
+		return
+	}
+	if _, err := w.Write(e.body); err != nil {
+		if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+			logger.Debugf("error writing cached response: %v", err)
+		}
+	}
+}
+
+// NewCache returns a cache of api responses
+func NewCache(opts ...Option) *Cache {
+	options := Options{
+		Topic: DefaultTopic,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c := &Cache{
+		opts:  options,
+		cache: gocache.New(gocache.NoExpiration, 30*time.Second),
+	}
+
+	if options.Broker != nil {
+		if _, err := options.Broker.Subscribe(options.Topic, func(e broker.Event) error {
+			c.Purge(string(e.Message().Body))
+			return nil
+		}); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("error subscribing to cache purge topic %s: %v", options.Topic, err)
+			}
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/broker"
+	"github.com/asim/go-micro/v3/registry"
+)
+
+func TestCache(t *testing.T) {
+	var calls int
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		fmt.Fprintf(w, `{"calls":%d}`, calls)
+	})
+
+	c := NewCache(Headers("Accept-Language"), PurgeToken("secret"))
+	srv := c.Wrap(h)
+
+	get := func(path string, hdr map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		for k, v := range hdr {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/foo?a=1&b=2", nil)
+	if w.Header().Get("X-Cache") != "MISS" || w.Body.String() != `{"calls":1}` {
+		t.Fatalf("unexpected response %v %s", w.Header(), w.Body)
+	}
+	etag := w.Header().Get("ETag")
+	if len(etag) == 0 {
+		t.Fatal("expected an etag")
+	}
+
+	// the query order doesn't matter
+	w = get("/foo?b=2&a=1", nil)
+	if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != `{"calls":1}` {
+		t.Fatalf("unexpected response %v %s", w.Header(), w.Body)
+	}
+
+	w = get("/foo?a=1&b=2", map[string]string{"If-None-Match": etag})
+	if w.Code != http.StatusNotModified || w.Body.Len() > 0 {
+		t.Fatalf("expected 304 got %d %s", w.Code, w.Body)
+	}
+
+	// selected headers are part of the key
+	if w := get("/foo?a=1&b=2", map[string]string{"Accept-Language": "fr"}); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("expected a miss for another language")
+	}
+
+	// private responses aren't cached
+	get("/private", nil)
+	if w := get("/private", nil); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("expected private responses not to be cached")
+	}
+
+	// purging requires the token
+	r := httptest.NewRequest("DELETE", "/cache?path=/foo", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", rw.Code)
+	}
+
+	// purge via the admin handler
+	r = httptest.NewRequest("DELETE", "/cache?path=/foo", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Body.String() != `{"purged":2}` {
+		t.Fatalf("unexpected purge response %s", rw.Body)
+	}
+	if w := get("/foo?a=1&b=2", nil); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("expected a miss after purging")
+	}
+}
+
+func TestCacheCredentials(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/public" {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		}
+		fmt.Fprintf(w, `{"user":%q}`, r.Header.Get("Authorization"))
+	})
+
+	srv := NewCache(TTL(time.Minute)).Wrap(h)
+
+	get := func(path, user string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if len(user) > 0 {
+			r.Header.Set("Authorization", user)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+
+	// responses to requests with credentials aren't cached unless public
+	get("/foo", "alice")
+	if w := get("/foo", "alice"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("expected responses with credentials not to be cached")
+	}
+	if w := get("/foo", "bob"); w.Body.String() != `{"user":"bob"}` {
+		t.Fatalf("expected the response of bob got %s", w.Body)
+	}
+
+	// nor shared between users when they are
+	get("/public", "alice")
+	if w := get("/public", "alice"); w.Header().Get("X-Cache") != "HIT" {
+		t.Fatal("expected the public response to be cached")
+	}
+	if w := get("/public", "bob"); w.Header().Get("X-Cache") != "MISS" || w.Body.String() != `{"user":"bob"}` {
+		t.Fatalf("expected the response of bob got %s", w.Body)
+	}
+	if w := get("/public", ""); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("expected the response of alice not to be served without credentials")
+	}
+}
+
+func TestCacheStorable(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cookie":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "alice"})
+		case "/vary":
+			w.Header().Set("Vary", "Accept-Encoding")
+		case "/keyed":
+			w.Header().Set("Vary", "accept-language")
+		case "/any":
+			w.Header().Set("Vary", "*")
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	srv := NewCache(TTL(time.Minute), Headers("Accept-Language")).Wrap(h)
+
+	testData := map[string]string{
+		"/cookie": "MISS",
+		"/vary":   "MISS",
+		"/any":    "MISS",
+		"/keyed":  "HIT",
+		"/plain":  "HIT",
+	}
+
+	for path, cache := range testData {
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if i == 1 && w.Header().Get("X-Cache") != cache {
+				t.Fatalf("%s: expected %s got %s", path, cache, w.Header().Get("X-Cache"))
+			}
+		}
+	}
+}
+
+func TestPurgeTopic(t *testing.T) {
+	b := broker.NewBroker(broker.Registry(registry.NewMemoryRegistry()))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	c := NewCache(Broker(b), TTL(time.Minute))
+	srv := c.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo", nil))
+	if c.cache.ItemCount() != 1 {
+		t.Fatal("expected the response to be cached")
+	}
+
+	if err := b.Publish(DefaultTopic, &broker.Message{Body: []byte("/foo")}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && c.cache.ItemCount() > 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if c.cache.ItemCount() != 0 {
+		t.Fatal("expected the response to be purged")
+	}
+}
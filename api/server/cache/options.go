@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/asim/go-micro/v3/api/router"
+	"github.com/asim/go-micro/v3/broker"
+)
+
+var (
+	// DefaultTopic is the broker topic purge requests are published to
+	DefaultTopic = "go.micro.api.cache.purge"
+)
+
+type Options struct {
+	// TTL of responses when neither the route nor the service set one
+	TTL time.Duration
+	// Headers of the request which are part of the cache key
+	Headers []string
+	// Router used to look up the cache duration of a route
+	Router router.Router
+	// Broker purge requests are received on
+	Broker broker.Broker
+	// Topic purge requests are published to
+	Topic string
+	// PurgeToken required as a bearer token to purge via ServeHTTP
+	PurgeToken string
+}
+
+type Option func(o *Options)
+
+// TTL of responses when neither the route nor the service set one.
+// The default of zero only caches responses which ask to be.
+func TTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = d
+	}
+}
+
+// Headers of the request to include in the cache key e.g. Accept-Language
+func Headers(h ...string) Option {
+	return func(o *Options) {
+		o.Headers = append(o.Headers, h...)
+	}
+}
+
+// Router used to look up the cache duration of the route a request is for
+func Router(r router.Router) Option {
+	return func(o *Options) {
+		o.Router = r
+	}
+}
+
+// Broker to receive purge requests on. The body of a message published
+// to the topic is the path prefix to purge, empty purges everything.
+func Broker(b broker.Broker) Option {
+	return func(o *Options) {
+		o.Broker = b
+	}
+}
+
+// Topic purge requests are published to
+func Topic(t string) Option {
+	return func(o *Options) {
+		o.Topic = t
+	}
+}
+
+// PurgeToken is the bearer token required to purge responses via
+// ServeHTTP, without one the handler forbids purging
+func PurgeToken(t string) Option {
+	return func(o *Options) {
+		o.PurgeToken = t
+	}
+}
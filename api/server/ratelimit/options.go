@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/asim/go-micro/v3/auth"
+	"github.com/asim/go-micro/v3/config"
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/store"
+	"github.com/asim/go-micro/v3/sync"
+)
+
+type Options struct {
+	// Store the counters are kept in, shared by replicas
+	Store store.Store
+	// Limits applied to requests
+	Limits []*Limit
+	// Config the limits are loaded from and watched
+	Config config.Config
+	// Path of the limits in the config
+	Path []string
+	// Header carrying the api key
+	Header string
+	// Prefix of the store keys
+	Prefix string
+	// Sync locking the counters across replicas, without it replicas
+	// updating the same counter at once may both take the last token
+	Sync sync.Sync
+	// TrustedProxies X-Forwarded-For is read from to limit by ip
+	TrustedProxies []*net.IPNet
+	// Auth inspecting bearer tokens to limit by account
+	Auth auth.Auth
+	// Context the config is watched until it's done
+	Context context.Context
+}
+
+type Option func(o *Options)
+
+// Store the counters are kept in
+func Store(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Limits to apply
+func Limits(l ...*Limit) Option {
+	return func(o *Options) {
+		o.Limits = append(o.Limits, l...)
+	}
+}
+
+// Config the limits are loaded from at the given path. The limits
+// are reloaded whenever the config changes.
+func Config(c config.Config, path ...string) Option {
+	return func(o *Options) {
+		o.Config = c
+		o.Path = path
+	}
+}
+
+// Header carrying the api key, defaults to X-Api-Key
+func Header(h string) Option {
+	return func(o *Options) {
+		o.Header = h
+	}
+}
+
+// Prefix of the keys written to the store, defaults to ratelimit/
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// Sync locks the counters across gateway replicas while they're updated
+func Sync(s sync.Sync) Option {
+	return func(o *Options) {
+		o.Sync = s
+	}
+}
+
+// TrustedProxies are the addresses or cidr ranges of the proxies in front of
+// the gateway. The ip of a request from one of them is read from X-Forwarded-For,
+// otherwise the header is ignored since clients can set it to anything.
+func TrustedProxies(proxies ...string) Option {
+	return func(o *Options) {
+		for _, p := range proxies {
+			if !strings.Contains(p, "/") {
+				if strings.Contains(p, ":") {
+					p += "/128"
+				} else {
+					p += "/32"
+				}
+			}
+			_, n, err := net.ParseCIDR(p)
+			if err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("ratelimit: invalid trusted proxy %s: %v", p, err)
+				}
+				continue
+			}
+			o.TrustedProxies = append(o.TrustedProxies, n)
+		}
+	}
+}
+
+// Auth inspects the bearer token of requests without an account in their
+// context, for limits applied by account
+func Auth(a auth.Auth) Option {
+	return func(o *Options) {
+		o.Auth = a
+	}
+}
+
+// Context the config of the limits is watched until it's done
+func Context(ctx context.Context) Option {
+	return func(o *Options) {
+		o.Context = ctx
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Store:   store.DefaultStore,
+		Header:  "X-Api-Key",
+		Prefix:  "ratelimit/",
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
@@ -0,0 +1,615 @@
+// Package ratelimit provides an api gateway wrapper which applies token bucket
+// limits and daily or monthly quotas keyed by api key, account, ip or route.
+// The counters are kept in a store so they're shared by gateway replicas, and
+// updated atomically across them when a distributed sync is set with Sync.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/v3/api/server"
+	"github.com/asim/go-micro/v3/auth"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/store"
+)
+
+// Keys a limit can be applied by
+const (
+	KeyIP      = "ip"
+	KeyAccount = "account"
+	KeyAPIKey  = "key"
+	KeyRoute   = "route"
+)
+
+// Limit is a rate limit and quota as defined in config, e.g.
+//
+//	limits:
+//	- key: ip
+//	  requests: 10
+//	  period: 1s
+//	- key: key
+//	  path: /v1/
+//	  requests: 100
+//	  period: 1m
+//	  daily: 10000
+type Limit struct {
+	// Name of the limit, the counters of each limit are kept separately
+	Name string `json:"name"`
+	// Key the limit is applied by; ip, account, key or route
+	Key string `json:"key"`
+	// Path prefix and methods the limit applies to, empty for all requests
+	Path   string   `json:"path"`
+	Method []string `json:"method"`
+	// Requests allowed per period, zero only applies the quotas
+	Requests int `json:"requests"`
+	// Period the requests are allowed in e.g. 1m, defaults to 1s
+	Period string `json:"period"`
+	// Burst of requests allowed, defaults to the requests
+	Burst int `json:"burst"`
+	// Daily and Monthly quotas, zero for none
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// limit is a validated Limit
+type limit struct {
+	*Limit
+	// tokens added per second
+	rate float64
+	// size of the bucket
+	burst float64
+}
+
+// bucket is the token bucket state kept in the store
+type bucket struct {
+	Tokens  float64 `json:"tokens"`
+	Updated int64   `json:"updated"`
+}
+
+// result of taking a request from a limit
+type result struct {
+	allowed   bool
+	limit     int
+	remaining int
+	reset     time.Duration
+	retry     time.Duration
+	reason    string
+}
+
+// stripes of the locks of the counters kept by this process
+const stripes = 64
+
+type limiter struct {
+	opts Options
+
+	// serialise updates of the counters made by this process,
+	// striped by key so unrelated counters are updated concurrently
+	locks [stripes]sync.Mutex
+
+	mtx    sync.RWMutex
+	limits []*limit
+}
+
+// taken is a request taken from a limit, written to the store once
+// every limit the request matches allows it
+type taken struct {
+	lim    *limit
+	key    string
+	res    *result
+	writes []*store.Record
+}
+
+func compile(limits []*Limit) ([]*limit, error) {
+	out := make([]*limit, 0, len(limits))
+	for _, l := range limits {
+		switch l.Key {
+		case KeyIP, KeyAccount, KeyAPIKey, KeyRoute:
+		default:
+			return nil, fmt.Errorf("limit %s: invalid key %q", l.Name, l.Key)
+		}
+		if l.Requests < 0 || l.Burst < 0 || l.Daily < 0 || l.Monthly < 0 {
+			return nil, fmt.Errorf("limit %s: negative limit", l.Name)
+		}
+		if l.Requests == 0 && l.Daily == 0 && l.Monthly == 0 {
+			return nil, fmt.Errorf("limit %s: requests or quota required", l.Name)
+		}
+
+		period := time.Second
+		if len(l.Period) > 0 {
+			p, err := time.ParseDuration(l.Period)
+			if err != nil || p <= 0 {
+				return nil, fmt.Errorf("limit %s: invalid period %q", l.Name, l.Period)
+			}
+			period = p
+		}
+
+		c := &limit{Limit: l}
+		if len(c.Name) == 0 {
+			c.Limit = &Limit{}
+			*c.Limit = *l
+			c.Name = l.Key + ":" + l.Path
+		}
+		if l.Requests > 0 {
+			c.rate = float64(l.Requests) / period.Seconds()
+			c.burst = float64(l.Requests)
+			if l.Burst > 0 {
+				c.burst = float64(l.Burst)
+			}
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// load replaces the limits read from config, leaving the current ones in place if any are invalid
+func (l *limiter) load(limits []*Limit) error {
+	all := make([]*Limit, 0, len(l.opts.Limits)+len(limits))
+	all = append(all, l.opts.Limits...)
+	c, err := compile(append(all, limits...))
+	if err != nil {
+		return err
+	}
+	l.mtx.Lock()
+	l.limits = c
+	l.mtx.Unlock()
+	return nil
+}
+
+// watch the config for limit changes until the context is done
+func (l *limiter) watch() {
+	var attempts int
+	done := l.opts.Context.Done()
+
+	for {
+		w, err := l.opts.Config.Watch(l.opts.Path...)
+		if err != nil {
+			attempts++
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("error watching rate limits: %v", err)
+			}
+			select {
+			case <-time.After(time.Duration(attempts) * time.Second):
+				continue
+			case <-done:
+				return
+			}
+		}
+
+		ch := make(chan bool)
+		go func() {
+			select {
+			case <-ch:
+			case <-done:
+			}
+			w.Stop()
+		}()
+
+		// reset if we get here
+		attempts = 0
+
+		for {
+			v, err := w.Next()
+			if err != nil {
+				close(ch)
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("error getting next rate limits: %v", err)
+				}
+				break
+			}
+
+			var limits []*Limit
+			if err := v.Scan(&limits); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("error reading rate limits: %v", err)
+				}
+				continue
+			}
+			if err := l.load(limits); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("error loading rate limits, keeping current limits: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// key returns the value the limit is applied by, empty if the request has none
+func (l *limiter) key(lim *limit, r *http.Request) string {
+	switch lim.Key {
+	case KeyIP:
+		return l.ip(r)
+	case KeyAccount:
+		if acc, _ := auth.AccountFromContext(r.Context()); acc != nil {
+			return acc.ID
+		}
+	case KeyAPIKey:
+		return r.Header.Get(l.opts.Header)
+	case KeyRoute:
+		return r.Method + " " + r.URL.Path
+	}
+	return ""
+}
+
+// trusted checks whether the ip is that of a trusted proxy
+func (l *limiter) trusted(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, n := range l.opts.TrustedProxies {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ip of the client. X-Forwarded-For is only read when the request comes from a
+// trusted proxy, the client being the last address not of a trusted proxy.
+func (l *limiter) ip(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	if !l.trusted(ip) {
+		return ip
+	}
+
+	var forwarded []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(v, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		f := strings.TrimSpace(forwarded[i])
+		if len(f) == 0 {
+			continue
+		}
+		ip = f
+		if !l.trusted(f) {
+			break
+		}
+	}
+	return ip
+}
+
+// match checks whether the limit applies to the request
+func match(lim *limit, r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, lim.Path) {
+		return false
+	}
+	if len(lim.Method) == 0 {
+		return true
+	}
+	for _, m := range lim.Method {
+		if strings.EqualFold(m, r.Method) {
+			return true
+		}
+	}
+	return false
+}
+
+// allow takes the request from every limit it matches, the result is that of
+// the limit denying the request or with the least remaining. Nothing is taken
+// unless every limit allows the request.
+func (l *limiter) allow(r *http.Request) *result {
+	l.mtx.RLock()
+	limits := l.limits
+	l.mtx.RUnlock()
+
+	var pending []*taken
+	for _, lim := range limits {
+		if !match(lim, r) {
+			continue
+		}
+		id := l.key(lim, r)
+		if len(id) == 0 {
+			continue
+		}
+		pending = append(pending, &taken{lim: lim, key: l.opts.Prefix + lim.Name + "/" + id})
+	}
+	if len(pending) == 0 {
+		return &result{allowed: true}
+	}
+
+	keys := make([]string, len(pending))
+	for i, t := range pending {
+		keys[i] = t.key
+	}
+	unlock, err := l.lock(keys)
+	if err != nil {
+		// fail open rather than take the gateway down with the lock
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("error locking rate limits: %v", err)
+		}
+		return &result{allowed: true}
+	}
+	defer unlock()
+
+	now := time.Now()
+	var res *result
+
+	for _, t := range pending {
+		if err := l.check(t, now); err != nil {
+			// fail open rather than take the gateway down with the store
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("error applying rate limit %s: %v", t.lim.Name, err)
+			}
+			continue
+		}
+		if !t.res.allowed {
+			return t.res
+		}
+		if t.res.limit > 0 && (res == nil || t.res.remaining < res.remaining) {
+			res = t.res
+		}
+	}
+
+	// every limit allows the request so take it from all of them
+	for _, t := range pending {
+		if err := l.commit(t); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("error applying rate limit %s: %v", t.lim.Name, err)
+			}
+		}
+	}
+
+	if res == nil {
+		return &result{allowed: true}
+	}
+	return res
+}
+
+// lock the counters of the keys, across replicas when a sync is set. Keys are
+// locked in order so requests matching the same limits can't deadlock.
+func (l *limiter) lock(keys []string) (func(), error) {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	var stripes []int
+	seen := make(map[int]bool)
+	for _, k := range keys {
+		h := fnv.New32a()
+		h.Write([]byte(k))
+		i := int(h.Sum32() % uint32(len(l.locks)))
+		if !seen[i] {
+			seen[i] = true
+			stripes = append(stripes, i)
+		}
+	}
+	sort.Ints(stripes)
+
+	for _, i := range stripes {
+		l.locks[i].Lock()
+	}
+
+	var locked []string
+	unlock := func() {
+		for _, k := range locked {
+			l.opts.Sync.Unlock(k)
+		}
+		for _, i := range stripes {
+			l.locks[i].Unlock()
+		}
+	}
+
+	if l.opts.Sync != nil {
+		for _, k := range keys {
+			if err := l.opts.Sync.Lock(k); err != nil {
+				unlock()
+				return nil, err
+			}
+			locked = append(locked, k)
+		}
+	}
+
+	return unlock, nil
+}
+
+// check whether the limit allows the request, setting the result
+// and the counters to write if it's taken
+func (l *limiter) check(t *taken, now time.Time) error {
+	lim := t.lim
+	res := &result{allowed: true}
+	t.res = res
+
+	type quota struct {
+		key    string
+		limit  int64
+		expiry time.Duration
+	}
+	var quotas []*quota
+
+	// quotas reset at midnight utc
+	utc := now.UTC()
+	day := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(utc.Year(), utc.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	if lim.Daily > 0 {
+		quotas = append(quotas, &quota{
+			key:    t.key + "/day/" + day.Format("2006-01-02"),
+			limit:  lim.Daily,
+			expiry: day.AddDate(0, 0, 1).Sub(now),
+		})
+	}
+	if lim.Monthly > 0 {
+		quotas = append(quotas, &quota{
+			key:    t.key + "/month/" + month.Format("2006-01"),
+			limit:  lim.Monthly,
+			expiry: month.AddDate(0, 1, 0).Sub(now),
+		})
+	}
+
+	var writes []*store.Record
+
+	for _, q := range quotas {
+		count, err := l.count(q.key)
+		if err != nil {
+			return err
+		}
+		if count >= q.limit {
+			res.allowed = false
+			res.retry = q.expiry
+			res.reason = "quota exceeded"
+			return nil
+		}
+		writes = append(writes, &store.Record{
+			Key:    q.key,
+			Value:  []byte(strconv.FormatInt(count+1, 10)),
+			Expiry: q.expiry,
+		})
+	}
+
+	// take a token from the bucket
+	if lim.rate > 0 {
+		b := &bucket{Tokens: lim.burst, Updated: now.UnixNano()}
+		recs, err := l.opts.Store.Read(t.key)
+		if err != nil && err != store.ErrNotFound {
+			return err
+		}
+		if len(recs) > 0 {
+			if err := json.Unmarshal(recs[0].Value, b); err != nil {
+				return err
+			}
+			elapsed := now.Sub(time.Unix(0, b.Updated)).Seconds()
+			b.Tokens = math.Min(lim.burst, b.Tokens+math.Max(elapsed, 0)*lim.rate)
+			b.Updated = now.UnixNano()
+		}
+
+		res.limit = int(lim.burst)
+		if b.Tokens < 1 {
+			res.allowed = false
+			res.retry = seconds((1 - b.Tokens) / lim.rate)
+			res.reset = seconds((lim.burst - b.Tokens) / lim.rate)
+			res.reason = "rate limit exceeded"
+			return nil
+		}
+
+		b.Tokens--
+		res.remaining = int(b.Tokens)
+		res.reset = seconds((lim.burst - b.Tokens) / lim.rate)
+
+		v, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		// the bucket is full again once it expires
+		writes = append(writes, &store.Record{Key: t.key, Value: v, Expiry: res.reset})
+	}
+
+	t.writes = writes
+	return nil
+}
+
+// commit the counters of the request taken from the limit
+func (l *limiter) commit(t *taken) error {
+	for _, r := range t.writes {
+		if err := l.opts.Store.Write(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// count reads a quota counter
+func (l *limiter) count(key string) (int64, error) {
+	recs, err := l.opts.Store.Read(key)
+	if err == store.ErrNotFound || (err == nil && len(recs) == 0) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(recs[0].Value), 10, 64)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// ceil returns the duration in whole seconds rounded up
+func ceil(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// account sets the account of the bearer token of the request in its
+// context, unless it already has one
+func (l *limiter) account(r *http.Request) *http.Request {
+	if l.opts.Auth == nil {
+		return r
+	}
+	if acc, _ := auth.AccountFromContext(r.Context()); acc != nil {
+		return r
+	}
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, auth.BearerScheme) {
+		return r
+	}
+	// invalid tokens are left to the auth of the handler
+	acc, err := l.opts.Auth.Inspect(strings.TrimPrefix(h, auth.BearerScheme))
+	if err != nil {
+		return r
+	}
+	return r.WithContext(auth.ContextWithAccount(r.Context(), acc))
+}
+
+func (l *limiter) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = l.account(r)
+		res := l.allow(r)
+
+		if res.limit > 0 {
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.remaining))
+			w.Header().Set("RateLimit-Reset", ceil(res.reset))
+		}
+
+		if !res.allowed {
+			w.Header().Set("Retry-After", ceil(res.retry))
+			ce := errors.New("go.micro.api", res.reason, http.StatusTooManyRequests)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(ce.Error()))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// NewWrapper returns a wrapper which rate limits requests, or an error
+// if the limits given or read from config are invalid
+func NewWrapper(opts ...Option) (server.Wrapper, error) {
+	l := &limiter{opts: newOptions(opts...)}
+
+	if err := l.load(nil); err != nil {
+		return nil, err
+	}
+
+	if l.opts.Config != nil {
+		var limits []*Limit
+		if err := l.opts.Config.Get(l.opts.Path...).Scan(&limits); err != nil {
+			return nil, fmt.Errorf("reading rate limits: %v", err)
+		}
+		if err := l.load(limits); err != nil {
+			return nil, err
+		}
+		go l.watch()
+	}
+
+	return l.wrap, nil
+}
@@ -0,0 +1,290 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/auth"
+	"github.com/asim/go-micro/v3/config"
+	"github.com/asim/go-micro/v3/config/source"
+	"github.com/asim/go-micro/v3/config/source/memory"
+	"github.com/asim/go-micro/v3/store"
+)
+
+func TestRateLimit(t *testing.T) {
+	s := store.NewMemoryStore()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	// two gateways sharing the store share the limits
+	limits := []*Limit{{Key: KeyAPIKey, Path: "/v1/", Requests: 2, Period: "1m"}}
+	w1, err := NewWrapper(Store(s), Limits(limits...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w2, err := NewWrapper(Store(s), Limits(limits...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h1, h2 := w1(ok), w2(ok)
+
+	do := func(h http.Handler, path, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("X-Api-Key", key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(h1, "/v1/foo", "a")
+	if w.Code != 200 || w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if w := do(h2, "/v1/foo", "a"); w.Code != 200 || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+
+	w = do(h1, "/v1/bar", "a")
+	if w.Code != 429 {
+		t.Fatalf("expected 429 got %d", w.Code)
+	}
+	// a token is added every 30 seconds
+	if v := w.Header().Get("Retry-After"); v != "30" {
+		t.Fatalf("expected retry after 30 got %s", v)
+	}
+
+	// other keys and paths aren't limited
+	if w := do(h1, "/v1/foo", "b"); w.Code != 200 {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+	if w := do(h1, "/v2/foo", "a"); w.Code != 200 || len(w.Header().Get("RateLimit-Limit")) > 0 {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+}
+
+func TestQuota(t *testing.T) {
+	l := &limiter{opts: newOptions(Store(store.NewMemoryStore()), Limits(&Limit{Key: KeyIP, Daily: 2}))}
+	if err := l.load(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	take := func() *result {
+		tk := &taken{lim: l.limits[0], key: "127.0.0.1"}
+		if err := l.check(tk, now); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.commit(tk); err != nil {
+			t.Fatal(err)
+		}
+		return tk.res
+	}
+
+	for i := 0; i < 2; i++ {
+		if res := take(); !res.allowed {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+
+	if res := take(); res.allowed || res.retry != time.Hour*12 {
+		t.Fatalf("expected quota to be exceeded until midnight got %+v", res)
+	}
+}
+
+func TestDeniedNotTaken(t *testing.T) {
+	s := store.NewMemoryStore()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	// a generous limit checked before a strict one on the same requests
+	wrap, err := NewWrapper(Store(s), Limits(
+		&Limit{Name: "generous", Key: KeyRoute, Requests: 3, Period: "1m"},
+		&Limit{Name: "strict", Key: KeyRoute, Path: "/strict", Requests: 1, Period: "1m"},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := wrap(ok)
+
+	do := func(path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+
+	if code := do("/strict"); code != 200 {
+		t.Fatalf("expected 200 got %d", code)
+	}
+	// denied by the strict limit, the generous one keeps its tokens
+	for i := 0; i < 3; i++ {
+		if code := do("/strict"); code != 429 {
+			t.Fatalf("expected 429 got %d", code)
+		}
+	}
+
+	recs, err := s.Read("ratelimit/generous/GET /strict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(recs[0].Value), `"tokens":2`) {
+		t.Fatalf("expected 2 tokens left got %s", recs[0].Value)
+	}
+}
+
+// testAuth has an account per token
+type testAuth struct {
+	auth.Auth
+}
+
+func (testAuth) Inspect(token string) (*auth.Account, error) {
+	if len(token) == 0 {
+		return nil, auth.ErrInvalidToken
+	}
+	return &auth.Account{ID: token}, nil
+}
+
+func TestKeyAccount(t *testing.T) {
+	var account string
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acc, _ := auth.AccountFromContext(r.Context())
+		account = acc.ID
+	})
+
+	wrap, err := NewWrapper(
+		Store(store.NewMemoryStore()),
+		Limits(&Limit{Key: KeyAccount, Requests: 1, Period: "1m"}),
+		Auth(testAuth{auth.NewAuth()}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := wrap(ok)
+
+	do := func(token string) int {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", auth.BearerScheme+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// the account of the token is limited and passed on
+	if code := do("alice"); code != 200 || account != "alice" {
+		t.Fatalf("expected 200 for alice got %d %q", code, account)
+	}
+	if code := do("alice"); code != 429 {
+		t.Fatalf("expected 429 got %d", code)
+	}
+	if code := do("bob"); code != 200 || account != "bob" {
+		t.Fatalf("expected 200 for bob got %d %q", code, account)
+	}
+}
+
+func TestInvalidLimits(t *testing.T) {
+	if _, err := NewWrapper(Limits(&Limit{Key: "foo", Requests: 1})); err == nil {
+		t.Fatal("expected invalid limits to fail")
+	}
+}
+
+func TestConfig(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"limits": [{"key": "ip", "requests": 1, "period": "1m"}]}`)))
+	c, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load(src); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &limiter{opts: newOptions(Config(c, "limits"), Context(ctx))}
+	if err := l.load(nil); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() {
+		l.watch()
+		close(done)
+	}()
+
+	update := func(requests int) {
+		data := fmt.Sprintf(`{"limits": [{"key": "ip", "requests": %d, "period": "1m"}]}`, requests)
+		if err := src.Write(&source.ChangeSet{Data: []byte(data), Format: "json"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	requests := func() int {
+		l.mtx.RLock()
+		defer l.mtx.RUnlock()
+		if len(l.limits) == 0 {
+			return 0
+		}
+		return l.limits[0].Requests
+	}
+
+	// changes made before watching starts aren't seen
+	time.Sleep(100 * time.Millisecond)
+	update(2)
+	for i := 0; requests() != 2; i++ {
+		if i > 100 {
+			t.Fatalf("expected the limits to be reloaded, got %d requests", requests())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the config is no longer watched once the context is done
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected watching to stop")
+	}
+}
+
+func TestKeyIP(t *testing.T) {
+	testData := []struct {
+		name    string
+		proxies []string
+		remote  string
+		forward []string
+		ip      string
+	}{
+		{"Direct", nil, "1.2.3.4:1234", nil, "1.2.3.4"},
+		{"Spoofed", nil, "1.2.3.4:1234", []string{"5.6.7.8"}, "1.2.3.4"},
+		{"Proxied", []string{"10.0.0.0/8"}, "10.0.0.1:1234", []string{"5.6.7.8"}, "5.6.7.8"},
+		{"ProxiedSpoofed", []string{"10.0.0.0/8"}, "10.0.0.1:1234", []string{"9.9.9.9, 5.6.7.8, 10.0.0.2"}, "5.6.7.8"},
+		{"UntrustedProxy", []string{"10.0.0.1"}, "10.0.0.2:1234", []string{"5.6.7.8"}, "10.0.0.2"},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			l := &limiter{opts: newOptions(TrustedProxies(d.proxies...))}
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = d.remote
+			for _, f := range d.forward {
+				r.Header.Add("X-Forwarded-For", f)
+			}
+			if ip := l.key(&limit{Limit: &Limit{Key: KeyIP}}, r); ip != d.ip {
+				t.Fatalf("expected ip %s got %s", d.ip, ip)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	for _, l := range []*Limit{
+		{Key: "foo", Requests: 1},
+		{Key: KeyIP},
+		{Key: KeyIP, Requests: 1, Period: "never"},
+	} {
+		if _, err := compile([]*Limit{l}); err == nil {
+			t.Fatalf("expected %+v to be invalid", l)
+		}
+	}
+}
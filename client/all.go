@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/selector"
+)
+
+const (
+	// QuorumAll needs every node to succeed
+	QuorumAll = 0
+	// QuorumMajority needs more than half of the nodes to succeed
+	QuorumMajority = -1
+)
+
+// Result of calling a node
+type Result struct {
+	Node     *registry.Node
+	Response interface{}
+	Error    error
+}
+
+// Results of calling every node of a service, by node id
+type Results map[string]*Result
+
+// Errors of the nodes which failed, by node id
+func (r Results) Errors() map[string]error {
+	errs := make(map[string]error)
+	for id, res := range r {
+		if res.Error != nil {
+			errs[id] = res.Error
+		}
+	}
+	return errs
+}
+
+// CallAll calls the endpoint of the request on every node of the service
+// using the default client e.g. to invalidate caches, see CallAllWith
+func CallAll(ctx context.Context, request Request, newRsp func() interface{}, opts ...CallOption) (Results, error) {
+	return CallAllWith(ctx, DefaultClient, request, newRsp, opts...)
+}
+
+// CallAllWith calls the endpoint of the request on every node of the service,
+// as selected with the call options, with a response from newRsp for each.
+// Nodes are called concurrently, WithConcurrency at once, and an error is
+// returned along with the results unless the quorum of them succeeds.
+func CallAllWith(ctx context.Context, c Client, request Request, newRsp func() interface{}, opts ...CallOption) (Results, error) {
+	callOpts := c.Options().CallOptions
+	for _, opt := range opts {
+		opt(&callOpts)
+	}
+
+	nodes, err := allNodes(c, request.Service(), callOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := callOpts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	sem := make(chan bool, concurrency)
+
+	results := make(Results, len(nodes))
+	var wg sync.WaitGroup
+
+	for _, node := range nodes {
+		res := &Result{Node: node, Response: newRsp()}
+		results[node.Id] = res
+
+		select {
+		case sem <- true:
+		case <-ctx.Done():
+			res.Error = errors.Timeout("go.micro.client", fmt.Sprintf("%v", ctx.Err()))
+			continue
+		}
+
+		wg.Add(1)
+		go func(node *registry.Node, res *Result) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			// call the node and only the node, never coalesced with the calls of the others
+			nodeOpts := append(opts[:len(opts):len(opts)], WithSelectOption(selector.WithStrategy(nodeStrategy(node.Id))), func(o *CallOptions) {
+				o.Coalesce = false
+			})
+			if len(callOpts.Address) > 0 {
+				nodeOpts = append(nodeOpts, WithAddress(node.Address))
+			}
+
+			res.Error = c.Call(ctx, request, res.Response, nodeOpts...)
+		}(node, res)
+	}
+
+	wg.Wait()
+
+	var succeeded int
+	for _, res := range results {
+		if res.Error == nil {
+			succeeded++
+		}
+	}
+
+	quorum := callOpts.Quorum
+	switch quorum {
+	case QuorumAll:
+		quorum = len(nodes)
+	case QuorumMajority:
+		quorum = len(nodes)/2 + 1
+	}
+
+	if succeeded < quorum {
+		return results, errors.InternalServerError("go.micro.client", "%d of %d nodes of %s succeeded, quorum of %d not met",
+			succeeded, len(nodes), request.Service(), quorum)
+	}
+
+	return results, nil
+}
+
+// allNodes of the service the call options select
+func allNodes(c Client, service string, opts CallOptions) ([]*registry.Node, error) {
+	// the addresses given
+	if len(opts.Address) > 0 {
+		nodes := make([]*registry.Node, len(opts.Address))
+		for i, addr := range opts.Address {
+			nodes[i] = &registry.Node{
+				Id:      addr,
+				Address: addr,
+				Metadata: map[string]string{
+					"protocol": "mucp",
+				},
+			}
+		}
+		return nodes, nil
+	}
+
+	var nodes []*registry.Node
+
+	selectOpts := append(opts.SelectOptions[:len(opts.SelectOptions):len(opts.SelectOptions)], selector.WithStrategy(func(services []*registry.Service) selector.Next {
+		for _, s := range services {
+			nodes = append(nodes, s.Nodes...)
+		}
+		return nil
+	}))
+
+	version := opts.Version
+	if len(version) == 0 {
+		version = c.Options().Versions[service]
+	}
+	if len(version) > 0 {
+		if _, err := selector.ParseConstraint(version); err != nil {
+			return nil, errors.BadRequest("go.micro.client", err.Error())
+		}
+		selectOpts = append(selectOpts, selector.WithVersion(version))
+	}
+
+	if _, err := c.Options().Selector.Select(service, selectOpts...); err != nil {
+		if err == selector.ErrNotFound {
+			return nil, errors.InternalServerError("go.micro.client", "service %s: %s", service, err.Error())
+		}
+		return nil, errors.InternalServerError("go.micro.client", "error selecting %s nodes: %s", service, err.Error())
+	}
+
+	return nodes, nil
+}
+
+// nodeStrategy selects the node with the id
+func nodeStrategy(id string) selector.Strategy {
+	return func(services []*registry.Service) selector.Next {
+		return func() (*registry.Node, error) {
+			for _, s := range services {
+				for _, n := range s.Nodes {
+					if n.Id == id {
+						return n, nil
+					}
+				}
+			}
+			return nil, selector.ErrNoneAvailable
+		}
+	}
+}
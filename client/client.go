@@ -95,6 +95,9 @@ type MessageOption func(*MessageOptions)
 // RequestOption used by NewRequest
 type RequestOption func(*RequestOptions)
 
+// RoutingKeyHeader is the metadata holding the key routed with consistent hashing
+const RoutingKeyHeader = "Micro-Routing-Key"
+
 var (
 	// DefaultClient is a default client to use out of the box
 	DefaultClient Client = newRpcClient()
@@ -110,6 +113,8 @@ var (
 	DefaultPoolSize = 100
 	// DefaultPoolTTL sets the connection pool ttl
 	DefaultPoolTTL = time.Minute
+	// DefaultConcurrency is the number of nodes called at once by CallAll
+	DefaultConcurrency = 10
 
 	// NewClient returns a new client
 	NewClient func(...Option) Client = newRpcClient
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	raw "github.com/asim/go-micro/v3/codec/bytes"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/metadata"
+	"github.com/golang/protobuf/proto"
+)
+
+// coalescer of identical calls in flight
+type coalescer struct {
+	sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a call shared by the callers waiting on it
+type flight struct {
+	done    chan bool
+	rsp     interface{}
+	md      metadata.Metadata
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+	// deadline of the call
+	deadline time.Time
+}
+
+// detached context keeping the values of the context it's made from but
+// not its deadline or cancellation, the call outlives the caller making it
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{
+		flights: make(map[string]*flight),
+	}
+}
+
+// coalesceKey of the call, that of the cache along with the type of the
+// response, the options choosing the nodes called and the values of the
+// Authorization and other metadata keys
+func coalesceKey(ctx context.Context, req Request, rsp interface{}, opts CallOptions) string {
+	h := fnv.New64()
+	h.Write([]byte(key(ctx, &req)))
+	h.Write([]byte(reflect.TypeOf(rsp).String()))
+	h.Write([]byte("address=" + strings.Join(opts.Address, ",") + "\n"))
+	h.Write([]byte("version=" + opts.Version + "\n"))
+	h.Write([]byte("routing=" + opts.RoutingKey + "\n"))
+	for _, k := range append([]string{"Authorization"}, opts.CoalesceMetadata...) {
+		v, _ := metadata.Get(ctx, k)
+		h.Write([]byte(k + "=" + v + "\n"))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// copyResponse deep copies the response of the flight into that of a
+// caller, so callers don't share the values it points to
+func copyResponse(dst, src interface{}) error {
+	switch s := src.(type) {
+	case proto.Message:
+		d := dst.(proto.Message)
+		d.Reset()
+		proto.Merge(d, s)
+	case *raw.Frame:
+		dst.(*raw.Frame).Data = append([]byte(nil), s.Data...)
+	default:
+		b, err := json.Marshal(src)
+		if err != nil {
+			return errors.InternalServerError("go.micro.client", "copying response: %v", err)
+		}
+		reflect.ValueOf(dst).Elem().Set(reflect.Zero(reflect.TypeOf(dst).Elem()))
+		if err := json.Unmarshal(b, dst); err != nil {
+			return errors.InternalServerError("go.micro.client", "copying response: %v", err)
+		}
+	}
+	return nil
+}
+
+// leave the flight, cancelling it once no caller waits on it
+func (c *coalescer) leave(k string, f *flight) {
+	c.Lock()
+	defer c.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	f.cancel()
+	if c.flights[k] == f {
+		delete(c.flights, k)
+	}
+}
+
+// coalesce the call with the identical ones in flight. The first caller
+// makes the call, with a context of its own and the deadline of the caller,
+// and every caller gets a copy of the response. A caller with a later
+// deadline than that of the call makes a new one for the callers after it.
+// Callers stop waiting when their context is done and the call is cancelled
+// when none are left.
+func (r *rpcClient) coalesce(ctx context.Context, request Request, response interface{}, callOpts CallOptions, opts []CallOption) error {
+	k := coalesceKey(ctx, request, response, callOpts)
+	c := r.coalescer
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(callOpts.RequestTimeout)
+	}
+
+	c.Lock()
+	f, joined := c.flights[k]
+	if !joined || ok && f.deadline.Before(deadline) {
+		fctx, cancel := context.WithDeadline(detached{ctx}, deadline)
+		f = &flight{
+			done:     make(chan bool),
+			rsp:      reflect.New(reflect.TypeOf(response).Elem()).Interface(),
+			cancel:   cancel,
+			deadline: deadline,
+		}
+		c.flights[k] = f
+
+		go func(f *flight) {
+			fopts := append(opts[:len(opts):len(opts)], WithResponseMetadata(&f.md), func(o *CallOptions) {
+				o.Coalesce = false
+			})
+			f.err = r.Call(fctx, request, f.rsp, fopts...)
+
+			c.Lock()
+			if c.flights[k] == f {
+				delete(c.flights, k)
+			}
+			c.Unlock()
+
+			cancel()
+			close(f.done)
+		}(f)
+	}
+	f.waiters++
+	c.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		c.leave(k, f)
+		return errors.Timeout("go.micro.client", fmt.Sprintf("%v", ctx.Err()))
+	}
+
+	if f.err != nil {
+		return f.err
+	}
+
+	if err := copyResponse(response, f.rsp); err != nil {
+		return err
+	}
+
+	if callOpts.ResponseMetadata != nil {
+		md := make(metadata.Metadata, len(f.md))
+		for k, v := range f.md {
+			md[k] = v
+		}
+		*callOpts.ResponseMetadata = md
+	}
+
+	return nil
+}
@@ -0,0 +1,330 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/metadata"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/selector"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// waiters of the flights in the client
+func waiters(c Client) int {
+	co := c.(*rpcClient).coalescer
+	co.Lock()
+	defer co.Unlock()
+
+	var n int
+	for _, f := range co.flights {
+		n += f.waiters
+	}
+	return n
+}
+
+func waitFor(t *testing.T, c Client, n int) {
+	for i := 0; waiters(c) != n; i++ {
+		if i > 100 {
+			t.Fatalf("expected %d callers waiting, got %d", n, waiters(c))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCoalescing(t *testing.T) {
+	var calls int32
+	release := make(chan bool)
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			atomic.AddInt32(&calls, 1)
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return errors.Timeout("go.micro.client", "cancelled")
+			}
+			*(rsp.(*string)) = "bar"
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+
+	t.Run("Shared", func(t *testing.T) {
+		var wg sync.WaitGroup
+		rsps := make([]string, 10)
+		errs := make([]error, 10)
+
+		for i := range rsps {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = c.Call(context.Background(), req, &rsps[i], WithCoalescing())
+			}(i)
+		}
+
+		// a caller giving up leaves the others waiting
+		ctx, cancel := context.WithCancel(context.Background())
+		var rsp string
+		errc := make(chan error, 1)
+		go func() {
+			errc <- c.Call(ctx, req, &rsp, WithCoalescing())
+		}()
+
+		waitFor(t, c, 11)
+		cancel()
+		if err := <-errc; err == nil {
+			t.Fatal("expected the cancelled caller to fail")
+		}
+
+		close(release)
+		wg.Wait()
+
+		if n := atomic.LoadInt32(&calls); n != 1 {
+			t.Fatalf("expected 1 call, got %d", n)
+		}
+		for i := range rsps {
+			if errs[i] != nil || rsps[i] != "bar" {
+				t.Fatalf("unexpected response %q %v", rsps[i], errs[i])
+			}
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		release = make(chan bool)
+
+		// the call is cancelled once no caller is left
+		ctx, cancel := context.WithCancel(context.Background())
+		var rsp string
+		errc := make(chan error, 1)
+		go func() {
+			errc <- c.Call(ctx, req, &rsp, WithCoalescing())
+		}()
+
+		waitFor(t, c, 1)
+		cancel()
+		<-errc
+		waitFor(t, c, 0)
+
+		// a new caller makes a new call
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+		if err := c.Call(context.Background(), req, &rsp, WithCoalescing()); err != nil {
+			t.Fatal(err)
+		}
+		if n := atomic.LoadInt32(&calls); n != 2 {
+			t.Fatalf("expected 2 calls, got %d", n)
+		}
+	})
+}
+
+func TestCoalesceCopies(t *testing.T) {
+	type response struct {
+		Tags  []string
+		Value *wrapperspb.StringValue
+	}
+
+	release := make(chan bool)
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			<-release
+			switch v := rsp.(type) {
+			case *response:
+				v.Tags = []string{"bar"}
+			case *wrapperspb.StringValue:
+				v.Value = "bar"
+			}
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+
+	var wg sync.WaitGroup
+	rsps := make([]response, 2)
+	msgs := make([]wrapperspb.StringValue, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if err := c.Call(context.Background(), req, &rsps[i], WithCoalescing()); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			if err := c.Call(context.Background(), req, &msgs[i], WithCoalescing()); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	waitFor(t, c, 4)
+	close(release)
+	wg.Wait()
+
+	rsps[0].Tags[0] = "baz"
+	if rsps[1].Tags[0] != "bar" {
+		t.Fatalf("expected callers to get a copy of the response, got %v", rsps[1].Tags)
+	}
+	msgs[0].Value = "baz"
+	if msgs[1].Value != "bar" {
+		t.Fatalf("expected callers to get a copy of the message, got %q", msgs[1].Value)
+	}
+}
+
+func TestCoalesceDeadline(t *testing.T) {
+	var mtx sync.Mutex
+	var deadlines []time.Time
+	release := make(chan bool)
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			d, _ := ctx.Deadline()
+			mtx.Lock()
+			deadlines = append(deadlines, d)
+			mtx.Unlock()
+			<-release
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+
+	call := func(d time.Time) chan error {
+		errc := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithDeadline(context.Background(), d)
+			defer cancel()
+			var rsp string
+			errc <- c.Call(ctx, req, &rsp, WithCoalescing())
+		}()
+		return errc
+	}
+
+	// the call has the deadline of its caller, not the request timeout
+	short := time.Now().Add(time.Minute)
+	a := call(short)
+	waitFor(t, c, 1)
+
+	// a caller with a later deadline makes a call of its own
+	long := short.Add(time.Minute)
+	b := call(long)
+	for i := 0; ; i++ {
+		mtx.Lock()
+		n := len(deadlines)
+		mtx.Unlock()
+		if n == 2 {
+			break
+		}
+		if i > 100 {
+			t.Fatal("expected a second call")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// and one with an earlier deadline joins the last call
+	d := call(short.Add(-time.Second))
+	waitFor(t, c, 2)
+
+	close(release)
+	for _, errc := range []chan error{a, b, d} {
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(deadlines) != 2 || !deadlines[0].Equal(short) || !deadlines[1].Equal(long) {
+		t.Fatalf("expected calls with the deadlines of their callers, got %v", deadlines)
+	}
+}
+
+func TestCoalesceKey(t *testing.T) {
+	req := newRequest("foo", "Foo.Bar", nil, "application/json")
+	var rsp string
+
+	base := coalesceKey(context.Background(), req, &rsp, CallOptions{})
+	if k := coalesceKey(context.Background(), req, &rsp, CallOptions{}); k != base {
+		t.Fatal("expected identical calls to have the same key")
+	}
+
+	testData := map[string]struct {
+		ctx  context.Context
+		opts CallOptions
+	}{
+		"Address":       {context.Background(), CallOptions{Address: []string{"10.0.0.1:8080"}}},
+		"Version":       {context.Background(), CallOptions{Version: ">=2"}},
+		"RoutingKey":    {context.Background(), CallOptions{RoutingKey: "user-1"}},
+		"Authorization": {metadata.Set(context.Background(), "Authorization", "Bearer foo"), CallOptions{}},
+		"Metadata":      {metadata.Set(context.Background(), "Tenant", "foo"), CallOptions{CoalesceMetadata: []string{"Tenant"}}},
+	}
+
+	for name, d := range testData {
+		if k := coalesceKey(d.ctx, req, &rsp, d.opts); k == base {
+			t.Fatalf("%s: expected calls to be told apart", name)
+		}
+	}
+}
+
+func TestCoalescingCallAll(t *testing.T) {
+	var mtx sync.Mutex
+	called := make(map[string]int)
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			mtx.Lock()
+			called[node.Id]++
+			mtx.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			*(rsp.(*string)) = node.Id
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+	results, err := CallAllWith(context.Background(), c, req, func() interface{} { return new(string) }, WithCoalescing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, res := range results {
+		if called[id] != 1 || *(res.Response.(*string)) != id {
+			t.Fatalf("expected node %s to be called once, got %d calls and %v", id, called[id], *(res.Response.(*string)))
+		}
+	}
+}
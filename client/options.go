@@ -6,6 +6,7 @@ import (
 
 	"github.com/asim/go-micro/v3/broker"
 	"github.com/asim/go-micro/v3/codec"
+	"github.com/asim/go-micro/v3/metadata"
 	"github.com/asim/go-micro/v3/registry"
 	"github.com/asim/go-micro/v3/selector"
 	"github.com/asim/go-micro/v3/transport"
@@ -38,6 +39,9 @@ type Options struct {
 	// Default Call Options
 	CallOptions CallOptions
 
+	// Default version constraints of services by name
+	Versions map[string]string
+
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
@@ -64,6 +68,20 @@ type CallOptions struct {
 	ServiceToken bool
 	// Duration to cache the response for
 	CacheExpiry time.Duration
+	// Version constraint of the nodes called e.g. >=1.4 <2.0
+	Version string
+	// Key routed to the same node with consistent hashing
+	RoutingKey string
+	// Number of nodes called at once by CallAll
+	Concurrency int
+	// Number of nodes CallAll needs to succeed, see WithQuorum
+	Quorum int
+	// Collapse identical calls in flight into one
+	Coalesce bool
+	// Metadata keys which tell coalesced calls apart
+	CoalesceMetadata []string
+	// Metadata returned with the response
+	ResponseMetadata *metadata.Metadata
 
 	// Middleware for low level call func
 	CallWrappers []CallWrapper
@@ -106,6 +124,7 @@ func NewOptions(options ...Option) Options {
 			Retries:        DefaultRetries,
 			RequestTimeout: DefaultRequestTimeout,
 			DialTimeout:    transport.DefaultDialTimeout,
+			Concurrency:    DefaultConcurrency,
 		},
 		PoolSize:  DefaultPoolSize,
 		PoolTTL:   DefaultPoolTTL,
@@ -202,6 +221,17 @@ func Backoff(fn BackoffFunc) Option {
 	}
 }
 
+// ServiceVersion sets the default version constraint of the nodes
+// of the service called e.g. ">=1.4 <2.0"
+func ServiceVersion(service, constraint string) Option {
+	return func(o *Options) {
+		if o.Versions == nil {
+			o.Versions = make(map[string]string)
+		}
+		o.Versions[service] = constraint
+	}
+}
+
 // Number of retries when making the request.
 // Should this be a Call Option?
 func Retries(i int) Option {
@@ -262,6 +292,55 @@ func WithAddress(a ...string) CallOption {
 	}
 }
 
+// WithVersion only calls nodes of the service with a semantic version
+// meeting the constraint e.g. ">=1.4 <2.0", overriding the default set
+// with ServiceVersion. Invalid constraints fail the call with a bad request.
+// See selector.WithVersion.
+func WithVersion(constraint string) CallOption {
+	return func(o *CallOptions) {
+		o.Version = constraint
+	}
+}
+
+// WithRoutingKey routes calls with the same key to the same node with
+// consistent hashing, for services caching by key. Without it the key is
+// taken from the Micro-Routing-Key metadata of the context, which isn't
+// passed on to the service so it doesn't route its own calls. Streams count
+// towards the load of their node until they're closed. See selector.Hash.
+func WithRoutingKey(k string) CallOption {
+	return func(o *CallOptions) {
+		o.RoutingKey = k
+	}
+}
+
+// WithConcurrency sets the number of nodes CallAll calls at once
+func WithConcurrency(n int) CallOption {
+	return func(o *CallOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithQuorum sets the number of nodes CallAll needs to succeed, QuorumAll
+// by default or QuorumMajority for more than half of them
+func WithQuorum(n int) CallOption {
+	return func(o *CallOptions) {
+		o.Quorum = n
+	}
+}
+
+// WithCoalescing collapses identical calls in flight into a single one
+// whose response every caller gets a copy of. Calls are identical when their
+// service, endpoint, body and response type are, as are their addresses,
+// version and routing key and the values of the Authorization and other
+// metadata keys e.g. those responses differ by. Calls with select options
+// and those of CallAll are never coalesced.
+func WithCoalescing(md ...string) CallOption {
+	return func(o *CallOptions) {
+		o.Coalesce = true
+		o.CoalesceMetadata = md
+	}
+}
+
 func WithSelectOption(so ...selector.SelectOption) CallOption {
 	return func(o *CallOptions) {
 		o.SelectOptions = append(o.SelectOptions, so...)
@@ -307,6 +386,14 @@ func WithRequestTimeout(d time.Duration) CallOption {
 	}
 }
 
+// WithResponseMetadata is a CallOption which sets md to the
+// metadata the service returned with the response
+func WithResponseMetadata(md *metadata.Metadata) CallOption {
+	return func(o *CallOptions) {
+		o.ResponseMetadata = md
+	}
+}
+
 // WithStreamTimeout sets the stream timeout
 func WithStreamTimeout(d time.Duration) CallOption {
 	return func(o *CallOptions) {
@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sync/atomic"
 	"time"
 
@@ -21,10 +22,11 @@ import (
 )
 
 type rpcClient struct {
-	seq  uint64
-	once atomic.Value
-	opts Options
-	pool pool.Pool
+	seq       uint64
+	once      atomic.Value
+	opts      Options
+	pool      pool.Pool
+	coalescer *coalescer
 }
 
 func newRpcClient(opt ...Option) Client {
@@ -37,9 +39,10 @@ func newRpcClient(opt ...Option) Client {
 	)
 
 	rc := &rpcClient{
-		opts: opts,
-		pool: p,
-		seq:  0,
+		opts:      opts,
+		pool:      p,
+		seq:       0,
+		coalescer: newCoalescer(),
 	}
 	rc.once.Store(false)
 
@@ -157,6 +160,11 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 			return
 		}
 
+		// return the metadata set by the service
+		if opts.ResponseMetadata != nil {
+			*opts.ResponseMetadata = responseMetadata(rsp.header)
+		}
+
 		// success
 		ch <- nil
 	}()
@@ -320,8 +328,11 @@ func (r *rpcClient) Options() Options {
 	return r.opts
 }
 
-// next returns an iterator for the next nodes to call
-func (r *rpcClient) next(request Request, opts CallOptions) (selector.Next, error) {
+// next returns an iterator for the next nodes to call and the func called
+// with each once its call is done, releasing those routed by the hash
+func (r *rpcClient) next(request Request, opts CallOptions) (selector.Next, func(*registry.Node), error) {
+	done := func(*registry.Node) {}
+
 	// try get the proxy
 	service, address, _ := net.Proxy(request.Service(), opts.Address)
 
@@ -342,19 +353,56 @@ func (r *rpcClient) next(request Request, opts CallOptions) (selector.Next, erro
 		// crude return method
 		return func() (*registry.Node, error) {
 			return nodes[time.Now().Unix()%int64(len(nodes))], nil
-		}, nil
+		}, done, nil
+	}
+
+	selectOpts := opts.SelectOptions
+
+	// route the key with consistent hashing, strategies set by the caller take precedence
+	if len(opts.RoutingKey) > 0 {
+		// only nodes the hash picked are in flight for it
+		var hashed int32
+		hash := selector.DefaultHash.Strategy(opts.RoutingKey)
+		strategy := func(services []*registry.Service) selector.Next {
+			next := hash(services)
+			return func() (*registry.Node, error) {
+				node, err := next()
+				if err == nil {
+					atomic.StoreInt32(&hashed, 1)
+				}
+				return node, err
+			}
+		}
+		done = func(node *registry.Node) {
+			if atomic.LoadInt32(&hashed) == 1 {
+				selector.DefaultHash.Done(node)
+			}
+		}
+		selectOpts = append([]selector.SelectOption{selector.WithStrategy(strategy)}, selectOpts...)
+	}
+
+	// only select the versions meeting the constraint
+	version := opts.Version
+	if len(version) == 0 {
+		version = r.opts.Versions[service]
+	}
+	if len(version) > 0 {
+		if _, err := selector.ParseConstraint(version); err != nil {
+			return nil, nil, errors.BadRequest("go.micro.client", err.Error())
+		}
+		selectOpts = append(selectOpts[:len(selectOpts):len(selectOpts)], selector.WithVersion(version))
 	}
 
 	// get next nodes from the selector
-	next, err := r.opts.Selector.Select(service, opts.SelectOptions...)
+	next, err := r.opts.Selector.Select(service, selectOpts...)
 	if err != nil {
 		if err == selector.ErrNotFound {
-			return nil, errors.InternalServerError("go.micro.client", "service %s: %s", service, err.Error())
+			return nil, nil, errors.InternalServerError("go.micro.client", "service %s: %s", service, err.Error())
 		}
-		return nil, errors.InternalServerError("go.micro.client", "error selecting %s node: %s", service, err.Error())
+		return nil, nil, errors.InternalServerError("go.micro.client", "error selecting %s node: %s", service, err.Error())
 	}
 
-	return next, nil
+	return next, done, nil
 }
 
 func (r *rpcClient) Call(ctx context.Context, request Request, response interface{}, opts ...CallOption) error {
@@ -364,7 +412,22 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 		opt(&callOpts)
 	}
 
-	next, err := r.next(request, callOpts)
+	// route by the key in the metadata unless set, it's only for this call
+	// so it's stripped before being sent on to calls made downstream
+	if k, ok := metadata.Get(ctx, RoutingKeyHeader); ok {
+		if len(callOpts.RoutingKey) == 0 {
+			callOpts.RoutingKey = k
+		}
+		ctx = metadata.Delete(ctx, RoutingKeyHeader)
+	}
+
+	// collapse the call into an identical one in flight, select options
+	// can't be told apart so calls with them are always made
+	if callOpts.Coalesce && len(callOpts.SelectOptions) == 0 && response != nil && reflect.TypeOf(response).Kind() == reflect.Ptr {
+		return r.coalesce(ctx, request, response, callOpts, opts)
+	}
+
+	next, done, err := r.next(request, callOpts)
 	if err != nil {
 		return err
 	}
@@ -424,6 +487,7 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 		// make the call
 		err = rcall(ctx, node, request, response, callOpts)
 		r.opts.Selector.Mark(service, node, err)
+		done(node)
 		return err
 	}
 
@@ -475,7 +539,16 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 		opt(&callOpts)
 	}
 
-	next, err := r.next(request, callOpts)
+	// route by the key in the metadata unless set, it's only for this call
+	// so it's stripped before being sent on to calls made downstream
+	if k, ok := metadata.Get(ctx, RoutingKeyHeader); ok {
+		if len(callOpts.RoutingKey) == 0 {
+			callOpts.RoutingKey = k
+		}
+		ctx = metadata.Delete(ctx, RoutingKeyHeader)
+	}
+
+	next, done, err := r.next(request, callOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -510,7 +583,15 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 
 		stream, err := r.stream(ctx, node, request, callOpts)
 		r.opts.Selector.Mark(service, node, err)
-		return stream, err
+		if err != nil {
+			done(node)
+			return nil, err
+		}
+		// a node routed to is in flight until the stream is closed
+		if len(callOpts.RoutingKey) > 0 {
+			return &doneStream{Stream: stream, done: func() { done(node) }}, nil
+		}
+		return stream, nil
 	}
 
 	type response struct {
@@ -3,9 +3,13 @@ package client
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/metadata"
 	"github.com/asim/go-micro/v3/registry"
 	"github.com/asim/go-micro/v3/selector"
 )
@@ -158,3 +162,213 @@ func TestCallWrapper(t *testing.T) {
 		t.Fatal("wrapper not called")
 	}
 }
+
+func TestCallVersion(t *testing.T) {
+	var nodes []string
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			nodes = append(nodes, node.Id)
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+		ServiceVersion("foo", ">=1.0.1 <1.0.3"),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+
+	// the default of the service
+	if err := c.Call(context.Background(), req, nil); err != nil {
+		t.Fatal(err)
+	}
+	// overridden by the call
+	if err := c.Call(context.Background(), req, nil, WithVersion("1.0.3")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 2 || nodes[0] != "foo-1.0.1-321" || nodes[1] != "foo-1.0.3-345" {
+		t.Fatalf("unexpected nodes called %v", nodes)
+	}
+
+	err := c.Call(context.Background(), req, nil, WithVersion(">=2"))
+	if err == nil || !strings.Contains(err.Error(), "versions seen 1.0.0, 1.0.1, 1.0.3") {
+		t.Fatalf("expected the versions seen in the error, got %v", err)
+	}
+
+	err = c.Call(context.Background(), req, nil, WithVersion(">=x"))
+	if e := errors.FromError(err); e.Code != 400 {
+		t.Fatalf("expected a bad request for an invalid constraint, got %v", err)
+	}
+}
+
+func TestCallRoutingKey(t *testing.T) {
+	nodes := make(map[string]string)
+	var key string
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			if id, ok := nodes[key]; ok && id != node.Id {
+				t.Fatalf("key %s routed to %s and %s", key, id, node.Id)
+			}
+			if _, ok := metadata.Get(ctx, RoutingKeyHeader); ok {
+				t.Fatal("expected the routing key kept from the service")
+			}
+			nodes[key] = node.Id
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+
+	for i := 0; i < 2; i++ {
+		key = "user-1"
+		if err := c.Call(context.Background(), req, nil, WithRoutingKey(key)); err != nil {
+			t.Fatal(err)
+		}
+		key = "user-2"
+		ctx := metadata.Set(context.Background(), RoutingKeyHeader, key)
+		if err := c.Call(ctx, req, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(nodes) != 2 {
+		t.Fatalf("expected both keys called, got %v", nodes)
+	}
+}
+
+func TestCallRoutingKeyDone(t *testing.T) {
+	h := selector.DefaultHash
+	selector.DefaultHash = selector.NewHash()
+	defer func() {
+		selector.DefaultHash = h
+	}()
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(func(cf CallFunc) CallFunc {
+			return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+				return nil
+			}
+		}),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	services, err := r.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a call of another key in flight
+	node, err := selector.DefaultHash.Strategy("user-1")(services)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+
+	// the strategy of the caller picks the node, not the hash
+	if err := c.Call(context.Background(), req, nil, WithRoutingKey("user-2"), WithSelectOption(selector.WithStrategy(nodeStrategy(node.Id)))); err != nil {
+		t.Fatal(err)
+	}
+	if l := selector.DefaultHash.Load(node.Id); l != 1 {
+		t.Fatalf("expected the call in flight to be left alone, got a load of %d", l)
+	}
+
+	// calls routed by the hash are done once they return
+	for i := 0; i < 3; i++ {
+		if err := c.Call(context.Background(), req, nil, WithRoutingKey("user-1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l := selector.DefaultHash.Load(node.Id); l != 1 {
+		t.Fatalf("expected only the call in flight, got a load of %d", l)
+	}
+}
+
+func TestCallAll(t *testing.T) {
+	var mtx sync.Mutex
+	var inflight, max int
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			mtx.Lock()
+			inflight++
+			if inflight > max {
+				max = inflight
+			}
+			mtx.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mtx.Lock()
+			inflight--
+			mtx.Unlock()
+
+			if node.Id == "foo-1.0.3-345" {
+				return errors.InternalServerError("go.micro.client", "failed")
+			}
+			*(rsp.(*string)) = node.Id
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+	newRsp := func() interface{} { return new(string) }
+
+	// every node needs to succeed by default
+	results, err := CallAllWith(context.Background(), c, req, newRsp, WithConcurrency(2))
+	if err == nil {
+		t.Fatal("expected the quorum not to be met")
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if max > 2 {
+		t.Fatalf("expected at most 2 calls at once, got %d", max)
+	}
+	for id, res := range results {
+		if id == "foo-1.0.3-345" {
+			continue
+		}
+		if res.Error != nil || *(res.Response.(*string)) != id {
+			t.Fatalf("unexpected result of %s: %v %v", id, *(res.Response.(*string)), res.Error)
+		}
+	}
+	if errs := results.Errors(); len(errs) != 1 || errs["foo-1.0.3-345"] == nil {
+		t.Fatalf("expected the error of foo-1.0.3-345, got %v", errs)
+	}
+
+	if _, err := CallAllWith(context.Background(), c, req, newRsp, WithQuorum(QuorumMajority)); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the nodes of the version
+	results, err = CallAllWith(context.Background(), c, req, newRsp, WithVersion("1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+}
@@ -1,7 +1,10 @@
 package client
 
 import (
+	"strings"
+
 	"github.com/asim/go-micro/v3/codec"
+	"github.com/asim/go-micro/v3/metadata"
 	"github.com/asim/go-micro/v3/transport"
 )
 
@@ -33,3 +36,16 @@ func (r *rpcResponse) Read() ([]byte, error) {
 
 	return msg.Body, nil
 }
+
+// responseMetadata returns the metadata of a response set by the service,
+// the headers prefixed with metadata.ResponsePrefix, leaving out those
+// of the transport
+func responseMetadata(hdr map[string]string) metadata.Metadata {
+	md := make(metadata.Metadata)
+	for k, v := range hdr {
+		if strings.HasPrefix(k, metadata.ResponsePrefix) {
+			md[strings.TrimPrefix(k, metadata.ResponsePrefix)] = v
+		}
+	}
+	return md
+}
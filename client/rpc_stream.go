@@ -95,6 +95,11 @@ func (r *rpcStream) Recv(msg interface{}) error {
 		return err
 	}
 
+	// keep the headers of the latest response
+	if rsp, ok := r.response.(*rpcResponse); ok {
+		rsp.header = resp.Header
+	}
+
 	switch {
 	case len(resp.Error) > 0:
 		// We've got an error response. Give this to the request;
@@ -162,3 +167,16 @@ func (r *rpcStream) Close() error {
 		return err
 	}
 }
+
+// doneStream calls done once when the stream is closed
+type doneStream struct {
+	Stream
+	once sync.Once
+	done func()
+}
+
+func (d *doneStream) Close() error {
+	err := d.Stream.Close()
+	d.once.Do(d.done)
+	return err
+}
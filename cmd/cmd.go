@@ -2,8 +2,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"os"
 	"strings"
 	"time"
 
@@ -41,6 +43,10 @@ type cmd struct {
 type Option func(o *Options)
 
 var (
+	// ErrPrintConfig is returned by Init once the configuration was printed
+	// with --print_config, the process is expected to exit
+	ErrPrintConfig = errors.New("configuration printed")
+
 	DefaultCmd = newCmd()
 
 	DefaultFlags = []cli.Flag{
@@ -235,6 +241,15 @@ var (
 			EnvVars: []string{"MICRO_CONFIG"},
 			Usage:   "The source of the config to be used to get configuration",
 		},
+		&cli.StringFlag{
+			Name:    "config_file",
+			EnvVars: []string{"MICRO_CONFIG_FILE"},
+			Usage:   "A micro.yaml or micro.json file setting any of the flags. Flags and env vars take precedence",
+		},
+		&cli.BoolFlag{
+			Name:  "print_config",
+			Usage: "Print the effective configuration and the source of each value then exit",
+		},
 	}
 
 	DefaultBrokers = map[string]func(...broker.Option) broker.Broker{}
@@ -331,6 +346,17 @@ func (c *cmd) Options() Options {
 }
 
 func (c *cmd) Before(ctx *cli.Context) error {
+	// Load the flags set in the config file
+	sources, err := loadConfigFile(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool("print_config") {
+		printConfig(ctx.App.Writer, ctx, sources)
+		return ErrPrintConfig
+	}
+
 	// If flags are set then use them otherwise do nothing
 	var serverOpts []server.Option
 	var clientOpts []client.Option
@@ -609,7 +635,14 @@ func (c *cmd) Init(opts ...Option) error {
 	}
 	c.app.HideVersion = len(c.opts.Version) == 0
 	c.app.Usage = c.opts.Description
-	c.app.RunAndExitOnError()
+
+	// the config printed is returned for the caller to exit
+	if err := c.app.Run(os.Args); err == ErrPrintConfig {
+		return err
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		cli.OsExiter(1)
+	}
 	return nil
 }
 
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/asim/go-micro/v3/broker"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/cmd"
+	proto "github.com/asim/go-micro/v3/debug/proto"
+	"github.com/asim/go-micro/v3/metadata"
+	"github.com/asim/go-micro/v3/registry"
+	util "github.com/asim/go-micro/v3/util/broker"
+	"github.com/micro/cli/v2"
+)
+
+// callFlags of the commands calling a service
+func callFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "metadata",
+			Aliases: []string{"m"},
+			Usage:   "A list of key-value pairs sent as metadata. key=value",
+		},
+		&cli.StringFlag{
+			Name:  "address",
+			Usage: "Address of the node to call, skipping selection",
+		},
+	}
+}
+
+// commands of the cli. The plugins are read from the options when a command
+// runs, after the global flags such as --registry have been applied.
+func commands(c cmd.Cmd) []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:    "services",
+			Aliases: []string{"list"},
+			Usage:   "List the services in the registry",
+			Action: func(ctx *cli.Context) error {
+				return listServices(ctx, *c.Options().Registry)
+			},
+		},
+		{
+			Name:      "describe",
+			Usage:     "Describe the nodes, endpoints and schemas of a service",
+			ArgsUsage: "[service]",
+			Action: func(ctx *cli.Context) error {
+				return describeService(ctx, *c.Options().Registry)
+			},
+		},
+		{
+			Name:      "call",
+			Usage:     "Call an endpoint of a service with a json request",
+			ArgsUsage: "[service] [endpoint] [request]",
+			Flags:     callFlags(),
+			Action: func(ctx *cli.Context) error {
+				return callService(ctx, *c.Options().Client)
+			},
+		},
+		{
+			Name:      "stream",
+			Usage:     "Stream the responses of an endpoint of a service",
+			ArgsUsage: "[service] [endpoint] [request]",
+			Flags:     callFlags(),
+			Action: func(ctx *cli.Context) error {
+				return streamService(ctx, *c.Options().Client)
+			},
+		},
+		{
+			Name:      "publish",
+			Usage:     "Publish a json message to a topic",
+			ArgsUsage: "[topic] [message]",
+			Flags:     callFlags()[:1],
+			Action: func(ctx *cli.Context) error {
+				return publish(ctx, *c.Options().Client)
+			},
+		},
+		{
+			Name:      "subscribe",
+			Usage:     "Subscribe to a topic and print the messages",
+			ArgsUsage: "[topic]",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "queue",
+					Usage: "Queue to share the messages with other subscribers",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				return subscribe(ctx, *c.Options().Broker)
+			},
+		},
+		{
+			Name:      "health",
+			Usage:     "Check the health of every node of a service",
+			ArgsUsage: "[service]",
+			Action: func(ctx *cli.Context) error {
+				return debug(ctx, *c.Options().Registry, *c.Options().Client, health)
+			},
+		},
+		{
+			Name:      "stats",
+			Usage:     "Show the stats of every node of a service",
+			ArgsUsage: "[service]",
+			Action: func(ctx *cli.Context) error {
+				return debug(ctx, *c.Options().Registry, *c.Options().Client, stats)
+			},
+		},
+	}
+}
+
+func listServices(ctx *cli.Context, r registry.Registry) error {
+	services, err := r.ListServices()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, s := range services {
+		if !seen[s.Name] {
+			seen[s.Name] = true
+			names = append(names, s.Name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintln(ctx.App.Writer, name)
+	}
+	return nil
+}
+
+func describeService(ctx *cli.Context, r registry.Registry) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("service required")
+	}
+	services, err := r.GetService(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	return printJSON(ctx.App.Writer, services)
+}
+
+func callService(ctx *cli.Context, c client.Client) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("service and endpoint required")
+	}
+
+	body, err := requestBody(ctx, 2)
+	if err != nil {
+		return err
+	}
+
+	req := c.NewRequest(
+		ctx.Args().Get(0),
+		ctx.Args().Get(1),
+		&body,
+		client.WithContentType("application/json"),
+	)
+
+	var rsp json.RawMessage
+	if err := c.Call(callContext(ctx), req, &rsp, callOptions(ctx)...); err != nil {
+		return err
+	}
+	return printRaw(ctx.App.Writer, rsp)
+}
+
+func streamService(ctx *cli.Context, c client.Client) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("service and endpoint required")
+	}
+
+	body, err := requestBody(ctx, 2)
+	if err != nil {
+		return err
+	}
+
+	req := c.NewRequest(
+		ctx.Args().Get(0),
+		ctx.Args().Get(1),
+		&body,
+		client.WithContentType("application/json"),
+		client.StreamingRequest(),
+	)
+
+	stream, err := c.Stream(callContext(ctx), req, callOptions(ctx)...)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if err := stream.Send(&body); err != nil {
+		return err
+	}
+
+	for {
+		var rsp json.RawMessage
+		if err := stream.Recv(&rsp); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := printRaw(ctx.App.Writer, rsp); err != nil {
+			return err
+		}
+	}
+}
+
+func publish(ctx *cli.Context, c client.Client) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("topic required")
+	}
+
+	body, err := requestBody(ctx, 1)
+	if err != nil {
+		return err
+	}
+
+	msg := c.NewMessage(
+		ctx.Args().Get(0),
+		&body,
+		client.WithMessageContentType("application/json"),
+	)
+	return c.Publish(callContext(ctx), msg)
+}
+
+func subscribe(ctx *cli.Context, b broker.Broker) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("topic required")
+	}
+
+	if err := b.Connect(); err != nil {
+		return err
+	}
+	defer b.Disconnect()
+
+	var opts []broker.SubscribeOption
+	if q := ctx.String("queue"); len(q) > 0 {
+		opts = append(opts, broker.Queue(q))
+	}
+
+	// events are delivered concurrently, print one at a time
+	var mtx sync.Mutex
+
+	sub, err := b.Subscribe(ctx.Args().Get(0), func(e broker.Event) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		return printJSON(ctx.App.Writer, map[string]interface{}{
+			"topic":  e.Topic(),
+			"header": e.Message().Header,
+			"body":   util.Body(e.Message()),
+		})
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	// wait to be stopped
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT)
+	<-ch
+	return nil
+}
+
+// debug calls the debug handler of every node of the service
+func debug(ctx *cli.Context, r registry.Registry, c client.Client, fn func(context.Context, proto.DebugService, *registry.Node) (interface{}, error)) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("service required")
+	}
+
+	name := ctx.Args().Get(0)
+	services, err := r.GetService(name)
+	if err != nil {
+		return err
+	}
+
+	debug := proto.NewDebugService(name, c)
+	rsp := make(map[string]interface{})
+
+	for _, s := range services {
+		for _, node := range s.Nodes {
+			v, err := fn(callContext(ctx), debug, node)
+			if err != nil {
+				v = map[string]string{"error": err.Error()}
+			}
+			rsp[node.Id] = map[string]interface{}{
+				"version": s.Version,
+				"address": node.Address,
+				"debug":   v,
+			}
+		}
+	}
+
+	return printJSON(ctx.App.Writer, rsp)
+}
+
+func health(ctx context.Context, d proto.DebugService, node *registry.Node) (interface{}, error) {
+	return d.Health(ctx, &proto.HealthRequest{}, client.WithAddress(node.Address))
+}
+
+func stats(ctx context.Context, d proto.DebugService, node *registry.Node) (interface{}, error) {
+	return d.Stats(ctx, &proto.StatsRequest{}, client.WithAddress(node.Address))
+}
+
+// requestBody is the json argument at the index, read from stdin if it's -
+func requestBody(ctx *cli.Context, i int) (json.RawMessage, error) {
+	arg := ctx.Args().Get(i)
+	switch arg {
+	case "":
+		return json.RawMessage(`{}`), nil
+	case "-":
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		arg = string(b)
+	}
+	if !json.Valid([]byte(arg)) {
+		return nil, fmt.Errorf("invalid json: %s", arg)
+	}
+	return json.RawMessage(arg), nil
+}
+
+// callContext carries the metadata flags
+func callContext(ctx *cli.Context) context.Context {
+	md := make(metadata.Metadata)
+	for _, kv := range ctx.StringSlice("metadata") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			md[parts[0]] = parts[1]
+		}
+	}
+	return metadata.NewContext(context.Background(), md)
+}
+
+func callOptions(ctx *cli.Context) []client.CallOption {
+	var opts []client.CallOption
+	if addr := ctx.String("address"); len(addr) > 0 {
+		opts = append(opts, client.WithAddress(addr))
+	}
+	return opts
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func printRaw(w io.Writer, b []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "  "); err != nil {
+		// not json, print as is
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+	_, err := fmt.Fprintln(w, buf.String())
+	return err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/cmd"
+	"github.com/asim/go-micro/v3/debug/handler"
+	"github.com/asim/go-micro/v3/metadata"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/server"
+)
+
+type Greeter struct{}
+
+func (g *Greeter) Hello(ctx context.Context, req map[string]interface{}, rsp *map[string]interface{}) error {
+	md, _ := metadata.FromContext(ctx)
+	*rsp = map[string]interface{}{"greeting": fmt.Sprintf("hello %v", req["name"]), "user": md["User"]}
+	return nil
+}
+
+func (g *Greeter) Count(ctx context.Context, stream server.Stream) error {
+	var req map[string]interface{}
+	if err := stream.Recv(&req); err != nil {
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(map[string]int{"count": i}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCommands(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := server.NewServer(
+		server.Name("test.greeter"),
+		server.Registry(r),
+		server.Address("127.0.0.1:0"),
+	)
+	if err := s.Handle(s.NewHandler(new(Greeter))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Handle(s.NewHandler(handler.NewHandler(nil), server.InternalHandler(true))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r))
+	command := cmd.NewCmd(cmd.Name("micro"), cmd.Registry(&r), cmd.Client(&c))
+
+	run := func(args ...string) string {
+		var buf bytes.Buffer
+		app := command.App()
+		app.Writer = &buf
+		app.Commands = commands(command)
+		if err := app.Run(append([]string{"micro"}, args...)); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return buf.String()
+	}
+
+	testData := []struct {
+		args   []string
+		expect []string
+	}{
+		{[]string{"services"}, []string{"test.greeter"}},
+		{[]string{"describe", "test.greeter"}, []string{`"name": "Greeter.Hello"`, `"address"`}},
+		{[]string{"call", "-m", "User=john", "test.greeter", "Greeter.Hello", `{"name": "john"}`}, []string{`"greeting": "hello john"`, `"user": "john"`}},
+		{[]string{"stream", "test.greeter", "Greeter.Count"}, []string{`"count": 0`, `"count": 2`}},
+		{[]string{"health", "test.greeter"}, []string{`"status": "ok"`}},
+	}
+
+	for _, d := range testData {
+		out := run(d.args...)
+		for _, e := range d.expect {
+			if !strings.Contains(out, e) {
+				t.Fatalf("%v: expected %s in %s", d.args, e, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Command micro is a command line client for services. It lists and
+// describes services, calls and streams endpoints, publishes and subscribes
+// to topics and reads the health and stats of services.
+//
+// Usage:
+//
+//	micro [--registry mdns] services
+//	micro describe go.micro.srv.greeter
+//	micro call go.micro.srv.greeter Greeter.Hello '{"name": "john"}'
+//	micro stream go.micro.srv.greeter Greeter.Watch '{}'
+//	micro publish go.micro.topic.events '{"id": 1}'
+//	micro subscribe go.micro.topic.events
+//	micro health go.micro.srv.greeter
+//	micro stats go.micro.srv.greeter
+package main
+
+import (
+	"github.com/asim/go-micro/v3/cmd"
+	"github.com/asim/go-micro/v3/logger"
+)
+
+func main() {
+	c := cmd.NewCmd(
+		cmd.Name("micro"),
+		cmd.Description("a command line client for go-micro services"),
+	)
+	app := c.App()
+	app.Commands = commands(c)
+	app.Action = nil
+
+	if err := c.Init(); err == cmd.ErrPrintConfig {
+		return
+	} else if err != nil {
+		logger.Fatal(err)
+	}
+}
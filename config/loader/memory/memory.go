@@ -416,8 +416,8 @@ func (w *watcher) Stop() error {
 	select {
 	case <-w.exit:
 	default:
+		// updates is left open as the loader may still be sending on it
 		close(w.exit)
-		close(w.updates)
 	}
 
 	return nil
@@ -57,6 +57,7 @@ func (d *Debug) Stats(ctx context.Context, req *proto.StatsRequest, rsp *proto.S
 	rsp.Threads = stats[0].Threads
 	rsp.Requests = stats[0].Requests
 	rsp.Errors = stats[0].Errors
+	rsp.SnapshotAge = uint64(stats[0].SnapshotAge)
 
 	return nil
 }
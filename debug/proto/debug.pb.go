@@ -181,7 +181,9 @@ type StatsResponse struct {
 	// total number of requests
 	Requests uint64 `protobuf:"varint,7,opt,name=requests,proto3" json:"requests,omitempty"`
 	// total number of errors
-	Errors               uint64   `protobuf:"varint,8,opt,name=errors,proto3" json:"errors,omitempty"`
+	Errors uint64 `protobuf:"varint,8,opt,name=errors,proto3" json:"errors,omitempty"`
+	// age in seconds of the registry snapshot being served, 0 if none
+	SnapshotAge          uint64   `protobuf:"varint,9,opt,name=snapshot_age,json=snapshotAge,proto3" json:"snapshot_age,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -268,6 +270,13 @@ func (m *StatsResponse) GetErrors() uint64 {
 	return 0
 }
 
+func (m *StatsResponse) GetSnapshotAge() uint64 {
+	if m != nil {
+		return m.SnapshotAge
+	}
+	return 0
+}
+
 // LogRequest requests service logs
 type LogRequest struct {
 	// service to request logs for
@@ -596,42 +605,43 @@ func init() {
 func init() { proto.RegisterFile("proto/debug.proto", fileDescriptor_466b588516b7ea56) }
 
 var fileDescriptor_466b588516b7ea56 = []byte{
-	// 589 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x54, 0xdb, 0x6e, 0xd3, 0x40,
-	0x10, 0x8d, 0xed, 0x38, 0xb1, 0xa7, 0x8d, 0x29, 0xcb, 0x45, 0x96, 0xb9, 0x55, 0x96, 0x90, 0xc2,
-	0x45, 0x2e, 0x94, 0x17, 0x04, 0x6f, 0xa8, 0x48, 0x20, 0x95, 0x56, 0xda, 0xb6, 0x1f, 0xb0, 0xb5,
-	0x47, 0xae, 0xa1, 0xbe, 0xb0, 0xbb, 0xae, 0x94, 0x6f, 0xe1, 0x0b, 0x78, 0xe3, 0x67, 0xf8, 0x1f,
-	0xb4, 0x17, 0xa7, 0xb1, 0x10, 0xea, 0x03, 0x6f, 0x7b, 0xce, 0xce, 0x9e, 0xcc, 0x9c, 0x1c, 0x0f,
-	0xdc, 0xee, 0x78, 0x2b, 0xdb, 0xbd, 0x02, 0xcf, 0xfb, 0x32, 0xd3, 0xe7, 0xf4, 0x19, 0x2c, 0x3e,
-	0x21, 0xbb, 0x94, 0x17, 0x14, 0xbf, 0xf7, 0x28, 0x24, 0x89, 0x61, 0x2e, 0x90, 0x5f, 0x55, 0x39,
-	0xc6, 0xce, 0xae, 0xb3, 0x0c, 0xe9, 0x00, 0xd3, 0x25, 0x44, 0x43, 0xa9, 0xe8, 0xda, 0x46, 0x20,
-	0xb9, 0x0f, 0x33, 0x21, 0x99, 0xec, 0x85, 0x2d, 0xb5, 0x28, 0x5d, 0xc2, 0xf6, 0x89, 0x64, 0x52,
-	0xdc, 0xac, 0xf9, 0xdb, 0x81, 0x85, 0x2d, 0xb5, 0x9a, 0x0f, 0x21, 0x94, 0x55, 0x8d, 0x42, 0xb2,
-	0xba, 0xd3, 0xd5, 0x53, 0x7a, 0x4d, 0x68, 0x25, 0xc9, 0xb8, 0xc4, 0x22, 0x76, 0xf5, 0xdd, 0x00,
-	0x55, 0x2f, 0x7d, 0xa7, 0x0a, 0x63, 0x4f, 0x5f, 0x58, 0xa4, 0xf8, 0x1a, 0xeb, 0x96, 0xaf, 0xe2,
-	0xa9, 0xe1, 0x0d, 0x52, 0x4a, 0xf2, 0x82, 0x23, 0x2b, 0x44, 0xec, 0x1b, 0x25, 0x0b, 0x49, 0x04,
-	0x6e, 0x99, 0xc7, 0x33, 0x4d, 0xba, 0x65, 0x4e, 0x12, 0x08, 0xb8, 0x19, 0x44, 0xc4, 0x73, 0xcd,
-	0xae, 0xb1, 0x52, 0x47, 0xce, 0x5b, 0x2e, 0xe2, 0xc0, 0xa8, 0x1b, 0x94, 0x7e, 0x05, 0x38, 0x6c,
-	0xcb, 0x1b, 0xe7, 0x37, 0x0e, 0x72, 0x64, 0xb5, 0x1e, 0x27, 0xa0, 0x16, 0x91, 0xbb, 0xe0, 0xe7,
-	0x6d, 0xdf, 0x48, 0x3d, 0x8c, 0x47, 0x0d, 0x50, 0xac, 0xa8, 0x9a, 0x1c, 0xf5, 0x28, 0x1e, 0x35,
-	0x20, 0xfd, 0xe5, 0xc0, 0x8c, 0x62, 0xde, 0xf2, 0xe2, 0x6f, 0xf3, 0xbc, 0x4d, 0xf3, 0x5e, 0x43,
-	0x50, 0xa3, 0x64, 0x05, 0x93, 0x2c, 0x76, 0x77, 0xbd, 0xe5, 0xd6, 0xfe, 0xbd, 0xcc, 0x3c, 0xcc,
-	0xbe, 0x58, 0xfe, 0x63, 0x23, 0xf9, 0x8a, 0xae, 0xcb, 0x54, 0xe7, 0x35, 0x0a, 0xc1, 0x4a, 0x63,
-	0x6b, 0x48, 0x07, 0x98, 0xbc, 0x87, 0xc5, 0xe8, 0x11, 0xd9, 0x01, 0xef, 0x1b, 0xae, 0xec, 0x80,
-	0xea, 0xa8, 0xda, 0xbd, 0x62, 0x97, 0x3d, 0xea, 0xd9, 0x42, 0x6a, 0xc0, 0x3b, 0xf7, 0xad, 0x93,
-	0x3e, 0x86, 0xed, 0x53, 0xce, 0x72, 0x1c, 0x0c, 0x8a, 0xc0, 0xad, 0x0a, 0xfb, 0xd4, 0xad, 0x8a,
-	0xf4, 0x25, 0x2c, 0xec, 0xbd, 0x4d, 0xc5, 0x03, 0xf0, 0x45, 0xc7, 0x1a, 0x15, 0x34, 0xd5, 0xb7,
-	0x9f, 0x9d, 0x74, 0xac, 0xa1, 0x86, 0x4b, 0x7f, 0xb8, 0x30, 0x55, 0x58, 0xfd, 0xa0, 0x54, 0xcf,
-	0xac, 0x92, 0x01, 0x56, 0xdc, 0x1d, 0xc4, 0x95, 0xe7, 0x1d, 0xe3, 0x68, 0xcd, 0x0d, 0xa9, 0x45,
-	0x84, 0xc0, 0xb4, 0x61, 0xb5, 0x31, 0x37, 0xa4, 0xfa, 0xbc, 0x99, 0x37, 0x7f, 0x9c, 0xb7, 0x04,
-	0x82, 0xa2, 0xe7, 0x4c, 0x56, 0x6d, 0x63, 0xb3, 0xb2, 0xc6, 0x64, 0x6f, 0xc3, 0xe8, 0xb9, 0x6e,
-	0xf8, 0x8e, 0x6e, 0xf8, 0x9f, 0x36, 0x3f, 0x82, 0xa9, 0x5c, 0x75, 0xa8, 0x43, 0x14, 0xed, 0x87,
-	0xba, 0xf8, 0x74, 0xd5, 0x21, 0xd5, 0xf4, 0x7f, 0x79, 0xfd, 0xfc, 0x29, 0x04, 0x83, 0x1c, 0xd9,
-	0x82, 0xf9, 0xe7, 0xa3, 0x0f, 0xc7, 0x67, 0x47, 0x07, 0x3b, 0x13, 0xb2, 0x0d, 0xc1, 0xf1, 0xd9,
-	0xa9, 0x41, 0xce, 0xfe, 0x4f, 0x07, 0xfc, 0x03, 0xb5, 0x18, 0xc8, 0x13, 0xf0, 0x0e, 0xdb, 0x92,
-	0x6c, 0x65, 0xd7, 0x09, 0x4e, 0xe6, 0x36, 0x28, 0xe9, 0xe4, 0x95, 0x43, 0x5e, 0xc0, 0xcc, 0x2c,
-	0x02, 0x12, 0x65, 0xa3, 0xe5, 0x91, 0xdc, 0xca, 0xc6, 0x1b, 0x22, 0x9d, 0x90, 0x25, 0xf8, 0xfa,
-	0x03, 0x27, 0x8b, 0x6c, 0x73, 0x27, 0x24, 0x51, 0x36, 0xfa, 0xee, 0x4d, 0xa5, 0xfe, 0xd3, 0xc9,
-	0x22, 0xdb, 0x0c, 0x47, 0x12, 0x65, 0xa3, 0x2c, 0xa4, 0x93, 0xf3, 0x99, 0xde, 0x5d, 0x6f, 0xfe,
-	0x04, 0x00, 0x00, 0xff, 0xff, 0x4c, 0x2d, 0xec, 0xd8, 0xd0, 0x04, 0x00, 0x00,
+	// 607 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x54, 0x4b, 0x6f, 0xd3, 0x4c,
+	0x14, 0x8d, 0xed, 0x38, 0xb1, 0x6f, 0x12, 0x7f, 0xfd, 0x86, 0x87, 0x2c, 0xf3, 0x2a, 0x96, 0x90,
+	0xc2, 0x43, 0x2e, 0x94, 0x0d, 0x82, 0x15, 0xa8, 0x48, 0x20, 0x95, 0x56, 0x9a, 0xb6, 0x6b, 0x34,
+	0xb5, 0xaf, 0xdc, 0x40, 0xfd, 0x60, 0x66, 0x5c, 0x29, 0xbf, 0x85, 0x5f, 0xc0, 0x8e, 0x9f, 0xc7,
+	0x12, 0xcd, 0xc3, 0x6d, 0x2c, 0x84, 0xba, 0x60, 0x37, 0xe7, 0xcc, 0x9d, 0xe3, 0x7b, 0x8f, 0x8f,
+	0x2e, 0xfc, 0xdf, 0xf2, 0x46, 0x36, 0x3b, 0x05, 0x9e, 0x76, 0x65, 0xa6, 0xcf, 0xe9, 0x63, 0x58,
+	0x7c, 0x40, 0x76, 0x2e, 0xcf, 0x28, 0x7e, 0xeb, 0x50, 0x48, 0x12, 0xc3, 0x54, 0x20, 0xbf, 0x58,
+	0xe5, 0x18, 0x3b, 0xdb, 0xce, 0x32, 0xa4, 0x3d, 0x4c, 0x97, 0x10, 0xf5, 0xa5, 0xa2, 0x6d, 0x6a,
+	0x81, 0xe4, 0x36, 0x4c, 0x84, 0x64, 0xb2, 0x13, 0xb6, 0xd4, 0xa2, 0x74, 0x09, 0xf3, 0x23, 0xc9,
+	0xa4, 0xb8, 0x5e, 0xf3, 0x97, 0x03, 0x0b, 0x5b, 0x6a, 0x35, 0xef, 0x42, 0x28, 0x57, 0x15, 0x0a,
+	0xc9, 0xaa, 0x56, 0x57, 0x8f, 0xe9, 0x15, 0xa1, 0x95, 0x24, 0xe3, 0x12, 0x8b, 0xd8, 0xd5, 0x77,
+	0x3d, 0x54, 0xbd, 0x74, 0xad, 0x2a, 0x8c, 0x3d, 0x7d, 0x61, 0x91, 0xe2, 0x2b, 0xac, 0x1a, 0xbe,
+	0x8e, 0xc7, 0x86, 0x37, 0x48, 0x29, 0xc9, 0x33, 0x8e, 0xac, 0x10, 0xb1, 0x6f, 0x94, 0x2c, 0x24,
+	0x11, 0xb8, 0x65, 0x1e, 0x4f, 0x34, 0xe9, 0x96, 0x39, 0x49, 0x20, 0xe0, 0x66, 0x10, 0x11, 0x4f,
+	0x35, 0x7b, 0x89, 0x95, 0x3a, 0x72, 0xde, 0x70, 0x11, 0x07, 0x46, 0xdd, 0x20, 0xf2, 0x10, 0xe6,
+	0xa2, 0x66, 0xad, 0x38, 0x6b, 0xe4, 0x67, 0x56, 0x62, 0x1c, 0xea, 0xdb, 0x59, 0xcf, 0xbd, 0x2d,
+	0x31, 0xfd, 0x02, 0xb0, 0xdf, 0x94, 0xd7, 0x5a, 0x64, 0x4c, 0xe6, 0xc8, 0x2a, 0x3d, 0x71, 0x40,
+	0x2d, 0x22, 0x37, 0xc1, 0xcf, 0x9b, 0xae, 0x96, 0x7a, 0x5e, 0x8f, 0x1a, 0xa0, 0x58, 0xb1, 0xaa,
+	0x73, 0xd4, 0xd3, 0x7a, 0xd4, 0x80, 0xf4, 0xa7, 0x03, 0x13, 0x8a, 0x79, 0xc3, 0x8b, 0x3f, 0xfd,
+	0xf5, 0x36, 0xfd, 0x7d, 0x01, 0x41, 0x85, 0x92, 0x15, 0x4c, 0xb2, 0xd8, 0xdd, 0xf6, 0x96, 0xb3,
+	0xdd, 0x5b, 0x99, 0x79, 0x98, 0x7d, 0xb2, 0xfc, 0xfb, 0x5a, 0xf2, 0x35, 0xbd, 0x2c, 0x53, 0x9d,
+	0x57, 0x28, 0x84, 0x9a, 0xd2, 0x33, 0x9d, 0x5b, 0x98, 0xbc, 0x81, 0xc5, 0xe0, 0x11, 0xd9, 0x02,
+	0xef, 0x2b, 0xae, 0xed, 0x80, 0xea, 0xa8, 0xda, 0xbd, 0x60, 0xe7, 0x1d, 0xea, 0xd9, 0x42, 0x6a,
+	0xc0, 0x6b, 0xf7, 0x95, 0x93, 0xde, 0x87, 0xf9, 0x31, 0x67, 0x39, 0xf6, 0x06, 0x45, 0xe0, 0xae,
+	0x0a, 0xfb, 0xd4, 0x5d, 0x15, 0xe9, 0x33, 0x58, 0xd8, 0x7b, 0x1b, 0x9c, 0x3b, 0xe0, 0x8b, 0x96,
+	0xd5, 0x2a, 0x8b, 0xaa, 0x6f, 0x3f, 0x3b, 0x6a, 0x59, 0x4d, 0x0d, 0x97, 0x7e, 0x77, 0x61, 0xac,
+	0xb0, 0xfa, 0xa0, 0x54, 0xcf, 0xac, 0x92, 0x01, 0x56, 0xdc, 0xed, 0xc5, 0x95, 0xe7, 0x2d, 0xe3,
+	0x68, 0xcd, 0x0d, 0xa9, 0x45, 0x84, 0xc0, 0xb8, 0x66, 0x95, 0x31, 0x37, 0xa4, 0xfa, 0xbc, 0x19,
+	0x49, 0x7f, 0x18, 0xc9, 0x04, 0x82, 0xa2, 0xe3, 0x4c, 0xae, 0x9a, 0xda, 0xc6, 0xe9, 0x12, 0x93,
+	0x9d, 0x0d, 0xa3, 0xa7, 0xba, 0xe1, 0x1b, 0xba, 0xe1, 0xbf, 0xda, 0x7c, 0x0f, 0xc6, 0x72, 0xdd,
+	0xa2, 0xce, 0x59, 0xb4, 0x1b, 0xea, 0xe2, 0xe3, 0x75, 0x8b, 0x54, 0xd3, 0xff, 0xe4, 0xf5, 0x93,
+	0x47, 0x10, 0xf4, 0x72, 0x64, 0x06, 0xd3, 0x8f, 0x07, 0xef, 0x0e, 0x4f, 0x0e, 0xf6, 0xb6, 0x46,
+	0x64, 0x0e, 0xc1, 0xe1, 0xc9, 0xb1, 0x41, 0xce, 0xee, 0x0f, 0x07, 0xfc, 0x3d, 0xb5, 0x3b, 0xc8,
+	0x03, 0xf0, 0xf6, 0x9b, 0x92, 0xcc, 0xb2, 0xab, 0x04, 0x27, 0x53, 0x1b, 0x94, 0x74, 0xf4, 0xdc,
+	0x21, 0x4f, 0x61, 0x62, 0x76, 0x05, 0x89, 0xb2, 0xc1, 0x7e, 0x49, 0xfe, 0xcb, 0x86, 0x4b, 0x24,
+	0x1d, 0x91, 0x25, 0xf8, 0x7a, 0x07, 0x90, 0x45, 0xb6, 0xb9, 0x36, 0x92, 0x28, 0x1b, 0xac, 0x06,
+	0x53, 0xa9, 0x7f, 0x3a, 0x59, 0x64, 0x9b, 0xe1, 0x48, 0xa2, 0x6c, 0x90, 0x85, 0x74, 0x74, 0x3a,
+	0xd1, 0xeb, 0xed, 0xe5, 0xef, 0x01, 0x00, 0xc4, 0xe3, 0x48, 0x40, 0xf3, 0x04, 0x00, 0x00,
 }
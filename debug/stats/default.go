@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/asim/go-micro/v3/registry/cache"
 	"github.com/asim/go-micro/v3/util/ring"
 )
 
@@ -28,14 +29,16 @@ func (s *stats) snapshot() *Stat {
 	now := time.Now().Unix()
 
 	return &Stat{
-		Timestamp: now,
-		Started:   s.started,
-		Uptime:    now - s.started,
-		Memory:    mstat.Alloc,
-		GC:        mstat.PauseTotalNs,
-		Threads:   uint64(runtime.NumGoroutine()),
-		Requests:  s.requests,
-		Errors:    s.errors,
+		Timestamp:   now,
+		Started:     s.started,
+		Uptime:      now - s.started,
+		Memory:      mstat.Alloc,
+		GC:          mstat.PauseTotalNs,
+		Threads:     uint64(runtime.NumGoroutine()),
+		Requests:    s.requests,
+		Errors:      s.errors,
+		SnapshotAge: int64(cache.SnapshotAge() / time.Second),
+		Versions:    versions(),
 	}
 }
 
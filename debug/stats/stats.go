@@ -29,6 +29,10 @@ type Stat struct {
 	Requests uint64
 	// Total errors
 	Errors uint64
+	// Age in seconds of the registry snapshot being served, zero if none
+	SnapshotAge int64
+	// Traffic split between the versions of services
+	Versions []Version
 }
 
 var (
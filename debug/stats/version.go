@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+)
+
+// Version stat of the traffic sent to a version of a service
+type Version struct {
+	Service string
+	Version string
+	// Weight of the version when it was last selected
+	Weight int
+	// Number of times the version was selected
+	Selected uint64
+}
+
+var (
+	vmtx  sync.RWMutex
+	vstat = make(map[string]map[string]*Version)
+)
+
+// RecordVersion records the selection of a version of a service
+// with its weight e.g. by a selector splitting traffic by version
+func RecordVersion(service, version string, weight int) {
+	vmtx.Lock()
+	defer vmtx.Unlock()
+
+	if _, ok := vstat[service]; !ok {
+		vstat[service] = make(map[string]*Version)
+	}
+	v, ok := vstat[service][version]
+	if !ok {
+		v = &Version{Service: service, Version: version}
+		vstat[service][version] = v
+	}
+	v.Weight = weight
+	v.Selected++
+}
+
+// versions selected, sorted by service and version
+func versions() []Version {
+	vmtx.RLock()
+	defer vmtx.RUnlock()
+
+	var vs []Version
+	for _, versions := range vstat {
+		for _, v := range versions {
+			vs = append(vs, *v)
+		}
+	}
+
+	sort.Slice(vs, func(i, j int) bool {
+		if vs[i].Service == vs[j].Service {
+			return vs[i].Version < vs[j].Version
+		}
+		return vs[i].Service < vs[j].Service
+	})
+	return vs
+}
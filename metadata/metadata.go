@@ -8,6 +8,10 @@ import (
 
 type metadataKey struct{}
 
+// ResponsePrefix of the headers carrying the metadata a service sets for a
+// response, telling it apart from the headers of the transport
+const ResponsePrefix = "Micro-Response-"
+
 // Metadata is our way of representing request headers internally.
 // They're used at the RPC level and translate back and forth
 // from Transport headers.
@@ -45,6 +45,8 @@ type Options struct {
 	Context context.Context
 
 	Signal bool
+	// Reflection registers the reflection handler
+	Reflection bool
 }
 
 func newOptions(opts ...Option) Options {
@@ -110,6 +112,14 @@ func HandleSignal(b bool) Option {
 	}
 }
 
+// Reflection registers a handler next to the debug handler which lists
+// the handlers, subscribers and endpoint schemas of the service
+func Reflection(b bool) Option {
+	return func(o *Options) {
+		o.Reflection = b
+	}
+}
+
 // Profile to be used for debug profile
 func Profile(p profile.Profile) Option {
 	return func(o *Options) {
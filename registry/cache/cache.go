@@ -24,6 +24,11 @@ type Cache interface {
 type Options struct {
 	// TTL is the cache TTL
 	TTL time.Duration
+	// Snapshot is the file the cache is persisted to
+	Snapshot string
+	// SnapshotTTL is how long services of the snapshot are served
+	// without the registry confirming them
+	SnapshotTTL time.Duration
 }
 
 type Option func(o *Options)
@@ -37,6 +42,16 @@ type cache struct {
 	cache   map[string][]*registry.Service
 	ttls    map[string]time.Time
 	watched map[string]bool
+	// last time services were read from the registry
+	updated map[string]time.Time
+	// services loaded from the snapshot which weren't read since
+	stale map[string]bool
+	// last time stale services were served
+	served map[string]time.Time
+	// signals the snapshot needs saving
+	changed chan bool
+	// last warning of serving stale services
+	warned time.Time
 
 	// used to stop the cache
 	exit chan bool
@@ -53,6 +68,8 @@ type cache struct {
 
 var (
 	DefaultTTL = time.Minute
+	// DefaultSnapshotTTL of the services loaded from a snapshot
+	DefaultSnapshotTTL = 24 * time.Hour
 )
 
 func backoff(attempts int) time.Duration {
@@ -112,6 +129,10 @@ func (c *cache) del(service string) {
 	// otherwise delete entries
 	delete(c.cache, service)
 	delete(c.ttls, service)
+	delete(c.updated, service)
+	delete(c.stale, service)
+	delete(c.served, service)
+	c.markChanged()
 }
 
 func (c *cache) get(service string) ([]*registry.Service, error) {
@@ -140,10 +161,11 @@ func (c *cache) get(service string) ([]*registry.Service, error) {
 		})
 		services, _ := val.([]*registry.Service)
 		if err != nil {
-			// check the cache
-			if len(cached) > 0 {
+			// check the cache, snapshots are only served until they expire
+			if len(cached) > 0 && !c.expire(service, err) {
 				// set the error status
 				c.setStatus(err)
+				c.warnStale(service, err)
 
 				// return the stale cache
 				return cached, nil
@@ -193,6 +215,10 @@ func (c *cache) get(service string) ([]*registry.Service, error) {
 func (c *cache) set(service string, services []*registry.Service) {
 	c.cache[service] = services
 	c.ttls[service] = time.Now().Add(c.opts.TTL)
+	c.updated[service] = time.Now()
+	delete(c.stale, service)
+	delete(c.served, service)
+	c.markChanged()
 }
 
 func (c *cache) update(res *registry.Result) {
@@ -467,19 +493,36 @@ func (c *cache) String() string {
 func New(r registry.Registry, opts ...Option) Cache {
 	rand.Seed(time.Now().UnixNano())
 	options := Options{
-		TTL: DefaultTTL,
+		TTL:         DefaultTTL,
+		SnapshotTTL: DefaultSnapshotTTL,
 	}
 
 	for _, o := range opts {
 		o(&options)
 	}
 
-	return &cache{
+	c := &cache{
 		Registry: r,
 		opts:     options,
 		watched:  make(map[string]bool),
 		cache:    make(map[string][]*registry.Service),
 		ttls:     make(map[string]time.Time),
+		updated:  make(map[string]time.Time),
+		stale:    make(map[string]bool),
+		served:   make(map[string]time.Time),
 		exit:     make(chan bool),
 	}
+
+	if len(options.Snapshot) > 0 {
+		c.changed = make(chan bool, 1)
+		c.loadSnapshot()
+
+		snapshotsMu.Lock()
+		snapshots[c] = true
+		snapshotsMu.Unlock()
+
+		go c.snapshotLoop()
+	}
+
+	return c
 }
@@ -10,3 +10,20 @@ func WithTTL(t time.Duration) Option {
 		o.TTL = t
 	}
 }
+
+// WithSnapshot persists the cache to the file at path whenever it changes.
+// The snapshot is loaded by New and its services served when the registry
+// returns an error, so services can still be discovered during an outage.
+func WithSnapshot(path string) Option {
+	return func(o *Options) {
+		o.Snapshot = path
+	}
+}
+
+// WithSnapshotTTL sets how long services loaded from the snapshot are
+// served without the registry confirming them, after which they're dropped
+func WithSnapshotTTL(t time.Duration) Option {
+	return func(o *Options) {
+		o.SnapshotTTL = t
+	}
+}
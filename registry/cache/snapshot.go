@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/registry"
+	util "github.com/asim/go-micro/v3/util/registry"
+)
+
+var (
+	// caches persisting snapshots, used to report the age of stale services
+	snapshotsMu sync.RWMutex
+	snapshots   = make(map[*cache]bool)
+
+	// warnInterval between warnings of serving stale services
+	warnInterval = time.Minute
+)
+
+// snapshot of the cache persisted to disk
+type snapshot struct {
+	Services map[string]*snapshotEntry `json:"services"`
+}
+
+type snapshotEntry struct {
+	// Updated is when the services were last read from the registry
+	Updated  time.Time           `json:"updated"`
+	Services []*registry.Service `json:"services"`
+}
+
+// SnapshotAge is the age of the oldest services read from a snapshot which
+// caches served recently, within their ttl, zero if none were
+func SnapshotAge() time.Duration {
+	snapshotsMu.RLock()
+	defer snapshotsMu.RUnlock()
+
+	var age time.Duration
+	for c := range snapshots {
+		if a := c.snapshotAge(); a > age {
+			age = a
+		}
+	}
+	return age
+}
+
+// snapshotAge of the oldest stale service the cache served recently
+func (c *cache) snapshotAge() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+
+	var age time.Duration
+	for service, served := range c.served {
+		if !c.stale[service] || time.Since(served) > c.opts.TTL {
+			continue
+		}
+		if a := time.Since(c.updated[service]); a > age {
+			age = a
+		}
+	}
+	return age
+}
+
+// expired is whether the service is stale and older than the snapshot ttl
+func (c *cache) expired(service string) bool {
+	return c.stale[service] && time.Since(c.updated[service]) > c.opts.SnapshotTTL
+}
+
+// expire the service if it's stale and the registry didn't confirm it
+// within the snapshot ttl or reported it not found, returning whether it was
+func (c *cache) expire(service string, err error) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.expired(service) && !(c.stale[service] && err == registry.ErrNotFound) {
+		return false
+	}
+	delete(c.cache, service)
+	delete(c.ttls, service)
+	delete(c.updated, service)
+	delete(c.stale, service)
+	delete(c.served, service)
+	c.markChanged()
+	return true
+}
+
+// loadSnapshot reads the services into the cache marking them as stale.
+// They're served only when the registry can't be reached.
+func (c *cache) loadSnapshot() {
+	b, err := ioutil.ReadFile(c.opts.Snapshot)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+			logger.Warnf("rcache: error reading snapshot %s: %v", c.opts.Snapshot, err)
+		}
+		return
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+			logger.Warnf("rcache: error reading snapshot %s: %v", c.opts.Snapshot, err)
+		}
+		return
+	}
+
+	c.Lock()
+	for service, e := range s.Services {
+		if len(e.Services) == 0 || time.Since(e.Updated) > c.opts.SnapshotTTL {
+			continue
+		}
+		c.cache[service] = e.Services
+		c.updated[service] = e.Updated
+		c.stale[service] = true
+	}
+	c.Unlock()
+
+	if logger.V(logger.InfoLevel, logger.DefaultLogger) {
+		logger.Infof("rcache: loaded %d services from snapshot %s", len(s.Services), c.opts.Snapshot)
+	}
+}
+
+// saveSnapshot writes the cache to a temporary file replacing the snapshot
+func (c *cache) saveSnapshot() error {
+	s := snapshot{Services: make(map[string]*snapshotEntry)}
+
+	c.RLock()
+	for service, services := range c.cache {
+		if c.expired(service) {
+			continue
+		}
+		s.Services[service] = &snapshotEntry{
+			Updated:  c.updated[service],
+			Services: util.Copy(services),
+		}
+	}
+	c.RUnlock()
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	path := c.opts.Snapshot
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// snapshotLoop saves the snapshot whenever the cache changes
+func (c *cache) snapshotLoop() {
+	for {
+		select {
+		case <-c.changed:
+			if err := c.saveSnapshot(); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("rcache: error saving snapshot %s: %v", c.opts.Snapshot, err)
+				}
+			}
+		case <-c.exit:
+			snapshotsMu.Lock()
+			delete(snapshots, c)
+			snapshotsMu.Unlock()
+			return
+		}
+	}
+}
+
+// markChanged signals the snapshot needs saving
+func (c *cache) markChanged() {
+	if c.changed == nil {
+		return
+	}
+	select {
+	case c.changed <- true:
+	default:
+	}
+}
+
+// warnStale records the stale services of a snapshot were served and
+// logs a warning, at most once every warnInterval
+func (c *cache) warnStale(service string, err error) {
+	c.Lock()
+	stale := c.stale[service]
+	if stale {
+		c.served[service] = time.Now()
+	}
+	age := time.Since(c.updated[service])
+	warn := stale && time.Since(c.warned) > warnInterval
+	if warn {
+		c.warned = time.Now()
+	}
+	c.Unlock()
+
+	if warn && logger.V(logger.WarnLevel, logger.DefaultLogger) {
+		logger.Warnf("rcache: registry error %v, serving %s from a snapshot %s old", err, service, age.Round(time.Second))
+	}
+}
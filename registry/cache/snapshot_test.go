@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+// failingRegistry can't be reached
+type failingRegistry struct {
+	registry.Registry
+}
+
+func (f *failingRegistry) GetService(string, ...registry.GetOption) ([]*registry.Service, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (f *failingRegistry) Watch(...registry.WatchOption) (registry.Watcher, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.json")
+
+	r := registry.NewMemoryRegistry()
+	if err := r.Register(&registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-1", Address: "10.0.0.1:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(r, WithSnapshot(path))
+	if _, err := c.GetService("greeter"); err != nil {
+		t.Fatal(err)
+	}
+	if age := SnapshotAge(); age != 0 {
+		t.Fatalf("expected no snapshot to be served, got an age of %v", age)
+	}
+
+	// the snapshot is saved in the background
+	for i := 0; ; i++ {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatal("snapshot not saved")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Stop()
+
+	// the registry is down when the next cache starts
+	c = New(&failingRegistry{r}, WithSnapshot(path))
+	defer c.Stop()
+
+	services, err := c.GetService("greeter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].Nodes[0].Id != "greeter-1" {
+		t.Fatalf("expected the services of the snapshot, got %+v", services)
+	}
+	if age := SnapshotAge(); age <= 0 {
+		t.Fatalf("expected the age of the snapshot served, got %v", age)
+	}
+
+	if _, err := c.GetService("foo"); err == nil {
+		t.Fatal("expected an error for a service not in the snapshot")
+	}
+}
+
+func TestSnapshotExpiry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.json")
+
+	entry := func(name string, updated time.Time) *snapshotEntry {
+		return &snapshotEntry{
+			Updated: updated,
+			Services: []*registry.Service{{
+				Name:    name,
+				Version: "1.0.0",
+				Nodes:   []*registry.Node{{Id: name + "-1", Address: "10.0.0.1:8080"}},
+			}},
+		}
+	}
+	b, err := json.Marshal(snapshot{Services: map[string]*snapshotEntry{
+		"greeter": entry("greeter", time.Now().Add(-time.Minute)),
+		"old":     entry("old", time.Now().Add(-2*time.Hour)),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, path + ".2"} {
+		if err := ioutil.WriteFile(p, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New(&failingRegistry{registry.NewMemoryRegistry()}, WithSnapshot(path), WithSnapshotTTL(time.Hour)).(*cache)
+	defer c.Stop()
+
+	// services not served aren't reported
+	if age := c.snapshotAge(); age != 0 {
+		t.Fatalf("expected no age before serving the snapshot, got %v", age)
+	}
+	if _, err := c.GetService("old"); err == nil {
+		t.Fatal("expected services older than the snapshot ttl to be dropped")
+	}
+	if _, err := c.GetService("greeter"); err != nil {
+		t.Fatal(err)
+	}
+	if age := c.snapshotAge(); age < time.Minute {
+		t.Fatalf("expected the age of the service served, got %v", age)
+	}
+
+	// unconfirmed services expire
+	c.Lock()
+	c.opts.SnapshotTTL = time.Second
+	c.Unlock()
+	if _, err := c.GetService("greeter"); err == nil {
+		t.Fatal("expected the expired service not to be served")
+	}
+	if age := c.snapshotAge(); age != 0 {
+		t.Fatalf("expected no age once expired, got %v", age)
+	}
+
+	// and services the registry doesn't have are dropped
+	path += ".2"
+	c = New(registry.NewMemoryRegistry(), WithSnapshot(path), WithSnapshotTTL(time.Hour)).(*cache)
+	defer c.Stop()
+	if _, err := c.GetService("greeter"); err != registry.ErrNotFound {
+		t.Fatalf("expected the service not to be found, got %v", err)
+	}
+	if err := c.saveSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Services) != 0 {
+		t.Fatalf("expected dropped services not to be persisted, got %v", s.Services)
+	}
+}
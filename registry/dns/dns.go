@@ -0,0 +1,432 @@
+// Package dns serves the services of a registry over DNS so anything which
+// can resolve a name, such as nginx or a sidecar, can find their nodes.
+//
+// A service is served at its name under the domain e.g. greeter.micro.local
+// which resolves to the A and AAAA records of its nodes. SRV queries of the
+// name, or of _greeter._tcp.micro.local, return the port and weight of every
+// node targeting <node id>.greeter.micro.local. TXT records hold the version
+// and metadata of the nodes.
+package dns
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/registry/cache"
+	miekg "github.com/miekg/dns"
+)
+
+// WeightKey of the node metadata holding its SRV weight
+const WeightKey = "weight"
+
+// Server answering queries for the services of a registry
+type Server struct {
+	opts Options
+
+	sync.RWMutex
+	// last time a service changed in the registry
+	changed map[string]time.Time
+	cache   cache.Cache
+	udp     *miekg.Server
+	tcp     *miekg.Server
+	addr    string
+	exit    chan bool
+}
+
+// Start listening for udp and tcp queries
+func (s *Server) Start() error {
+	pc, err := net.ListenPacket("udp", s.opts.Address)
+	if err != nil {
+		return err
+	}
+	// tcp on the same port, which may have been picked for the udp listener
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		return err
+	}
+
+	s.Lock()
+	s.addr = pc.LocalAddr().String()
+	s.udp = &miekg.Server{PacketConn: pc, Handler: s}
+	s.tcp = &miekg.Server{Listener: l, Handler: s}
+	s.cache = cache.New(s.opts.Registry)
+	s.exit = make(chan bool)
+	udp, tcp, exit := s.udp, s.tcp, s.exit
+	s.Unlock()
+
+	go s.watch(exit)
+
+	for _, srv := range []*miekg.Server{udp, tcp} {
+		go func(srv *miekg.Server) {
+			if err := srv.ActivateAndServe(); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("dns server error: %v", err)
+				}
+			}
+		}(srv)
+	}
+
+	if logger.V(logger.InfoLevel, logger.DefaultLogger) {
+		logger.Infof("DNS server listening on %s serving %s", s.addr, s.opts.Domain)
+	}
+
+	return nil
+}
+
+// Stop the server
+func (s *Server) Stop() error {
+	s.Lock()
+	udp, tcp, exit, c := s.udp, s.tcp, s.exit, s.cache
+	s.udp, s.tcp, s.exit, s.cache = nil, nil, nil, nil
+	s.Unlock()
+
+	if exit == nil {
+		return nil
+	}
+	close(exit)
+	c.Stop()
+
+	var err error
+	for _, srv := range []*miekg.Server{udp, tcp} {
+		if e := srv.Shutdown(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Address the server is listening on
+func (s *Server) Address() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.addr
+}
+
+// watch the registry for changes to lower the ttl of services which changed
+func (s *Server) watch(exit chan bool) {
+	for {
+		w, err := s.opts.Registry.Watch()
+		if err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("dns server error watching registry: %v", err)
+			}
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-exit:
+				return
+			}
+		}
+
+		// stop the watcher on exit, or once it's done with
+		done := make(chan bool)
+		go func(w registry.Watcher) {
+			select {
+			case <-exit:
+			case <-done:
+			}
+			w.Stop()
+		}(w)
+
+		for {
+			res, err := w.Next()
+			if err != nil {
+				break
+			}
+			if res.Service == nil {
+				continue
+			}
+			s.change(res.Service.Name)
+		}
+		close(done)
+
+		select {
+		case <-exit:
+			return
+		default:
+		}
+	}
+}
+
+// change records the service changed now, forgetting the changes older
+// than the ttl as they no longer lower it
+func (s *Server) change(service string) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	for name, t := range s.changed {
+		if now.Sub(t) >= s.opts.TTL {
+			delete(s.changed, name)
+		}
+	}
+	s.changed[service] = now
+}
+
+// ttl of the records of a service, the time since it last changed
+// bounded by the min and max ttl
+func (s *Server) ttl(service string) uint32 {
+	s.RLock()
+	changed, ok := s.changed[service]
+	s.RUnlock()
+
+	ttl := s.opts.TTL
+	if ok {
+		if d := time.Since(changed); d < ttl {
+			ttl = d
+		}
+		if ttl < s.opts.MinTTL {
+			ttl = s.opts.MinTTL
+		}
+	}
+	return uint32(ttl / time.Second)
+}
+
+// ServeDNS answers the questions of the query
+func (s *Server) ServeDNS(w miekg.ResponseWriter, req *miekg.Msg) {
+	m := new(miekg.Msg)
+	m.SetReply(req)
+	m.Authoritative = true
+
+	for _, q := range req.Question {
+		s.answer(m, q)
+	}
+
+	// udp responses fit in 512 bytes or the buffer size the client set
+	// with EDNS0, what's left out is marked truncated for a tcp retry
+	size := miekg.MaxMsgSize
+	udp := w.LocalAddr().Network() == "udp"
+	if udp {
+		size = miekg.MinMsgSize
+	}
+	if opt := req.IsEdns0(); opt != nil {
+		if udp && int(opt.UDPSize()) > size {
+			size = int(opt.UDPSize())
+		}
+		m.SetEdns0(uint16(size), opt.Do())
+	}
+	m.Truncate(size)
+
+	if err := w.WriteMsg(m); err != nil {
+		if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+			logger.Debugf("dns server error writing response: %v", err)
+		}
+	}
+}
+
+// answer a question adding the records to the message
+func (s *Server) answer(m *miekg.Msg, q miekg.Question) {
+	domain := strings.ToLower(miekg.Fqdn(s.opts.Domain))
+	name := miekg.Fqdn(q.Name)
+
+	if !strings.HasSuffix(strings.ToLower(name), "."+domain) {
+		m.Rcode = miekg.RcodeRefused
+		return
+	}
+
+	// the service name or <node id>.<service name>, names are case insensitive
+	rest := strings.ToLower(name[:len(name)-len(domain)-1])
+	if strings.HasPrefix(rest, "_") {
+		// _service._proto
+		rest = strings.TrimSuffix(strings.TrimSuffix(rest, "._tcp"), "._udp")
+		rest = strings.TrimPrefix(rest, "_")
+	}
+
+	service, nodes, err := s.lookup(rest)
+	if err == registry.ErrNotFound {
+		m.Rcode = miekg.RcodeNameError
+		return
+	} else if err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("dns server error looking up %s: %v", rest, err)
+		}
+		m.Rcode = miekg.RcodeServerFailure
+		return
+	}
+
+	ttl := s.ttl(service)
+
+	for _, n := range nodes {
+		switch q.Qtype {
+		case miekg.TypeA, miekg.TypeAAAA:
+			for _, rr := range addrRecords(q.Name, n.node, ttl) {
+				if rr.Header().Rrtype == q.Qtype {
+					m.Answer = append(m.Answer, rr)
+				}
+			}
+		case miekg.TypeSRV:
+			target := miekg.Fqdn(n.node.Id + "." + service + "." + domain)
+			if rr := srvRecord(q.Name, target, n.node, ttl); rr != nil {
+				m.Answer = append(m.Answer, rr)
+				m.Extra = append(m.Extra, addrRecords(target, n.node, ttl)...)
+			}
+		case miekg.TypeTXT:
+			m.Answer = append(m.Answer, txtRecord(q.Name, n.version, n.node, ttl))
+		case miekg.TypeANY:
+			m.Answer = append(m.Answer, addrRecords(q.Name, n.node, ttl)...)
+			m.Answer = append(m.Answer, txtRecord(q.Name, n.version, n.node, ttl))
+		}
+	}
+}
+
+// node of a version of a service
+type node struct {
+	version string
+	node    *registry.Node
+}
+
+// lookup the nodes of a service or of a single node named <node id>.<service>.
+// Node ids and service names may both have dots in them, so the name is split
+// at each dot in turn until a node of the service has the id.
+func (s *Server) lookup(name string) (string, []node, error) {
+	nodes, err := s.nodes(name)
+	if err != registry.ErrNotFound {
+		return name, nodes, err
+	}
+
+	for i := strings.Index(name, "."); i > 0; {
+		id, service := name[:i], name[i+1:]
+
+		nodes, err := s.nodes(service)
+		if err != nil && err != registry.ErrNotFound {
+			return "", nil, err
+		}
+		for _, n := range nodes {
+			if strings.EqualFold(n.node.Id, id) {
+				return service, []node{n}, nil
+			}
+		}
+
+		j := strings.Index(service, ".")
+		if j < 0 {
+			break
+		}
+		i += j + 1
+	}
+	return "", nil, registry.ErrNotFound
+}
+
+// nodes of every version of the service
+func (s *Server) nodes(name string) ([]node, error) {
+	if len(name) == 0 {
+		return nil, registry.ErrNotFound
+	}
+
+	// the cache is only running while the server is started
+	var r registry.Registry = s.opts.Registry
+	s.RLock()
+	if s.cache != nil {
+		r = s.cache
+	}
+	s.RUnlock()
+
+	services, err := r.GetService(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []node
+	for _, service := range services {
+		for _, n := range service.Nodes {
+			nodes = append(nodes, node{version: service.Version, node: n})
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, registry.ErrNotFound
+	}
+	return nodes, nil
+}
+
+func header(name string, rrtype uint16, ttl uint32) miekg.RR_Header {
+	return miekg.RR_Header{
+		Name:   name,
+		Rrtype: rrtype,
+		Class:  miekg.ClassINET,
+		Ttl:    ttl,
+	}
+}
+
+// addrRecords of the node, none if its address isn't an ip
+func addrRecords(name string, n *registry.Node, ttl uint32) []miekg.RR {
+	host, _, err := net.SplitHostPort(n.Address)
+	if err != nil {
+		host = n.Address
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsUnspecified() {
+		return nil
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return []miekg.RR{&miekg.A{Hdr: header(name, miekg.TypeA, ttl), A: ip4}}
+	}
+	return []miekg.RR{&miekg.AAAA{Hdr: header(name, miekg.TypeAAAA, ttl), AAAA: ip}}
+}
+
+// srvRecord of the node, nil if its address has no port
+func srvRecord(name, target string, n *registry.Node, ttl uint32) miekg.RR {
+	_, p, err := net.SplitHostPort(n.Address)
+	if err != nil {
+		return nil
+	}
+	port, err := strconv.ParseUint(p, 10, 16)
+	if err != nil {
+		return nil
+	}
+
+	weight := uint64(1)
+	if w, err := strconv.ParseUint(n.Metadata[WeightKey], 10, 16); err == nil {
+		weight = w
+	}
+
+	return &miekg.SRV{
+		Hdr:    header(name, miekg.TypeSRV, ttl),
+		Weight: uint16(weight),
+		Port:   uint16(port),
+		Target: target,
+	}
+}
+
+// txtRecord holding the id, version and metadata of the node
+func txtRecord(name, version string, n *registry.Node, ttl uint32) miekg.RR {
+	txt := []string{
+		"id=" + n.Id,
+		"version=" + version,
+	}
+
+	keys := make([]string, 0, len(n.Metadata))
+	for k := range n.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		kv := fmt.Sprintf("%s=%s", k, n.Metadata[k])
+		// strings of a txt record are at most 255 bytes
+		if len(kv) > 255 {
+			kv = kv[:255]
+		}
+		txt = append(txt, kv)
+	}
+
+	return &miekg.TXT{Hdr: header(name, miekg.TypeTXT, ttl), Txt: txt}
+}
+
+// NewServer returns a dns server for the services of the registry
+func NewServer(opts ...Option) *Server {
+	options := newOptions(opts...)
+
+	return &Server{
+		opts:    options,
+		changed: make(map[string]time.Time),
+	}
+}
@@ -0,0 +1,249 @@
+package dns
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/registry"
+	miekg "github.com/miekg/dns"
+)
+
+func TestServer(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	services := []*registry.Service{
+		{
+			Name:    "greeter",
+			Version: "1.0.0",
+			Nodes: []*registry.Node{
+				{Id: "greeter-1", Address: "10.0.0.1:8080", Metadata: map[string]string{"weight": "5"}},
+				{Id: "greeter-2", Address: "[fd00::2]:8081"},
+			},
+		},
+		{
+			Name:    "greeter",
+			Version: "2.0.0",
+			Nodes: []*registry.Node{
+				{Id: "greeter-3", Address: "10.0.0.3:8082", Metadata: map[string]string{"zone": "a"}},
+			},
+		},
+	}
+	for _, s := range services {
+		if err := r.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewServer(Registry(r), Address("127.0.0.1:0"), TTL(time.Minute))
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	query := func(name string, qtype uint16) *miekg.Msg {
+		m := new(miekg.Msg)
+		m.SetQuestion(name, qtype)
+		rsp, _, err := new(miekg.Client).Exchange(m, s.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rsp
+	}
+
+	rsp := query("greeter.micro.local.", miekg.TypeA)
+	if len(rsp.Answer) != 2 {
+		t.Fatalf("expected 2 A records, got %v", rsp.Answer)
+	}
+	for _, rr := range rsp.Answer {
+		if ttl := rr.Header().Ttl; ttl < 1 || ttl > 60 {
+			t.Fatalf("unexpected ttl %d", ttl)
+		}
+	}
+
+	rsp = query("greeter.micro.local.", miekg.TypeAAAA)
+	if len(rsp.Answer) != 1 || rsp.Answer[0].(*miekg.AAAA).AAAA.String() != "fd00::2" {
+		t.Fatalf("unexpected AAAA records %v", rsp.Answer)
+	}
+
+	rsp = query("_greeter._tcp.micro.local.", miekg.TypeSRV)
+	if len(rsp.Answer) != 3 {
+		t.Fatalf("expected 3 SRV records, got %v", rsp.Answer)
+	}
+	if len(rsp.Extra) != 3 {
+		t.Fatalf("expected the addresses of the targets, got %v", rsp.Extra)
+	}
+	for _, rr := range rsp.Answer {
+		srv := rr.(*miekg.SRV)
+		if srv.Target == "greeter-1.greeter.micro.local." && (srv.Port != 8080 || srv.Weight != 5) {
+			t.Fatalf("unexpected SRV record %v", srv)
+		}
+	}
+
+	rsp = query("greeter-3.greeter.micro.local.", miekg.TypeA)
+	if len(rsp.Answer) != 1 || rsp.Answer[0].(*miekg.A).A.String() != "10.0.0.3" {
+		t.Fatalf("unexpected A records of the node %v", rsp.Answer)
+	}
+
+	rsp = query("greeter-3.greeter.micro.local.", miekg.TypeTXT)
+	if len(rsp.Answer) != 1 {
+		t.Fatalf("expected 1 TXT record, got %v", rsp.Answer)
+	}
+	txt := rsp.Answer[0].(*miekg.TXT).Txt
+	expected := []string{"id=greeter-3", "version=2.0.0", "zone=a"}
+	if len(txt) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, txt)
+	}
+	for i := range expected {
+		if txt[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, txt)
+		}
+	}
+
+	if rsp = query("Greeter-3.GREETER.Micro.Local.", miekg.TypeA); len(rsp.Answer) != 1 {
+		t.Fatalf("expected names to be case insensitive, got %v", rsp.Answer)
+	}
+
+	if rsp = query("foo.micro.local.", miekg.TypeA); rsp.Rcode != miekg.RcodeNameError {
+		t.Fatalf("expected NXDOMAIN, got %s", miekg.RcodeToString[rsp.Rcode])
+	}
+	if rsp = query("example.com.", miekg.TypeA); rsp.Rcode != miekg.RcodeRefused {
+		t.Fatalf("expected REFUSED, got %s", miekg.RcodeToString[rsp.Rcode])
+	}
+}
+
+func TestNodeIdDots(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	// default node ids are the service name with a uuid
+	service := &registry.Service{
+		Name:    "go.micro.srv.greeter",
+		Version: "latest",
+		Nodes: []*registry.Node{
+			{Id: "go.micro.srv.greeter-4b7e", Address: "10.0.0.1:8080"},
+			{Id: "go.micro.srv.greeter-9c1d", Address: "10.0.0.2:8080"},
+		},
+	}
+	if err := r.Register(service); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(Registry(r), Address("127.0.0.1:0"))
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	m := new(miekg.Msg)
+	m.SetQuestion("_go.micro.srv.greeter._tcp.micro.local.", miekg.TypeSRV)
+	rsp, _, err := new(miekg.Client).Exchange(m, s.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Answer) != 2 {
+		t.Fatalf("expected 2 SRV records, got %v", rsp.Answer)
+	}
+
+	// the targets of the records resolve to their node
+	for _, rr := range rsp.Answer {
+		srv := rr.(*miekg.SRV)
+		m := new(miekg.Msg)
+		m.SetQuestion(srv.Target, miekg.TypeA)
+		rsp, _, err := new(miekg.Client).Exchange(m, s.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rsp.Answer) != 1 {
+			t.Fatalf("expected the A record of %s, got %v", srv.Target, rsp.Answer)
+		}
+	}
+
+	m.SetQuestion("go.micro.srv.greeter-0000.go.micro.srv.greeter.micro.local.", miekg.TypeA)
+	if rsp, _, err = new(miekg.Client).Exchange(m, s.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Rcode != miekg.RcodeNameError {
+		t.Fatalf("expected NXDOMAIN for an unknown node, got %s", miekg.RcodeToString[rsp.Rcode])
+	}
+}
+
+func TestTTL(t *testing.T) {
+	s := NewServer(TTL(30*time.Second), MinTTL(2*time.Second))
+
+	if ttl := s.ttl("greeter"); ttl != 30 {
+		t.Fatalf("expected the ttl of an unchanged service to be 30, got %d", ttl)
+	}
+
+	s.changed["greeter"] = time.Now()
+	if ttl := s.ttl("greeter"); ttl != 2 {
+		t.Fatalf("expected the ttl of a changed service to be 2, got %d", ttl)
+	}
+
+	s.changed["greeter"] = time.Now().Add(-10 * time.Second)
+	if ttl := s.ttl("greeter"); ttl != 10 {
+		t.Fatalf("expected the ttl to be the time since the change, got %d", ttl)
+	}
+
+	// changes older than the ttl are forgotten
+	s.changed["greeter"] = time.Now().Add(-time.Minute)
+	s.change("foo")
+	if _, ok := s.changed["greeter"]; ok || len(s.changed) != 1 {
+		t.Fatalf("expected changes older than the ttl to be forgotten, got %v", s.changed)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	service := &registry.Service{Name: "greeter", Version: "1.0.0"}
+	for i := 0; i < 64; i++ {
+		service.Nodes = append(service.Nodes, &registry.Node{
+			Id:      fmt.Sprintf("greeter-%d", i),
+			Address: fmt.Sprintf("10.0.0.%d:8080", i+1),
+		})
+	}
+	if err := r.Register(service); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(Registry(r), Address("127.0.0.1:0"))
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	testData := []struct {
+		name      string
+		net       string
+		edns      uint16
+		truncated bool
+	}{
+		{"UDP", "udp", 0, true},
+		{"EDNS0", "udp", 4096, false},
+		{"TCP", "tcp", 0, false},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			m := new(miekg.Msg)
+			m.SetQuestion("greeter.micro.local.", miekg.TypeA)
+			if d.edns > 0 {
+				m.SetEdns0(d.edns, false)
+			}
+			rsp, _, err := (&miekg.Client{Net: d.net}).Exchange(m, s.Address())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rsp.Truncated != d.truncated {
+				t.Fatalf("expected truncated %v, got %v", d.truncated, rsp.Truncated)
+			}
+			rsp.Compress = true
+			if b, _ := rsp.Pack(); d.truncated && len(b) > miekg.MinMsgSize {
+				t.Fatalf("expected at most %d bytes, got %d", miekg.MinMsgSize, len(b))
+			}
+			if !d.truncated && len(rsp.Answer) != len(service.Nodes) {
+				t.Fatalf("expected %d A records, got %d", len(service.Nodes), len(rsp.Answer))
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"time"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+var (
+	// DefaultAddress the server listens on for udp and tcp queries
+	DefaultAddress = ":8053"
+	// DefaultDomain services are served under
+	DefaultDomain = "micro.local."
+	// DefaultTTL of the records of a service which hasn't changed recently
+	DefaultTTL = 30 * time.Second
+	// DefaultMinTTL of the records of a service which has just changed
+	DefaultMinTTL = time.Second
+)
+
+type Options struct {
+	// Registry the services are read from
+	Registry registry.Registry
+	// Address to listen on
+	Address string
+	// Domain the services are served under e.g. micro.local.
+	Domain string
+	// TTL of records, lowered down to MinTTL for services which changed
+	// recently so resolvers pick up nodes coming and going
+	TTL    time.Duration
+	MinTTL time.Duration
+}
+
+type Option func(o *Options)
+
+// Registry the services are read from
+func Registry(r registry.Registry) Option {
+	return func(o *Options) {
+		o.Registry = r
+	}
+}
+
+// Address to listen on
+func Address(a string) Option {
+	return func(o *Options) {
+		o.Address = a
+	}
+}
+
+// Domain the services are served under
+func Domain(d string) Option {
+	return func(o *Options) {
+		o.Domain = d
+	}
+}
+
+// TTL of the records of services which haven't changed recently
+func TTL(t time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = t
+	}
+}
+
+// MinTTL of the records of services which have just changed
+func MinTTL(t time.Duration) Option {
+	return func(o *Options) {
+		o.MinTTL = t
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Registry: registry.DefaultRegistry,
+		Address:  DefaultAddress,
+		Domain:   DefaultDomain,
+		TTL:      DefaultTTL,
+		MinTTL:   DefaultMinTTL,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
@@ -0,0 +1,498 @@
+// Package federation provides a registry federating the registries of
+// several regions and zones. Nodes are labelled with the region and zone of
+// the registry they come from so the selector can prefer nearby nodes with
+// selector.FilterLocality, and can be replicated between the registries.
+package federation
+
+import (
+	"time"
+
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/registry"
+	util "github.com/asim/go-micro/v3/util/registry"
+)
+
+const (
+	// RegionKey of the node metadata holding its region
+	RegionKey = "region"
+	// ZoneKey of the node metadata holding its zone
+	ZoneKey = "zone"
+)
+
+// Member of the federation, the registry of a region or of a zone in it.
+// Members in the same region must have a zone.
+type Member struct {
+	Region   string
+	Zone     string
+	Registry registry.Registry
+}
+
+// Registry federating the members
+type Registry interface {
+	registry.Registry
+	// Stop replicating
+	Stop()
+}
+
+type federation struct {
+	opts Options
+	// local member services are registered with
+	local Member
+	// every member, the local one first
+	members []Member
+
+	exit chan bool
+}
+
+type watcher struct {
+	res  chan *registry.Result
+	exit chan bool
+}
+
+func (w *watcher) Next() (*registry.Result, error) {
+	select {
+	case r := <-w.res:
+		return r, nil
+	case <-w.exit:
+		return nil, registry.ErrWatcherStopped
+	}
+}
+
+func (w *watcher) Stop() {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+	}
+}
+
+// label a copy of the service with the region and zone of the member.
+// Labels set by the nodes themselves are kept.
+func label(m Member, s *registry.Service) *registry.Service {
+	s = util.CopyService(s)
+
+	for _, n := range s.Nodes {
+		md := make(map[string]string, len(n.Metadata)+2)
+		for k, v := range n.Metadata {
+			md[k] = v
+		}
+		if _, ok := md[RegionKey]; !ok && len(m.Region) > 0 {
+			md[RegionKey] = m.Region
+		}
+		if _, ok := md[ZoneKey]; !ok && len(m.Zone) > 0 {
+			md[ZoneKey] = m.Zone
+		}
+		n.Metadata = md
+	}
+
+	return s
+}
+
+// native checks whether the node was registered with the member rather
+// than replicated to it from another one
+func native(m Member, n *registry.Node) bool {
+	if r, ok := n.Metadata[RegionKey]; ok && r != m.Region {
+		return false
+	}
+	if z, ok := n.Metadata[ZoneKey]; ok && len(m.Zone) > 0 && z != m.Zone {
+		return false
+	}
+	return true
+}
+
+// merge the services into the list by name and version, the
+// nodes already in it take precedence
+func merge(list []*registry.Service, services ...*registry.Service) []*registry.Service {
+	for _, s := range services {
+		var cur *registry.Service
+		for _, l := range list {
+			if l.Name == s.Name && l.Version == s.Version {
+				cur = l
+				break
+			}
+		}
+
+		if cur == nil {
+			list = append(list, s)
+			continue
+		}
+
+		for _, n := range s.Nodes {
+			var seen bool
+			for _, c := range cur.Nodes {
+				if c.Id == n.Id {
+					seen = true
+					break
+				}
+			}
+			if !seen {
+				cur.Nodes = append(cur.Nodes, n)
+			}
+		}
+	}
+
+	return list
+}
+
+func (f *federation) Init(opts ...registry.Option) error {
+	return f.local.Registry.Init(opts...)
+}
+
+func (f *federation) Options() registry.Options {
+	return f.local.Registry.Options()
+}
+
+// Register the service with the local member
+func (f *federation) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	return f.local.Registry.Register(label(f.local, s), opts...)
+}
+
+// Deregister the service from the local member
+func (f *federation) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
+	return f.local.Registry.Deregister(s, opts...)
+}
+
+// GetService from every member. Members which can't be reached are
+// skipped as long as one of them returns the service.
+func (f *federation) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	var services []*registry.Service
+	var gerr error
+
+	for _, m := range f.members {
+		list, err := m.Registry.GetService(name, opts...)
+		if err == registry.ErrNotFound {
+			continue
+		} else if err != nil {
+			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+				logger.Debugf("federation error getting %s from region %s zone %s: %v", name, m.Region, m.Zone, err)
+			}
+			if gerr == nil {
+				gerr = err
+			}
+			continue
+		}
+
+		for _, s := range list {
+			services = merge(services, label(m, s))
+		}
+	}
+
+	if len(services) > 0 {
+		return services, nil
+	}
+	if gerr != nil {
+		return nil, gerr
+	}
+	return nil, registry.ErrNotFound
+}
+
+// ListServices of every member which can be reached
+func (f *federation) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	var services []*registry.Service
+	var lerr error
+	var ok bool
+
+	for _, m := range f.members {
+		list, err := m.Registry.ListServices(opts...)
+		if err != nil {
+			if lerr == nil {
+				lerr = err
+			}
+			continue
+		}
+		ok = true
+
+		for _, s := range list {
+			services = merge(services, label(m, s))
+		}
+	}
+
+	if !ok {
+		return nil, lerr
+	}
+	return services, nil
+}
+
+// Watch every member, the nodes of the events are labelled
+func (f *federation) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	w := &watcher{
+		res:  make(chan *registry.Result),
+		exit: make(chan bool),
+	}
+
+	var watchers []registry.Watcher
+	for _, m := range f.members {
+		mw, err := m.Registry.Watch(opts...)
+		if err != nil {
+			for _, mw := range watchers {
+				mw.Stop()
+			}
+			return nil, err
+		}
+		watchers = append(watchers, mw)
+
+		go func(m Member, mw registry.Watcher) {
+			defer mw.Stop()
+			for {
+				res, err := mw.Next()
+				if err != nil {
+					w.Stop()
+					return
+				}
+				if res.Service != nil {
+					res.Service = label(m, res.Service)
+				}
+				select {
+				case w.res <- res:
+				case <-w.exit:
+					return
+				}
+			}
+		}(m, mw)
+	}
+
+	go func() {
+		<-w.exit
+		for _, mw := range watchers {
+			mw.Stop()
+		}
+	}()
+
+	return w, nil
+}
+
+func (f *federation) String() string {
+	return "federation"
+}
+
+func (f *federation) Stop() {
+	select {
+	case <-f.exit:
+	default:
+		close(f.exit)
+	}
+}
+
+// replicate the native nodes of the service registered with the member
+// to every other member. Replicated nodes are labelled with their origin
+// and never replicated again, which prevents loops.
+func (f *federation) replicate(from Member, action string, s *registry.Service) {
+	srv := label(from, s)
+
+	var nodes []*registry.Node
+	for _, n := range srv.Nodes {
+		if native(from, n) {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	srv.Nodes = nodes
+
+	for _, m := range f.members {
+		if m.Registry == from.Registry {
+			continue
+		}
+
+		var err error
+		switch action {
+		case "create", "update":
+			err = m.Registry.Register(srv)
+		case "delete":
+			err = m.Registry.Deregister(srv)
+		}
+		if err != nil {
+			if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+				logger.Warnf("federation error replicating %s from region %s zone %s to region %s zone %s: %v",
+					srv.Name, from.Region, from.Zone, m.Region, m.Zone, err)
+			}
+		}
+	}
+}
+
+// sync replicates the services registered with the member and removes the
+// nodes replicated from it which it no longer has, deletes which were
+// missed while it couldn't be watched
+func (f *federation) sync(m Member) error {
+	list, err := m.Registry.ListServices()
+	if err != nil {
+		return err
+	}
+
+	// nodes of the member by service, version and id
+	live := make(map[string]bool)
+	complete := true
+
+	seen := make(map[string]bool)
+	for _, s := range list {
+		if seen[s.Name] {
+			continue
+		}
+		seen[s.Name] = true
+
+		services, err := m.Registry.GetService(s.Name)
+		if err == registry.ErrNotFound {
+			continue
+		} else if err != nil {
+			complete = false
+			continue
+		}
+		for _, srv := range services {
+			f.replicate(m, "create", srv)
+			for _, n := range srv.Nodes {
+				live[nodeKey(srv.Name, srv.Version, n.Id)] = true
+			}
+		}
+	}
+
+	// only remove nodes when every service of the member was read
+	if complete {
+		f.reconcile(m, live)
+	}
+
+	return nil
+}
+
+func nodeKey(name, version, id string) string {
+	return name + "/" + version + "/" + id
+}
+
+// replicated checks whether the node of the member was replicated to it
+// from the other one
+func replicated(from, to Member, n *registry.Node) bool {
+	if native(to, n) {
+		return false
+	}
+	if n.Metadata[RegionKey] != from.Region {
+		return false
+	}
+	if len(from.Zone) > 0 && n.Metadata[ZoneKey] != from.Zone {
+		return false
+	}
+	return true
+}
+
+// reconcile deregisters the nodes replicated from the member to the
+// others which aren't live in it anymore
+func (f *federation) reconcile(from Member, live map[string]bool) {
+	for _, m := range f.members {
+		if m.Registry == from.Registry {
+			continue
+		}
+
+		list, err := m.Registry.ListServices()
+		if err != nil {
+			if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+				logger.Debugf("federation error listing region %s zone %s: %v", m.Region, m.Zone, err)
+			}
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, s := range list {
+			if seen[s.Name] {
+				continue
+			}
+			seen[s.Name] = true
+
+			services, err := m.Registry.GetService(s.Name)
+			if err != nil {
+				continue
+			}
+			for _, srv := range services {
+				var dead []*registry.Node
+				for _, n := range srv.Nodes {
+					if replicated(from, m, n) && !live[nodeKey(srv.Name, srv.Version, n.Id)] {
+						dead = append(dead, n)
+					}
+				}
+				if len(dead) == 0 {
+					continue
+				}
+
+				del := util.CopyService(srv)
+				del.Nodes = dead
+				if err := m.Registry.Deregister(del); err != nil {
+					if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+						logger.Warnf("federation error removing %s replicated from region %s zone %s from region %s zone %s: %v",
+							srv.Name, from.Region, from.Zone, m.Region, m.Zone, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// run replicates the member, watching it for changes
+func (f *federation) run(m Member) {
+	for {
+		err := f.sync(m)
+		if err == nil {
+			err = f.watch(m)
+		}
+		if err != nil && logger.V(logger.WarnLevel, logger.DefaultLogger) {
+			logger.Warnf("federation error replicating region %s zone %s: %v", m.Region, m.Zone, err)
+		}
+
+		select {
+		case <-f.exit:
+			return
+		case <-time.After(f.opts.Retry):
+		}
+	}
+}
+
+func (f *federation) watch(m Member) error {
+	w, err := m.Registry.Watch()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan bool)
+	defer close(done)
+
+	go func() {
+		select {
+		case <-f.exit:
+		case <-done:
+		}
+		w.Stop()
+	}()
+
+	for {
+		res, err := w.Next()
+		if err != nil {
+			return err
+		}
+		if res.Service == nil {
+			continue
+		}
+		f.replicate(m, res.Action, res.Service)
+	}
+}
+
+// NewRegistry returns a registry federating the local member with the others.
+// Services are registered with the local member and looked up in all of them.
+func NewRegistry(local Member, opts ...Option) Registry {
+	if local.Registry == nil {
+		local.Registry = registry.DefaultRegistry
+	}
+
+	options := newOptions(opts...)
+
+	f := &federation{
+		opts:    options,
+		local:   local,
+		members: append([]Member{local}, options.Members...),
+		exit:    make(chan bool),
+	}
+
+	if options.Replicate {
+		for _, m := range f.members {
+			go f.run(m)
+		}
+	}
+
+	return f
+}
@@ -0,0 +1,260 @@
+package federation
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+// nodes of the service by id
+func nodes(t *testing.T, r registry.Registry, name string) map[string]*registry.Node {
+	services, err := r.GetService(name)
+	if err == registry.ErrNotFound {
+		return nil
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	nodes := make(map[string]*registry.Node)
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			nodes[n.Id] = n
+		}
+	}
+	return nodes
+}
+
+func TestFederation(t *testing.T) {
+	east := registry.NewMemoryRegistry()
+	west := registry.NewMemoryRegistry()
+
+	if err := west.Register(&registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-west", Address: "10.1.0.1:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewRegistry(
+		Member{Region: "us-east", Zone: "a", Registry: east},
+		Members(Member{Region: "eu-west", Zone: "a", Registry: west}),
+	)
+	defer f.Stop()
+
+	if err := f.Register(&registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-east", Address: "10.0.0.1:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	n := nodes(t, f, "greeter")
+	if len(n) != 2 {
+		t.Fatalf("expected the nodes of both regions, got %v", n)
+	}
+	if r := n["greeter-east"].Metadata[RegionKey]; r != "us-east" {
+		t.Fatalf("expected the local node in us-east, got %s", r)
+	}
+	if r := n["greeter-west"].Metadata[RegionKey]; r != "eu-west" {
+		t.Fatalf("expected the remote node in eu-west, got %s", r)
+	}
+
+	// without replication the registries are left alone
+	if n := nodes(t, west, "greeter"); len(n) != 1 {
+		t.Fatalf("expected no replication, got %v", n)
+	}
+}
+
+func TestReplicate(t *testing.T) {
+	east := registry.NewMemoryRegistry()
+	west := registry.NewMemoryRegistry()
+
+	f := NewRegistry(
+		Member{Region: "us-east", Zone: "a", Registry: east},
+		Members(Member{Region: "eu-west", Zone: "a", Registry: west}),
+		Replicate(true),
+		Retry(10*time.Millisecond),
+	)
+	defer f.Stop()
+
+	// let the watchers start
+	time.Sleep(200 * time.Millisecond)
+
+	if err := f.Register(&registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-east", Address: "10.0.0.1:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := west.Register(&registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-west", Address: "10.1.0.1:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	wait := func(r registry.Registry, count int) map[string]*registry.Node {
+		for i := 0; i < 100; i++ {
+			if n := nodes(t, r, "greeter"); len(n) == count {
+				return n
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("expected %d nodes, got %v", count, nodes(t, r, "greeter"))
+		return nil
+	}
+
+	n := wait(west, 2)
+	if r := n["greeter-east"].Metadata[RegionKey]; r != "us-east" {
+		t.Fatalf("expected the replicated node to keep its region, got %s", r)
+	}
+	n = wait(east, 2)
+	if r := n["greeter-west"].Metadata[RegionKey]; r != "eu-west" {
+		t.Fatalf("expected the replicated node to keep its region, got %s", r)
+	}
+
+	// deregistering is replicated too
+	if err := west.Deregister(&registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-west"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	wait(east, 1)
+}
+
+// flakyRegistry can be disconnected, stopping its watchers
+type flakyRegistry struct {
+	registry.Registry
+
+	sync.Mutex
+	down     bool
+	watchers []registry.Watcher
+}
+
+func (f *flakyRegistry) disconnect(down bool) {
+	f.Lock()
+	defer f.Unlock()
+	f.down = down
+	if down {
+		for _, w := range f.watchers {
+			w.Stop()
+		}
+		f.watchers = nil
+	}
+}
+
+func (f *flakyRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	f.Lock()
+	down := f.down
+	f.Unlock()
+	if down {
+		return nil, errors.New("connection refused")
+	}
+	return f.Registry.ListServices(opts...)
+}
+
+func (f *flakyRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.down {
+		return nil, errors.New("connection refused")
+	}
+	w, err := f.Registry.Watch(opts...)
+	if err != nil {
+		return nil, err
+	}
+	f.watchers = append(f.watchers, w)
+	return w, nil
+}
+
+func TestReplicateMissedDelete(t *testing.T) {
+	east := &flakyRegistry{Registry: registry.NewMemoryRegistry()}
+	west := registry.NewMemoryRegistry()
+
+	f := NewRegistry(
+		Member{Region: "us-east", Zone: "a", Registry: east},
+		Members(Member{Region: "eu-west", Zone: "a", Registry: west}),
+		Replicate(true),
+		Retry(10*time.Millisecond),
+	)
+	defer f.Stop()
+
+	// let the watchers start
+	time.Sleep(200 * time.Millisecond)
+
+	srv := &registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-east", Address: "10.0.0.1:8080"}},
+	}
+	if err := f.Register(srv); err != nil {
+		t.Fatal(err)
+	}
+	if err := west.Register(&registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-west", Address: "10.1.0.1:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	wait := func(r registry.Registry, ids ...string) {
+		for i := 0; i < 100; i++ {
+			n := nodes(t, r, "greeter")
+			if len(n) == len(ids) {
+				var missing bool
+				for _, id := range ids {
+					if _, ok := n[id]; !ok {
+						missing = true
+					}
+				}
+				if !missing {
+					return
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("expected nodes %v, got %v", ids, nodes(t, r, "greeter"))
+	}
+	wait(west, "greeter-east", "greeter-west")
+
+	// the node is deregistered while east can't be watched
+	east.disconnect(true)
+	if err := east.Registry.Deregister(srv); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	east.disconnect(false)
+
+	// and removed from west once east is synced again, leaving its own node
+	wait(west, "greeter-west")
+}
+
+func TestNative(t *testing.T) {
+	m := Member{Region: "us-east", Zone: "a"}
+
+	testData := []struct {
+		metadata map[string]string
+		native   bool
+	}{
+		{nil, true},
+		{map[string]string{RegionKey: "us-east"}, true},
+		{map[string]string{RegionKey: "us-east", ZoneKey: "a"}, true},
+		{map[string]string{RegionKey: "us-east", ZoneKey: "b"}, false},
+		{map[string]string{RegionKey: "eu-west", ZoneKey: "a"}, false},
+	}
+
+	for _, d := range testData {
+		if native(m, &registry.Node{Metadata: d.metadata}) != d.native {
+			t.Fatalf("expected native %v for %v", d.native, d.metadata)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package federation
+
+import (
+	"time"
+)
+
+var (
+	// DefaultRetry interval of watching a member which returned an error
+	DefaultRetry = time.Second
+)
+
+type Options struct {
+	// Members in other regions or zones
+	Members []Member
+	// Replicate the nodes of every member to the others
+	Replicate bool
+	// Retry interval of watching a member which returned an error
+	Retry time.Duration
+}
+
+type Option func(o *Options)
+
+// Members of the federation in other regions or zones
+func Members(m ...Member) Option {
+	return func(o *Options) {
+		o.Members = append(o.Members, m...)
+	}
+}
+
+// Replicate the nodes registered with a member to every other member,
+// so clients which only use their local registry discover them too.
+// Nodes deregistered while a member can't be watched are removed from
+// the others once it's synced again.
+func Replicate(b bool) Option {
+	return func(o *Options) {
+		o.Replicate = b
+	}
+}
+
+// Retry interval of watching a member which returned an error
+func Retry(d time.Duration) Option {
+	return func(o *Options) {
+		o.Retry = d
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Retry: DefaultRetry,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
@@ -0,0 +1,585 @@
+// Package file is a registry reading services from a yaml or json file.
+// It's meant for local development and static environments where services
+// call fixed upstreams. The file is watched and changes to it are sent to
+// watchers as create, update and delete events.
+//
+//	services:
+//	  - name: greeter
+//	    version: 1.0.0
+//	    nodes:
+//	      - id: greeter-1
+//	        address: 10.0.0.1:8080
+//	        metadata:
+//	          zone: a
+//
+// Nodes without an id are identified by the name of the service and their
+// address. Services registered and deregistered through the registry are
+// kept in memory on top of the file, or written back to it with Persist.
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/registry"
+	util "github.com/asim/go-micro/v3/util/registry"
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// reloadDelay after the file changes before it's read
+	reloadDelay = 100 * time.Millisecond
+)
+
+// services by name and version
+type services map[string]map[string]*registry.Service
+
+// contents of the file
+type contents struct {
+	Services []*registry.Service `json:"services" yaml:"services"`
+}
+
+type fileRegistry struct {
+	opts registry.Options
+
+	sync.RWMutex
+	path    string
+	persist bool
+	// services read from the file
+	file services
+	// services registered through the registry
+	added services
+	// nodes deregistered through the registry, keyed by service, version and id
+	removed map[string]bool
+	// watchers sent the changes
+	watchers map[*watcher]bool
+	// watching the directory of the file
+	fw *fsnotify.Watcher
+}
+
+type watcher struct {
+	wo registry.WatchOptions
+
+	sync.Mutex
+	// results queued for Next, never dropped so a slow
+	// watcher doesn't miss a node being removed
+	queue []*registry.Result
+	// signalled when results are queued
+	next chan bool
+	exit chan bool
+}
+
+// push queues the result without blocking on the watcher
+func (w *watcher) push(res *registry.Result) {
+	if len(w.wo.Service) > 0 && w.wo.Service != res.Service.Name {
+		return
+	}
+
+	select {
+	case <-w.exit:
+		return
+	default:
+	}
+
+	w.Lock()
+	w.queue = append(w.queue, res)
+	w.Unlock()
+
+	select {
+	case w.next <- true:
+	default:
+	}
+}
+
+func (w *watcher) Next() (*registry.Result, error) {
+	for {
+		w.Lock()
+		if len(w.queue) > 0 {
+			res := w.queue[0]
+			w.queue[0] = nil
+			w.queue = w.queue[1:]
+			w.Unlock()
+			return res, nil
+		}
+		w.Unlock()
+
+		select {
+		case <-w.next:
+		case <-w.exit:
+			return nil, registry.ErrWatcherStopped
+		}
+	}
+}
+
+func (w *watcher) Stop() {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+	}
+}
+
+func removedKey(name, version, id string) string {
+	return name + "/" + version + "/" + id
+}
+
+// add the service to the set, nodes replace those with the same id
+func (s services) add(srv *registry.Service) {
+	if _, ok := s[srv.Name]; !ok {
+		s[srv.Name] = make(map[string]*registry.Service)
+	}
+
+	cur, ok := s[srv.Name][srv.Version]
+	if !ok {
+		cp := util.CopyService(srv)
+		cp.Nodes = nil
+		cur = cp
+		s[srv.Name][srv.Version] = cur
+	} else {
+		if len(srv.Metadata) > 0 {
+			cur.Metadata = srv.Metadata
+		}
+		if len(srv.Endpoints) > 0 {
+			cur.Endpoints = util.CopyService(srv).Endpoints
+		}
+	}
+
+	for _, n := range srv.Nodes {
+		node := *n
+		var replaced bool
+		for i, c := range cur.Nodes {
+			if c.Id == n.Id {
+				cur.Nodes[i] = &node
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			cur.Nodes = append(cur.Nodes, &node)
+		}
+	}
+}
+
+// list the services sorted by name and version
+func (s services) list() []*registry.Service {
+	var list []*registry.Service
+	for _, versions := range s {
+		for _, srv := range versions {
+			list = append(list, util.CopyService(srv))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name == list[j].Name {
+			return list[i].Version < list[j].Version
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// view of the file with the services registered and deregistered on top
+func (f *fileRegistry) view() services {
+	view := make(services)
+	for _, versions := range f.file {
+		for _, srv := range versions {
+			view.add(srv)
+		}
+	}
+	for _, versions := range f.added {
+		for _, srv := range versions {
+			view.add(srv)
+		}
+	}
+
+	for name, versions := range view {
+		for version, srv := range versions {
+			var nodes []*registry.Node
+			for _, n := range srv.Nodes {
+				if !f.removed[removedKey(name, version, n.Id)] {
+					nodes = append(nodes, n)
+				}
+			}
+			if len(nodes) == 0 {
+				delete(versions, version)
+				continue
+			}
+			srv.Nodes = nodes
+		}
+		if len(versions) == 0 {
+			delete(view, name)
+		}
+	}
+
+	return view
+}
+
+// diff the views into the events sent to watchers. Removed nodes of a
+// version which still exists are sent as a delete of just those nodes.
+func diff(old, neu services) []*registry.Result {
+	var results []*registry.Result
+
+	for _, srv := range old.list() {
+		if _, ok := neu[srv.Name][srv.Version]; !ok {
+			results = append(results, &registry.Result{Action: "delete", Service: srv})
+		}
+	}
+
+	for _, srv := range neu.list() {
+		cur, ok := old[srv.Name][srv.Version]
+		if !ok {
+			results = append(results, &registry.Result{Action: "create", Service: srv})
+			continue
+		}
+
+		nodes := make(map[string]*registry.Node, len(srv.Nodes))
+		for _, n := range srv.Nodes {
+			nodes[n.Id] = n
+		}
+
+		var removed []*registry.Node
+		for _, n := range cur.Nodes {
+			if _, ok := nodes[n.Id]; !ok {
+				node := *n
+				removed = append(removed, &node)
+			}
+		}
+
+		if len(removed) > 0 {
+			del := util.CopyService(cur)
+			del.Nodes = removed
+			results = append(results, &registry.Result{Action: "delete", Service: del})
+		}
+
+		rest := util.CopyService(cur)
+		rest.Nodes = nil
+		for _, n := range cur.Nodes {
+			if _, ok := nodes[n.Id]; ok {
+				rest.Nodes = append(rest.Nodes, n)
+			}
+		}
+		sort.Slice(rest.Nodes, func(i, j int) bool { return rest.Nodes[i].Id < rest.Nodes[j].Id })
+		sorted := util.CopyService(srv)
+		sort.Slice(sorted.Nodes, func(i, j int) bool { return sorted.Nodes[i].Id < sorted.Nodes[j].Id })
+
+		if !reflect.DeepEqual(rest, sorted) {
+			results = append(results, &registry.Result{Action: "update", Service: srv})
+		}
+	}
+
+	return results
+}
+
+// notify queues the changes for the watchers. It's called with the
+// lock held so the changes are queued in the order they were made.
+func (f *fileRegistry) notify(results []*registry.Result) {
+	for _, res := range results {
+		for w := range f.watchers {
+			w.push(res)
+		}
+	}
+}
+
+// read the services in the file
+func read(path string) (services, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c contents
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(b, &c)
+	default:
+		err = yaml.Unmarshal(b, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("registry file %s: %v", path, err)
+	}
+
+	s := make(services)
+	for _, srv := range c.Services {
+		if len(srv.Name) == 0 {
+			return nil, fmt.Errorf("registry file %s: service name required", path)
+		}
+		for _, n := range srv.Nodes {
+			if len(n.Id) == 0 {
+				n.Id = srv.Name + "-" + n.Address
+			}
+		}
+		s.add(srv)
+	}
+	return s, nil
+}
+
+// write the services to the file, replacing it once written
+func write(path string, s services) error {
+	c := contents{Services: s.list()}
+
+	var b []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		b, err = json.MarshalIndent(c, "", "  ")
+	default:
+		b, err = yaml.Marshal(c)
+	}
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// reload the file sending the changes to watchers
+func (f *fileRegistry) reload() error {
+	f.RLock()
+	path := f.path
+	f.RUnlock()
+
+	s, err := read(path)
+	if err != nil {
+		return err
+	}
+
+	f.Lock()
+	old := f.view()
+	f.file = s
+	f.notify(diff(old, f.view()))
+	f.Unlock()
+
+	return nil
+}
+
+// watch the directory of the file, which survives editors replacing it.
+// Events are debounced as writing a file is usually more than one.
+func (f *fileRegistry) watch(fw *fsnotify.Watcher, path string) {
+	var reload <-chan time.Time
+
+	for {
+		select {
+		case <-reload:
+			reload = nil
+			if err := f.reload(); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("error reloading registry file: %v", err)
+				}
+			}
+		case event, ok := <-fw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != path {
+				continue
+			}
+			// removed or renamed files are usually being replaced
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			reload = time.After(reloadDelay)
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return
+			}
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("error watching registry file: %v", err)
+			}
+		}
+	}
+}
+
+// configure the path and persistence from the options
+func (f *fileRegistry) configure() error {
+	path := DefaultPath
+	if len(f.opts.Addrs) > 0 && len(f.opts.Addrs[0]) > 0 {
+		path = f.opts.Addrs[0]
+	}
+	var persist *bool
+	if f.opts.Context != nil {
+		if p, ok := f.opts.Context.Value(pathKey{}).(string); ok && len(p) > 0 {
+			path = p
+		}
+		if b, ok := f.opts.Context.Value(persistKey{}).(bool); ok {
+			persist = &b
+		}
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	f.Lock()
+	if persist != nil {
+		f.persist = *persist
+	}
+	if f.fw != nil && f.path == path {
+		f.Unlock()
+		return nil
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		f.Unlock()
+		return err
+	}
+	if err := fw.Add(filepath.Dir(path)); err != nil {
+		fw.Close()
+		f.Unlock()
+		return err
+	}
+
+	if f.fw != nil {
+		f.fw.Close()
+	}
+	f.fw = fw
+	f.path = path
+	f.Unlock()
+
+	go f.watch(fw, path)
+
+	return f.reload()
+}
+
+// update the overlay with fn, sending the changes to watchers
+func (f *fileRegistry) update(fn func()) error {
+	f.Lock()
+	old := f.view()
+	fn()
+	neu := f.view()
+	results := diff(old, neu)
+
+	var err error
+	if f.persist && len(results) > 0 {
+		err = write(f.path, neu)
+	}
+	f.notify(results)
+	f.Unlock()
+
+	return err
+}
+
+func (f *fileRegistry) Init(opts ...registry.Option) error {
+	for _, o := range opts {
+		o(&f.opts)
+	}
+	return f.configure()
+}
+
+func (f *fileRegistry) Options() registry.Options {
+	return f.opts
+}
+
+// Register the nodes of the service. The ttl is ignored, nodes are kept
+// until they're deregistered.
+func (f *fileRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	return f.update(func() {
+		f.added.add(s)
+		for _, n := range s.Nodes {
+			delete(f.removed, removedKey(s.Name, s.Version, n.Id))
+		}
+	})
+}
+
+func (f *fileRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
+	return f.update(func() {
+		for _, n := range s.Nodes {
+			f.removed[removedKey(s.Name, s.Version, n.Id)] = true
+		}
+	})
+}
+
+func (f *fileRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	f.RLock()
+	view := f.view()
+	f.RUnlock()
+
+	versions, ok := view[name]
+	if !ok {
+		return nil, registry.ErrNotFound
+	}
+	return services{name: versions}.list(), nil
+}
+
+func (f *fileRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	f.RLock()
+	view := f.view()
+	f.RUnlock()
+
+	return view.list(), nil
+}
+
+func (f *fileRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+
+	w := &watcher{
+		wo:   wo,
+		next: make(chan bool, 1),
+		exit: make(chan bool),
+	}
+
+	f.Lock()
+	f.watchers[w] = true
+	f.Unlock()
+
+	go func() {
+		<-w.exit
+		f.Lock()
+		delete(f.watchers, w)
+		f.Unlock()
+	}()
+
+	return w, nil
+}
+
+func (f *fileRegistry) String() string {
+	return "file"
+}
+
+// NewRegistry returns a registry reading services from a yaml or json file
+func NewRegistry(opts ...registry.Option) registry.Registry {
+	f := &fileRegistry{
+		file:     make(services),
+		added:    make(services),
+		removed:  make(map[string]bool),
+		watchers: make(map[*watcher]bool),
+	}
+
+	for _, o := range opts {
+		o(&f.opts)
+	}
+
+	if err := f.configure(); err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("error reading registry file: %v", err)
+		}
+	}
+
+	return f
+}
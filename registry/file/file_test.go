@@ -0,0 +1,276 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func next(t *testing.T, w registry.Watcher) *registry.Result {
+	ch := make(chan *registry.Result, 1)
+	go func() {
+		res, err := w.Next()
+		if err != nil {
+			t.Error(err)
+		}
+		ch <- res
+	}()
+
+	select {
+	case res := <-ch:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return nil
+}
+
+func TestFileRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "registry.yaml")
+	writeFile(t, path, `
+services:
+  - name: greeter
+    version: 1.0.0
+    nodes:
+      - id: greeter-1
+        address: 10.0.0.1:8080
+        metadata:
+          zone: a
+      - address: 10.0.0.2:8080
+`)
+
+	r := NewRegistry(Path(path))
+
+	services, err := r.GetService("greeter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 2 {
+		t.Fatalf("unexpected services %+v", services)
+	}
+	if id := services[0].Nodes[1].Id; id != "greeter-10.0.0.2:8080" {
+		t.Fatalf("expected a node without an id to be identified by its address, got %s", id)
+	}
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// a node is removed and another one added to the file
+	writeFile(t, path, `
+services:
+  - name: greeter
+    version: 1.0.0
+    nodes:
+      - id: greeter-1
+        address: 10.0.0.1:8080
+      - id: greeter-3
+        address: 10.0.0.3:8080
+`)
+
+	res := next(t, w)
+	if res.Action != "delete" || len(res.Service.Nodes) != 1 || res.Service.Nodes[0].Id != "greeter-10.0.0.2:8080" {
+		t.Fatalf("expected the removed node to be deleted, got %s %+v", res.Action, res.Service.Nodes)
+	}
+	res = next(t, w)
+	if res.Action != "update" || len(res.Service.Nodes) != 2 {
+		t.Fatalf("expected an update of the service, got %s %+v", res.Action, res.Service.Nodes)
+	}
+
+	// registered services are kept on top of the file
+	srv := &registry.Service{
+		Name:    "greeter",
+		Version: "2.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-4", Address: "10.0.0.4:8080"}},
+	}
+	if err := r.Register(srv); err != nil {
+		t.Fatal(err)
+	}
+	if res := next(t, w); res.Action != "create" || res.Service.Version != "2.0.0" {
+		t.Fatalf("expected the new version to be created, got %s %s", res.Action, res.Service.Version)
+	}
+
+	// as are nodes of the file being deregistered
+	if err := r.Deregister(&registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-1"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if res := next(t, w); res.Action != "delete" || res.Service.Nodes[0].Id != "greeter-1" {
+		t.Fatalf("expected the node to be deleted, got %s %+v", res.Action, res.Service.Nodes)
+	}
+
+	services, err = r.GetService("greeter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 || len(services[0].Nodes) != 1 || services[0].Nodes[0].Id != "greeter-3" {
+		t.Fatalf("unexpected services %+v", services)
+	}
+
+	// the file is left as it is
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s["greeter"]["2.0.0"]; ok {
+		t.Fatalf("expected the registration not to be persisted, got %s", b)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "registry.json")
+	writeFile(t, path, `{"services": []}`)
+
+	r := NewRegistry(Path(path), Persist(true))
+
+	srv := &registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-1", Address: "10.0.0.1:8080"}},
+	}
+	if err := r.Register(srv); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s["greeter"]["1.0.0"].Nodes) != 1 {
+		t.Fatalf("expected the registration to be written to the file, got %+v", s)
+	}
+
+	// a new registry reads what was persisted
+	services, err := NewRegistry(Path(path)).GetService("greeter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if services[0].Nodes[0].Address != "10.0.0.1:8080" {
+		t.Fatalf("unexpected services %+v", services)
+	}
+
+	if err := r.Deregister(srv); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = read(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 0 {
+		t.Fatalf("expected the service to be removed from the file, got %+v", s)
+	}
+}
+
+func TestStalledWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "registry.yaml")
+	writeFile(t, path, "services: []\n")
+
+	r := NewRegistry(Path(path))
+
+	// never read from
+	stalled, err := r.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalled.Stop()
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 100; i++ {
+			if err := r.Register(&registry.Service{
+				Name:    fmt.Sprintf("greeter-%d", i),
+				Version: "1.0.0",
+				Nodes:   []*registry.Node{{Id: "greeter-1", Address: "10.0.0.1:8080"}},
+			}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("registering blocked on a stalled watcher")
+	}
+
+	// watchers which do read still get the events
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if err := r.Register(&registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "foo-1", Address: "10.0.0.2:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if res := next(t, w); res.Action != "create" || res.Service.Name != "foo" {
+		t.Fatalf("unexpected event %+v", res)
+	}
+
+	if err := r.Deregister(&registry.Service{
+		Name:    "greeter-0",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-1"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stalled watcher gets every event once it reads, deletes included
+	for i := 0; i < 100; i++ {
+		if res := next(t, stalled); res.Action != "create" || res.Service.Name != fmt.Sprintf("greeter-%d", i) {
+			t.Fatalf("unexpected event %+v", res)
+		}
+	}
+	if res := next(t, stalled); res.Action != "create" || res.Service.Name != "foo" {
+		t.Fatalf("unexpected event %+v", res)
+	}
+	if res := next(t, stalled); res.Action != "delete" || res.Service.Name != "greeter-0" {
+		t.Fatalf("unexpected event %+v", res)
+	}
+}
@@ -0,0 +1,35 @@
+package file
+
+import (
+	"context"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+// DefaultPath of the file read when neither a path nor an address is set
+var DefaultPath = "registry.yaml"
+
+type pathKey struct{}
+
+type persistKey struct{}
+
+// Path of the yaml or json file the services are read from.
+// The first registry address is used when it isn't set.
+func Path(p string) registry.Option {
+	return setOption(pathKey{}, p)
+}
+
+// Persist writes the services registered and deregistered through the
+// registry back to the file, rather than only keeping them in memory
+func Persist(b bool) registry.Option {
+	return setOption(persistKey{}, b)
+}
+
+func setOption(k, v interface{}) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
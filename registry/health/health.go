@@ -0,0 +1,336 @@
+// Package health provides a registry which actively checks the health of
+// registered nodes. The state of a node is set in its metadata and changes
+// are sent to watchers as update events so selectors can skip unhealthy nodes.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/registry"
+	util "github.com/asim/go-micro/v3/util/registry"
+)
+
+const (
+	// MetadataKey of the node metadata holding its health
+	MetadataKey = "health"
+	// Healthy node
+	Healthy = "healthy"
+	// Unhealthy node
+	Unhealthy = "unhealthy"
+)
+
+// Registry which checks the health of the nodes registered with it
+type Registry interface {
+	registry.Registry
+	// Stop checking nodes
+	Stop()
+}
+
+// state of a node
+type state struct {
+	healthy  bool
+	failures int
+}
+
+type healthRegistry struct {
+	registry.Registry
+	opts Options
+
+	sync.RWMutex
+	// state of the nodes by id
+	nodes map[string]*state
+	// watchers sent health events
+	watchers map[*watcher]bool
+
+	exit chan bool
+}
+
+type watcher struct {
+	wo   registry.WatchOptions
+	res  chan *registry.Result
+	exit chan bool
+}
+
+func (w *watcher) Next() (*registry.Result, error) {
+	select {
+	case r := <-w.res:
+		return r, nil
+	case <-w.exit:
+		return nil, registry.ErrWatcherStopped
+	}
+}
+
+func (w *watcher) Stop() {
+	select {
+	case <-w.exit:
+	default:
+		close(w.exit)
+	}
+}
+
+// annotate copies the services setting the health of nodes which were checked
+func (h *healthRegistry) annotate(services []*registry.Service) []*registry.Service {
+	services = util.Copy(services)
+
+	h.RLock()
+	defer h.RUnlock()
+
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			st, ok := h.nodes[n.Id]
+			if !ok {
+				continue
+			}
+			md := make(map[string]string, len(n.Metadata)+1)
+			for k, v := range n.Metadata {
+				md[k] = v
+			}
+			md[MetadataKey] = Unhealthy
+			if st.healthy {
+				md[MetadataKey] = Healthy
+			}
+			n.Metadata = md
+		}
+	}
+
+	return services
+}
+
+func (h *healthRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	services, err := h.Registry.GetService(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return h.annotate(services), nil
+}
+
+func (h *healthRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	services, err := h.Registry.ListServices(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return h.annotate(services), nil
+}
+
+// Watch the registry, the events include the health of nodes
+// along with an update event whenever the health of a node changes
+func (h *healthRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+
+	rw, err := h.Registry.Watch(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{
+		wo:   wo,
+		res:  make(chan *registry.Result),
+		exit: make(chan bool),
+	}
+
+	h.Lock()
+	h.watchers[w] = true
+	h.Unlock()
+
+	go func() {
+		<-w.exit
+		rw.Stop()
+		h.Lock()
+		delete(h.watchers, w)
+		h.Unlock()
+	}()
+
+	go func() {
+		defer w.Stop()
+		for {
+			res, err := rw.Next()
+			if err != nil {
+				return
+			}
+			if res.Service != nil {
+				res.Service = h.annotate([]*registry.Service{res.Service})[0]
+			}
+			select {
+			case w.res <- res:
+			case <-w.exit:
+				return
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+func (h *healthRegistry) String() string {
+	return h.Registry.String()
+}
+
+func (h *healthRegistry) Stop() {
+	select {
+	case <-h.exit:
+	default:
+		close(h.exit)
+	}
+}
+
+// notify the watchers of the node's new state
+func (h *healthRegistry) notify(service *registry.Service, node *registry.Node) {
+	srv := new(registry.Service)
+	*srv = *service
+	srv.Nodes = []*registry.Node{node}
+	res := &registry.Result{
+		Action:  "update",
+		Service: h.annotate([]*registry.Service{srv})[0],
+	}
+
+	h.RLock()
+	watchers := make([]*watcher, 0, len(h.watchers))
+	for w := range h.watchers {
+		if len(w.wo.Service) == 0 || w.wo.Service == service.Name {
+			watchers = append(watchers, w)
+		}
+	}
+	h.RUnlock()
+
+	for _, w := range watchers {
+		select {
+		case w.res <- res:
+		case <-w.exit:
+		case <-h.exit:
+			return
+		}
+	}
+}
+
+// check the node and record its state, returning true if it changed
+func (h *healthRegistry) check(service *registry.Service, node *registry.Node) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), h.opts.Timeout)
+	defer cancel()
+	err := h.opts.Check(ctx, service, node)
+
+	h.Lock()
+	defer h.Unlock()
+
+	st, ok := h.nodes[node.Id]
+	if !ok {
+		// nodes start out healthy
+		st = &state{healthy: true}
+		h.nodes[node.Id] = st
+	}
+
+	if err == nil {
+		st.failures = 0
+		if !st.healthy {
+			st.healthy = true
+			return true
+		}
+		return false
+	}
+
+	st.failures++
+	if logger.V(logger.DebugLevel, logger.DefaultLogger) {
+		logger.Debugf("health check of %s node %s failed: %v", service.Name, node.Id, err)
+	}
+	if st.healthy && st.failures >= h.opts.Threshold {
+		st.healthy = false
+		if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+			logger.Warnf("%s node %s is unhealthy: %v", service.Name, node.Id, err)
+		}
+		return true
+	}
+	return false
+}
+
+// checkAll the nodes of every service
+func (h *healthRegistry) checkAll() error {
+	list, err := h.Registry.ListServices()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	names := make(map[string]bool)
+	var wg sync.WaitGroup
+
+	for _, s := range list {
+		if names[s.Name] {
+			continue
+		}
+		names[s.Name] = true
+
+		services, err := h.Registry.GetService(s.Name)
+		if err != nil {
+			continue
+		}
+
+		for _, service := range services {
+			for _, node := range service.Nodes {
+				seen[node.Id] = true
+				wg.Add(1)
+				go func(service *registry.Service, node *registry.Node) {
+					defer wg.Done()
+					if h.check(service, node) {
+						h.notify(service, node)
+					}
+				}(service, node)
+			}
+		}
+	}
+
+	wg.Wait()
+
+	// forget the nodes which are gone
+	h.Lock()
+	for id := range h.nodes {
+		if !seen[id] {
+			delete(h.nodes, id)
+		}
+	}
+	h.Unlock()
+
+	return nil
+}
+
+func (h *healthRegistry) run() {
+	t := time.NewTicker(h.opts.Interval)
+	defer t.Stop()
+
+	for {
+		if err := h.checkAll(); err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Errorf("error listing services to health check: %v", err)
+			}
+		}
+
+		select {
+		case <-t.C:
+		case <-h.exit:
+			return
+		}
+	}
+}
+
+// NewRegistry returns a registry which checks the health of the nodes of r
+func NewRegistry(r registry.Registry, opts ...Option) Registry {
+	if r == nil {
+		r = registry.DefaultRegistry
+	}
+
+	h := &healthRegistry{
+		Registry: r,
+		opts:     newOptions(opts...),
+		nodes:    make(map[string]*state),
+		watchers: make(map[*watcher]bool),
+		exit:     make(chan bool),
+	}
+
+	go h.run()
+
+	return h
+}
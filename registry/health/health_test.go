@@ -0,0 +1,101 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+func TestHealth(t *testing.T) {
+	m := registry.NewMemoryRegistry()
+	if err := m.Register(&registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes: []*registry.Node{
+			{Id: "foo-1", Address: "10.0.0.1:8080"},
+			{Id: "foo-2", Address: "10.0.0.2:8080"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mtx sync.Mutex
+	failing := map[string]bool{"foo-2": true}
+
+	r := NewRegistry(m,
+		Interval(10*time.Millisecond),
+		Threshold(2),
+		WithCheck(func(ctx context.Context, s *registry.Service, n *registry.Node) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if failing[n.Id] {
+				return errors.New("down")
+			}
+			return nil
+		}),
+	)
+	defer r.Stop()
+
+	w, err := r.Watch(registry.WatchService("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// health events update a single node, the registry's own events are skipped
+	next := func() *registry.Node {
+		ch := make(chan *registry.Node, 1)
+		go func() {
+			for {
+				res, err := w.Next()
+				if err != nil {
+					return
+				}
+				if res.Action == "update" && len(res.Service.Nodes) == 1 {
+					ch <- res.Service.Nodes[0]
+					return
+				}
+			}
+		}()
+		select {
+		case node := <-ch:
+			return node
+		case <-time.After(time.Second):
+			t.Fatal("Expected a health event")
+		}
+		return nil
+	}
+
+	node := next()
+	if node.Id != "foo-2" || node.Metadata[MetadataKey] != Unhealthy {
+		t.Fatalf("Expected foo-2 to be unhealthy got %+v", node)
+	}
+
+	services, err := r.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range services[0].Nodes {
+		expect := Healthy
+		if n.Id == "foo-2" {
+			expect = Unhealthy
+		}
+		if v := n.Metadata[MetadataKey]; v != expect {
+			t.Fatalf("Expected %s to be %s got %s", n.Id, expect, v)
+		}
+	}
+
+	// recover
+	mtx.Lock()
+	failing["foo-2"] = false
+	mtx.Unlock()
+
+	node = next()
+	if node.Id != "foo-2" || node.Metadata[MetadataKey] != Healthy {
+		t.Fatalf("Expected foo-2 to be healthy got %+v", node)
+	}
+}
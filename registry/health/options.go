@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/asim/go-micro/v3/client"
+	proto "github.com/asim/go-micro/v3/debug/proto"
+	"github.com/asim/go-micro/v3/registry"
+)
+
+var (
+	// DefaultInterval between health checks of a node
+	DefaultInterval = 10 * time.Second
+	// DefaultTimeout of a health check
+	DefaultTimeout = 2 * time.Second
+	// DefaultThreshold of consecutive failed checks before a node is unhealthy
+	DefaultThreshold = 2
+)
+
+// Check probes the node of a service, returning an error if it's unhealthy
+type Check func(ctx context.Context, service *registry.Service, node *registry.Node) error
+
+type Options struct {
+	// Check used to probe nodes, defaults to a tcp dial
+	Check Check
+	// Interval between checks
+	Interval time.Duration
+	// Timeout of a check
+	Timeout time.Duration
+	// Threshold of consecutive failures before a node is unhealthy
+	Threshold int
+}
+
+type Option func(o *Options)
+
+// WithCheck sets the check used to probe nodes
+func WithCheck(c Check) Option {
+	return func(o *Options) {
+		o.Check = c
+	}
+}
+
+// Interval between checks of a node
+func Interval(d time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = d
+	}
+}
+
+// Timeout of a check
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// Threshold of consecutive failed checks before a node is marked unhealthy.
+// A single successful check marks it healthy again.
+func Threshold(n int) Option {
+	return func(o *Options) {
+		o.Threshold = n
+	}
+}
+
+// TCP checks a connection can be made to the node
+func TCP() Check {
+	return func(ctx context.Context, service *registry.Service, node *registry.Node) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", node.Address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// RPC calls the Debug.Health endpoint of the node which every service has
+func RPC(c client.Client) Check {
+	return func(ctx context.Context, service *registry.Service, node *registry.Node) error {
+		rsp, err := proto.NewDebugService(service.Name, c).Health(ctx, &proto.HealthRequest{}, client.WithAddress(node.Address))
+		if err != nil {
+			return err
+		}
+		if rsp.Status != "ok" {
+			return fmt.Errorf("status %s", rsp.Status)
+		}
+		return nil
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Check:     TCP(),
+		Interval:  DefaultInterval,
+		Timeout:   DefaultTimeout,
+		Threshold: DefaultThreshold,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
@@ -1,6 +1,9 @@
 package selector
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/asim/go-micro/v3/registry"
@@ -13,11 +16,14 @@ type registrySelector struct {
 }
 
 func (c *registrySelector) newCache() cache.Cache {
-	opts := make([]cache.Option, 0, 1)
+	opts := make([]cache.Option, 0, 2)
 	if c.so.Context != nil {
 		if t, ok := c.so.Context.Value("selector_ttl").(time.Duration); ok {
 			opts = append(opts, cache.WithTTL(t))
 		}
+		if p, ok := c.so.Context.Value("selector_snapshot").(string); ok {
+			opts = append(opts, cache.WithSnapshot(p))
+		}
 	}
 	return cache.New(c.so.Registry, opts...)
 }
@@ -57,19 +63,47 @@ func (c *registrySelector) Select(service string, opts ...SelectOption) (Next, e
 		return nil, err
 	}
 
-	// apply the filters
+	// versions seen, reported when none are left
+	versions := serviceVersions(services)
+
+	// skip unhealthy nodes then apply the filters
+	services = FilterHealthy()(services)
 	for _, filter := range sopts.Filters {
 		services = filter(services)
 	}
 
+	// prefer the nodes closest to us out of those left
+	if c.so.Context != nil {
+		if l, ok := c.so.Context.Value("selector_locality").([2]string); ok {
+			services = FilterLocality(l[0], l[1])(services)
+		}
+	}
+
 	// if there's nothing left, return
 	if len(services) == 0 {
+		if len(sopts.Version) > 0 {
+			return nil, fmt.Errorf("%w: no nodes of %s meet version %s, versions seen %s", ErrNoneAvailable, service, sopts.Version, strings.Join(versions, ", "))
+		}
 		return nil, ErrNoneAvailable
 	}
 
 	return sopts.Strategy(services), nil
 }
 
+// serviceVersions returns the sorted versions of the services
+func serviceVersions(services []*registry.Service) []string {
+	seen := make(map[string]bool)
+	var versions []string
+	for _, s := range services {
+		if !seen[s.Version] {
+			seen[s.Version] = true
+			versions = append(versions, s.Version)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
 func (c *registrySelector) Mark(service string, node *registry.Node, err error) {
 }
 
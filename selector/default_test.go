@@ -1,7 +1,9 @@
 package selector
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/asim/go-micro/v3/registry"
@@ -30,3 +32,22 @@ func TestRegistrySelector(t *testing.T) {
 		t.Logf("Selector Counts %v", counts)
 	}
 }
+
+func TestRegistrySelectorNoneAvailable(t *testing.T) {
+	r := registry.NewMemoryRegistry(registry.Services(testData))
+	s := NewSelector(Registry(r))
+
+	_, err := s.Select("foo", WithVersion(">=2.0"))
+	if !errors.Is(err, ErrNoneAvailable) {
+		t.Fatalf("Expected none available got %v", err)
+	}
+	if !strings.Contains(err.Error(), "versions seen 1.0.0, 1.0.1") {
+		t.Fatalf("Expected the versions seen in %v", err)
+	}
+
+	// the sentinel as is without a version
+	_, err = s.Select("foo", WithFilter(FilterVersion("2.0.0")))
+	if err != ErrNoneAvailable {
+		t.Fatalf("Expected none available got %v", err)
+	}
+}
@@ -71,3 +71,58 @@ func FilterVersion(version string) Filter {
 		return services
 	}
 }
+
+// FilterHealthy is a Select Filter which skips the nodes marked unhealthy
+// in their metadata, as done by the health checking registry. Nodes which
+// haven't been checked are treated as healthy. It's applied by default.
+func FilterHealthy() Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		var services []*registry.Service
+
+		for _, service := range old {
+			var nodes []*registry.Node
+
+			for _, node := range service.Nodes {
+				if node.Metadata != nil && node.Metadata["health"] == "unhealthy" {
+					continue
+				}
+				nodes = append(nodes, node)
+			}
+
+			// only add service if there's some nodes
+			if len(nodes) > 0 {
+				// copy
+				serv := new(registry.Service)
+				*serv = *service
+				serv.Nodes = nodes
+				services = append(services, serv)
+			}
+		}
+
+		return services
+	}
+}
+
+// FilterLocality is a Select Filter which prefers the nodes closest to the
+// caller based on their region and zone metadata, as labelled by the
+// federated registry. Nodes in the same zone are returned if there are any,
+// otherwise those in the same region and then every node.
+func FilterLocality(region, zone string) Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		if len(zone) > 0 {
+			services := FilterLabel("zone", zone)(old)
+			if len(region) > 0 {
+				services = FilterLabel("region", region)(services)
+			}
+			if len(services) > 0 {
+				return services
+			}
+		}
+		if len(region) > 0 {
+			if services := FilterLabel("region", region)(old); len(services) > 0 {
+				return services
+			}
+		}
+		return old
+	}
+}
@@ -237,3 +237,86 @@ func TestFilterVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterHealthy(t *testing.T) {
+	services := []*registry.Service{
+		{
+			Name:    "test",
+			Version: "1.0.0",
+			Nodes: []*registry.Node{
+				{Id: "test-1", Metadata: map[string]string{"health": "healthy"}},
+				{Id: "test-2", Metadata: map[string]string{"health": "unhealthy"}},
+				{Id: "test-3"},
+			},
+		},
+		{
+			Name:    "test",
+			Version: "1.1.0",
+			Nodes: []*registry.Node{
+				{Id: "test-4", Metadata: map[string]string{"health": "unhealthy"}},
+			},
+		},
+	}
+
+	filtered := FilterHealthy()(services)
+	if len(filtered) != 1 {
+		t.Fatalf("Expected 1 service got %d", len(filtered))
+	}
+	if n := filtered[0].Nodes; len(n) != 2 || n[0].Id != "test-1" || n[1].Id != "test-3" {
+		t.Fatalf("Expected the healthy and unchecked nodes got %+v", n)
+	}
+	if len(services[0].Nodes) != 3 {
+		t.Fatal("Expected the services to be left alone")
+	}
+}
+
+func TestFilterLocality(t *testing.T) {
+	services := []*registry.Service{
+		{
+			Name:    "test",
+			Version: "1.0.0",
+			Nodes: []*registry.Node{
+				{Id: "test-1", Metadata: map[string]string{"region": "us-east", "zone": "a"}},
+				{Id: "test-2", Metadata: map[string]string{"region": "us-east", "zone": "b"}},
+				{Id: "test-3", Metadata: map[string]string{"region": "eu-west", "zone": "a"}},
+			},
+		},
+		{
+			Name:    "test",
+			Version: "1.1.0",
+			Nodes: []*registry.Node{
+				{Id: "test-4", Metadata: map[string]string{"region": "us-east", "zone": "b"}},
+				{Id: "test-5"},
+			},
+		},
+	}
+
+	testData := []struct {
+		region string
+		zone   string
+		nodes  []string
+	}{
+		{"us-east", "a", []string{"test-1"}},
+		{"us-east", "b", []string{"test-2", "test-4"}},
+		{"us-east", "c", []string{"test-1", "test-2", "test-4"}},
+		{"eu-west", "a", []string{"test-3"}},
+		{"ap-south", "a", []string{"test-1", "test-2", "test-3", "test-4", "test-5"}},
+	}
+
+	for _, d := range testData {
+		var nodes []string
+		for _, s := range FilterLocality(d.region, d.zone)(services) {
+			for _, n := range s.Nodes {
+				nodes = append(nodes, n.Id)
+			}
+		}
+		if len(nodes) != len(d.nodes) {
+			t.Fatalf("Expected %v in %s %s got %v", d.nodes, d.region, d.zone, nodes)
+		}
+		for i := range nodes {
+			if nodes[i] != d.nodes[i] {
+				t.Fatalf("Expected %v in %s %s got %v", d.nodes, d.region, d.zone, nodes)
+			}
+		}
+	}
+}
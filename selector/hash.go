@@ -0,0 +1,310 @@
+package selector
+
+import (
+	"hash/crc32"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+var (
+	// DefaultHash is used by clients calling with a routing key
+	DefaultHash = NewHash()
+
+	// DefaultReplicas is the number of virtual nodes of a node on the ring
+	DefaultReplicas = 100
+	// DefaultLoadFactor a node may be loaded to relative to the average
+	DefaultLoadFactor = 1.25
+
+	// maxRings cached, the least recently used is dropped beyond it
+	maxRings = 64
+)
+
+type HashOptions struct {
+	// Replicas is the number of virtual nodes of a node on the ring
+	Replicas int
+	// LoadFactor is the load a node may take relative to the average
+	// before keys spill over to the next node on the ring
+	LoadFactor float64
+}
+
+type HashOption func(o *HashOptions)
+
+// HashReplicas sets the number of virtual nodes of a node on the ring
+func HashReplicas(n int) HashOption {
+	return func(o *HashOptions) {
+		o.Replicas = n
+	}
+}
+
+// HashLoadFactor sets the load a node may take relative to the average
+func HashLoadFactor(f float64) HashOption {
+	return func(o *HashOptions) {
+		o.LoadFactor = f
+	}
+}
+
+// Hash routes keys to nodes with consistent hashing, so a key lands on the
+// same node as long as it's up and only the keys of nodes coming or going
+// move. The load is bounded, a node with more calls in flight than the load
+// factor times the average of its ring is skipped for the next one. Calls are
+// in flight from their node being selected until Done is called with it.
+type Hash struct {
+	opts HashOptions
+
+	sync.Mutex
+	// rings by the id of their nodes
+	rings map[string]*ring
+	// rings of the selected nodes in flight
+	inflight map[*registry.Node][]*ring
+}
+
+// ring of virtual nodes
+type ring struct {
+	// id of the nodes it was built from
+	id     string
+	hashes []uint32
+	nodes  map[uint32]*registry.Node
+	count  int
+	// last time it was used
+	used time.Time
+	// calls in flight by node id
+	load  map[string]int
+	total int
+}
+
+func hashKey(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+func newRing(id string, nodes []*registry.Node, replicas int) *ring {
+	r := &ring{
+		id:    id,
+		nodes: make(map[uint32]*registry.Node, len(nodes)*replicas),
+		count: len(nodes),
+		load:  make(map[string]int),
+	}
+
+	for _, n := range nodes {
+		for i := 0; i < replicas; i++ {
+			h := hashKey(n.Id + "-" + strconv.Itoa(i))
+			// on a collision the lowest id wins so rings are the same everywhere
+			if cur, ok := r.nodes[h]; ok && cur.Id < n.Id {
+				continue
+			}
+			if _, ok := r.nodes[h]; !ok {
+				r.hashes = append(r.hashes, h)
+			}
+			r.nodes[h] = n
+		}
+	}
+
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// walk the ring from the key calling fn with every node once, in order
+func (r *ring) walk(key string, fn func(*registry.Node) bool) {
+	if len(r.hashes) == 0 {
+		return
+	}
+
+	h := hashKey(key)
+	start := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+
+	seen := make(map[string]bool, r.count)
+	for i := 0; i < len(r.hashes) && len(seen) < r.count; i++ {
+		n := r.nodes[r.hashes[(start+i)%len(r.hashes)]]
+		if seen[n.Id] {
+			continue
+		}
+		seen[n.Id] = true
+		if !fn(n) {
+			return
+		}
+	}
+}
+
+// ringOf the nodes of the services, built once for every set of nodes
+// so services selected with different filters don't rebuild each other's
+func (h *Hash) ringOf(services []*registry.Service) *ring {
+	var nodes []*registry.Node
+	for _, s := range services {
+		nodes = append(nodes, s.Nodes...)
+	}
+
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.Id
+	}
+	sort.Strings(ids)
+	id := strings.Join(ids, ",")
+
+	h.Lock()
+	r, ok := h.rings[id]
+	if ok {
+		r.used = time.Now()
+	}
+	h.Unlock()
+
+	if ok {
+		return r
+	}
+
+	// built without the lock held
+	r = newRing(id, nodes, h.opts.Replicas)
+	r.used = time.Now()
+
+	h.Lock()
+	defer h.Unlock()
+
+	if cur, ok := h.rings[id]; ok {
+		return cur
+	}
+	if len(h.rings) >= maxRings {
+		// rings with calls in flight are kept if possible
+		var oldest *ring
+		for _, c := range h.rings {
+			if oldest == nil || (c.total == 0) != (oldest.total == 0) && c.total == 0 ||
+				(c.total == 0) == (oldest.total == 0) && c.used.Before(oldest.used) {
+				oldest = c
+			}
+		}
+		delete(h.rings, oldest.id)
+	}
+	h.rings[id] = r
+	return r
+}
+
+// capacity of a node of the ring, the load factor times the average
+// load of the ring including the selection being made
+func (h *Hash) capacity(r *ring) int {
+	avg := float64(r.total+1) / float64(r.count)
+	return int(math.Ceil(avg * h.opts.LoadFactor))
+}
+
+// pick the first node from the key which isn't overloaded, skipping those tried
+func (h *Hash) pick(r *ring, key string, tried map[string]bool) *registry.Node {
+	h.Lock()
+	defer h.Unlock()
+
+	capacity := h.capacity(r)
+
+	var node, first *registry.Node
+	r.walk(key, func(n *registry.Node) bool {
+		if tried[n.Id] {
+			return true
+		}
+		if first == nil {
+			first = n
+		}
+		if r.load[n.Id] < capacity {
+			node = n
+			return false
+		}
+		return true
+	})
+
+	// every node is at capacity
+	if node == nil {
+		node = first
+	}
+	if node != nil {
+		r.load[node.Id]++
+		r.total++
+		h.inflight[node] = append(h.inflight[node], r)
+	}
+	return node
+}
+
+// Load of the node, the number of its calls in flight
+func (h *Hash) Load(id string) int {
+	h.Lock()
+	defer h.Unlock()
+
+	var load int
+	for n, rings := range h.inflight {
+		if n.Id == id {
+			load += len(rings)
+		}
+	}
+	return load
+}
+
+// Done marks the call to the node as no longer in flight
+func (h *Hash) Done(node *registry.Node) {
+	h.Lock()
+	defer h.Unlock()
+
+	rings := h.inflight[node]
+	if len(rings) == 0 {
+		return
+	}
+	r := rings[len(rings)-1]
+	if len(rings) == 1 {
+		delete(h.inflight, node)
+	} else {
+		h.inflight[node] = rings[:len(rings)-1]
+	}
+
+	r.load[node.Id]--
+	if r.load[node.Id] <= 0 {
+		delete(r.load, node.Id)
+	}
+	r.total--
+}
+
+// Strategy routing the key to a node. Every call of Next after the
+// first returns the next node on the ring for retries.
+func (h *Hash) Strategy(key string) Strategy {
+	return func(services []*registry.Service) Next {
+		if len(services) == 0 {
+			return func() (*registry.Node, error) {
+				return nil, ErrNoneAvailable
+			}
+		}
+
+		r := h.ringOf(services)
+		tried := make(map[string]bool)
+		var mtx sync.Mutex
+
+		return func() (*registry.Node, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			// start over once every node was tried
+			if len(tried) >= r.count {
+				tried = make(map[string]bool)
+			}
+
+			node := h.pick(r, key, tried)
+			if node == nil {
+				return nil, ErrNoneAvailable
+			}
+			tried[node.Id] = true
+			return node, nil
+		}
+	}
+}
+
+// NewHash returns a consistent hash router
+func NewHash(opts ...HashOption) *Hash {
+	options := HashOptions{
+		Replicas:   DefaultReplicas,
+		LoadFactor: DefaultLoadFactor,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &Hash{
+		opts:     options,
+		rings:    make(map[string]*ring),
+		inflight: make(map[*registry.Node][]*ring),
+	}
+}
@@ -0,0 +1,179 @@
+package selector
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+func hashServices(n int) []*registry.Service {
+	var nodes []*registry.Node
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, &registry.Node{
+			Id:      fmt.Sprintf("node-%d", i),
+			Address: fmt.Sprintf("10.0.0.%d:8080", i),
+		})
+	}
+	return []*registry.Service{{Name: "test", Version: "latest", Nodes: nodes}}
+}
+
+// hashNode selects the node of the key, the call is done unless in flight
+func hashNode(t *testing.T, h *Hash, services []*registry.Service, key string, inflight bool) string {
+	node, err := h.Strategy(key)(services)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inflight {
+		h.Done(node)
+	}
+	return node.Id
+}
+
+func TestHashSameNode(t *testing.T) {
+	h := NewHash()
+	services := hashServices(5)
+
+	first := hashNode(t, h, services, "user-1", false)
+	for i := 0; i < 3; i++ {
+		if id := hashNode(t, h, services, "user-1", false); id != first {
+			t.Fatalf("expected key on %s, got %s", first, id)
+		}
+	}
+}
+
+func TestHashMovement(t *testing.T) {
+	keys := 1000
+	h := NewHash()
+
+	before := make(map[string]string)
+	services := hashServices(5)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		before[key] = hashNode(t, h, services, key, false)
+	}
+
+	// a node joins, only keys moving to it should move
+	services = hashServices(6)
+	var moved int
+	for key, id := range before {
+		nid := hashNode(t, h, services, key, false)
+		if nid == id {
+			continue
+		}
+		if nid != "node-5" {
+			t.Fatalf("key %s moved from %s to %s", key, id, nid)
+		}
+		moved++
+	}
+
+	if moved == 0 || moved > keys/3 {
+		t.Fatalf("expected about a sixth of the keys to move, %d of %d did", moved, keys)
+	}
+}
+
+func TestHashBoundedLoad(t *testing.T) {
+	h := NewHash()
+	services := hashServices(4)
+
+	// calls of the hot key all in flight
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		counts[hashNode(t, h, services, "hot", true)]++
+	}
+
+	if len(counts) != 4 {
+		t.Fatalf("expected the hot key spilling over to every node, got %v", counts)
+	}
+	for id, n := range counts {
+		// 1.25 times the average of 25
+		if n > 32 {
+			t.Fatalf("node %s overloaded with %d of 100", id, n)
+		}
+	}
+}
+
+func TestHashLoadPerRing(t *testing.T) {
+	h := NewHash()
+	hot := hashServices(4)
+
+	// calls in flight to another service don't raise the capacity
+	other := hashServices(2)
+	other[0].Name = "other"
+	for _, n := range other[0].Nodes {
+		n.Id = "other-" + n.Id
+	}
+	for i := 0; i < 100; i++ {
+		hashNode(t, h, other, "cold", true)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		counts[hashNode(t, h, hot, "hot", true)]++
+	}
+	for id, n := range counts {
+		if n > 32 {
+			t.Fatalf("node %s overloaded with %d of 100", id, n)
+		}
+	}
+
+	// done calls leave the load of their own ring
+	node, err := h.Strategy("cold")(other)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	load := h.Load(node.Id)
+	h.Done(node)
+	if l := h.Load(node.Id); l != load-1 {
+		t.Fatalf("expected a load of %d, got %d", load-1, l)
+	}
+}
+
+func TestHashRetries(t *testing.T) {
+	h := NewHash()
+	next := h.Strategy("user-1")(hashServices(3))
+
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[node.Id] {
+			t.Fatalf("node %s retried before the others", node.Id)
+		}
+		seen[node.Id] = true
+	}
+
+	if _, err := h.Strategy("user-1")(nil)(); err != ErrNoneAvailable {
+		t.Fatalf("expected %v, got %v", ErrNoneAvailable, err)
+	}
+}
+
+func TestHashRings(t *testing.T) {
+	h := NewHash()
+	all := hashServices(4)
+
+	// the same service selected with different filters
+	some := []*registry.Service{{Name: "test", Version: "latest", Nodes: all[0].Nodes[:2]}}
+
+	a := h.ringOf(all)
+	b := h.ringOf(some)
+	if a == b {
+		t.Fatal("expected a ring for every set of nodes")
+	}
+	if h.ringOf(all) != a || h.ringOf(some) != b {
+		t.Fatal("expected the rings to be reused")
+	}
+
+	// the least recently used are dropped
+	for i := 0; i < maxRings; i++ {
+		h.ringOf(hashServices(i + 5))
+	}
+	if len(h.rings) != maxRings {
+		t.Fatalf("expected %d rings, got %d", maxRings, len(h.rings))
+	}
+	if _, ok := h.rings[a.id]; ok {
+		t.Fatal("expected the least recently used ring to be dropped")
+	}
+}
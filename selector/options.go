@@ -18,6 +18,8 @@ type Options struct {
 type SelectOptions struct {
 	Filters  []Filter
 	Strategy Strategy
+	// Version constraint the services must meet, see WithVersion
+	Version string
 
 	// Other options for implementations of the interface
 	// can be stored in a context
@@ -44,6 +46,28 @@ func SetStrategy(fn Strategy) Option {
 	}
 }
 
+// Snapshot persists the services cached by the selector to the file at
+// path. They're served when the registry is down, including at startup.
+func Snapshot(path string) Option {
+	return func(o *Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "selector_snapshot", path)
+	}
+}
+
+// Locality of the caller, nodes in its zone and then its region are
+// preferred on every select. See FilterLocality.
+func Locality(region, zone string) Option {
+	return func(o *Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "selector_locality", [2]string{region, zone})
+	}
+}
+
 // WithFilter adds a filter function to the list of filters
 // used during the Select call.
 func WithFilter(fn ...Filter) SelectOption {
@@ -52,6 +76,17 @@ func WithFilter(fn ...Filter) SelectOption {
 	}
 }
 
+// WithVersion only selects the services with a semantic version meeting
+// the constraint e.g. ">=1.4 <2.0", see FilterVersionConstraint. When none
+// do the error lists the versions seen and wraps ErrNoneAvailable, so is
+// checked with errors.Is.
+func WithVersion(constraint string) SelectOption {
+	return func(o *SelectOptions) {
+		o.Version = constraint
+		o.Filters = append(o.Filters, FilterVersionConstraint(constraint))
+	}
+}
+
 // Strategy sets the selector strategy
 func WithStrategy(fn Strategy) SelectOption {
 	return func(o *SelectOptions) {
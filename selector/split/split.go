@@ -0,0 +1,291 @@
+// Package split provides a selector strategy splitting traffic between the
+// versions of a service by weight, for canary releases and gradual rollouts.
+//
+// The weights are set per service and version, through Set or a config value
+// watched with Watch, falling back to the weight in the metadata of a service.
+// A version is picked by weight and then a node of that version.
+//
+//	s := split.New(split.Weights{
+//		"greeter": {"1.2.0": 90, "1.3.0": 10},
+//	})
+//	client.WithSelectOption(selector.WithStrategy(s.Strategy()))
+//
+// Callers can be kept on the same version by picking it with a key such
+// as the id of a user taken from the request metadata.
+//
+//	client.WithSelectOption(selector.WithStrategy(s.Sticky(md["User-Id"])))
+//
+// The versions selected are counted by Stats and reported in the
+// debug stats of the process.
+package split
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/asim/go-micro/v3/config"
+	"github.com/asim/go-micro/v3/debug/stats"
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/selector"
+)
+
+// WeightKey of the service metadata holding the weight of its version
+const WeightKey = "weight"
+
+// Weights of the versions of services, by service name and then version
+type Weights map[string]map[string]int
+
+// Stat of the traffic sent to a version of a service
+type Stat struct {
+	Service string
+	Version string
+	// Weight of the version when it was last selected
+	Weight int
+	// Selected is the number of times the version was selected
+	Selected uint64
+}
+
+// Split of traffic between the versions of services
+type Split struct {
+	sync.RWMutex
+	weights Weights
+	stats   map[string]map[string]*Stat
+}
+
+// version of a service and its nodes
+type version struct {
+	name   string
+	weight int
+	nodes  []*registry.Node
+}
+
+// New returns a split with the weights
+func New(w Weights) *Split {
+	s := &Split{
+		stats: make(map[string]map[string]*Stat),
+	}
+	s.Set(w)
+	return s
+}
+
+// Set the weights, replacing the current ones
+func (s *Split) Set(w Weights) {
+	weights := make(Weights, len(w))
+	for service, versions := range w {
+		weights[service] = make(map[string]int, len(versions))
+		for v, n := range versions {
+			weights[service][v] = n
+		}
+	}
+
+	s.Lock()
+	s.weights = weights
+	s.Unlock()
+}
+
+// Watch the config value at path setting the weights whenever it changes.
+// The value is an object of the weights of versions keyed by service.
+func (s *Split) Watch(c config.Config, path ...string) (config.Watcher, error) {
+	var w Weights
+	if err := c.Get(path...).Scan(&w); err != nil {
+		return nil, err
+	}
+	s.Set(w)
+
+	cw, err := c.Watch(path...)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			v, err := cw.Next()
+			if err != nil {
+				return
+			}
+			var w Weights
+			if err := v.Scan(&w); err != nil {
+				if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+					logger.Errorf("split: error reading weights: %v", err)
+				}
+				continue
+			}
+			s.Set(w)
+		}
+	}()
+
+	return cw, nil
+}
+
+// Stats of the versions selected, sorted by service and version
+func (s *Split) Stats() []Stat {
+	s.RLock()
+	defer s.RUnlock()
+
+	var stats []Stat
+	for _, versions := range s.stats {
+		for _, st := range versions {
+			stats = append(stats, *st)
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Service == stats[j].Service {
+			return stats[i].Version < stats[j].Version
+		}
+		return stats[i].Service < stats[j].Service
+	})
+	return stats
+}
+
+// record the selection of the version, also in the debug stats
+func (s *Split) record(service string, v *version) {
+	stats.RecordVersion(service, v.name, v.weight)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.stats[service]; !ok {
+		s.stats[service] = make(map[string]*Stat)
+	}
+	st, ok := s.stats[service][v.name]
+	if !ok {
+		st = &Stat{Service: service, Version: v.name}
+		s.stats[service][v.name] = st
+	}
+	st.Weight = v.weight
+	st.Selected++
+}
+
+// versions of the services with their weights, those set for the service
+// or else those in the metadata. Versions without a weight get none of the
+// traffic unless no version has one, then they're split equally.
+func (s *Split) versions(services []*registry.Service) []*version {
+	s.RLock()
+	weights := s.weights[services[0].Name]
+	s.RUnlock()
+
+	byName := make(map[string]*version)
+	var versions []*version
+	var weighted bool
+
+	for _, srv := range services {
+		v, ok := byName[srv.Version]
+		if !ok {
+			v = &version{name: srv.Version, weight: -1}
+			byName[srv.Version] = v
+			versions = append(versions, v)
+		}
+		v.nodes = append(v.nodes, srv.Nodes...)
+
+		// the weights set take precedence over the metadata
+		if len(weights) > 0 {
+			if w, ok := weights[srv.Version]; ok {
+				v.weight = w
+			}
+		} else if w, err := strconv.Atoi(srv.Metadata[WeightKey]); err == nil && v.weight < 0 {
+			v.weight = w
+		}
+		if v.weight >= 0 {
+			weighted = true
+		}
+	}
+
+	for _, v := range versions {
+		switch {
+		case !weighted:
+			v.weight = 1
+		case v.weight < 0:
+			v.weight = 0
+		}
+		// nodes in a stable order for sticky selection
+		sort.Slice(v.nodes, func(i, j int) bool { return v.nodes[i].Id < v.nodes[j].Id })
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i].name < versions[j].name })
+	return versions
+}
+
+// pick the version the number falls in, out of the total weight
+func pick(versions []*version, n int) *version {
+	for _, v := range versions {
+		if n < v.weight {
+			return v
+		}
+		n -= v.weight
+	}
+	return nil
+}
+
+func (s *Split) strategy(services []*registry.Service, key string) selector.Next {
+	if len(services) == 0 {
+		return func() (*registry.Node, error) {
+			return nil, selector.ErrNoneAvailable
+		}
+	}
+
+	service := services[0].Name
+	versions := s.versions(services)
+
+	var total int
+	for _, v := range versions {
+		if len(v.nodes) > 0 {
+			total += v.weight
+		} else {
+			v.weight = 0
+		}
+	}
+
+	var hash uint64
+	if len(key) > 0 {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		hash = h.Sum64()
+	}
+
+	var mtx sync.Mutex
+	var i int
+
+	return func() (*registry.Node, error) {
+		if total <= 0 {
+			return nil, selector.ErrNoneAvailable
+		}
+
+		// sticky keys always land on the same version, retries
+		// move on to the next node of it
+		if len(key) > 0 {
+			v := pick(versions, int(hash%uint64(total)))
+			s.record(service, v)
+
+			mtx.Lock()
+			node := v.nodes[(hash/uint64(total)+uint64(i))%uint64(len(v.nodes))]
+			i++
+			mtx.Unlock()
+
+			return node, nil
+		}
+
+		v := pick(versions, rand.Intn(total))
+		s.record(service, v)
+		return v.nodes[rand.Intn(len(v.nodes))], nil
+	}
+}
+
+// Strategy picks a version of the service by weight and then a random node
+func (s *Split) Strategy() selector.Strategy {
+	return func(services []*registry.Service) selector.Next {
+		return s.strategy(services, "")
+	}
+}
+
+// Sticky picks the same version of the service, and node while it's up,
+// for the key e.g. the id of a user. An empty key picks at random.
+func (s *Split) Sticky(key string) selector.Strategy {
+	return func(services []*registry.Service) selector.Next {
+		return s.strategy(services, key)
+	}
+}
@@ -0,0 +1,170 @@
+package split
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/config"
+	"github.com/asim/go-micro/v3/config/source"
+	"github.com/asim/go-micro/v3/config/source/memory"
+	"github.com/asim/go-micro/v3/debug/stats"
+	"github.com/asim/go-micro/v3/registry"
+)
+
+var testServices = []*registry.Service{
+	{
+		Name:    "greeter",
+		Version: "1.2.0",
+		Nodes: []*registry.Node{
+			{Id: "greeter-1.2.0-1"},
+			{Id: "greeter-1.2.0-2"},
+		},
+	},
+	{
+		Name:     "greeter",
+		Version:  "1.3.0",
+		Metadata: map[string]string{"weight": "1"},
+		Nodes: []*registry.Node{
+			{Id: "greeter-1.3.0-1"},
+		},
+	},
+}
+
+// versions selected out of n calls of the strategy
+func versions(t *testing.T, s *Split, n int) map[string]int {
+	next := s.Strategy()(testServices)
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[node.Id[len("greeter-"):len("greeter-1.2.0")]]++
+	}
+	return counts
+}
+
+func TestSplit(t *testing.T) {
+	s := New(Weights{"greeter": {"1.2.0": 90, "1.3.0": 10}})
+
+	counts := versions(t, s, 10000)
+	if c := counts["1.3.0"]; c < 700 || c > 1300 {
+		t.Fatalf("expected about 10%% of calls to 1.3.0, got %v", counts)
+	}
+
+	stats := s.Stats()
+	if len(stats) != 2 || stats[1].Version != "1.3.0" || stats[1].Weight != 10 || int(stats[1].Selected) != counts["1.3.0"] {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// all of the traffic moved over
+	s.Set(Weights{"greeter": {"1.3.0": 100}})
+	if counts := versions(t, s, 100); counts["1.3.0"] != 100 {
+		t.Fatalf("expected every call to 1.3.0, got %v", counts)
+	}
+
+	// the weights of the metadata are used without any set, so the
+	// version without one gets none of the traffic
+	s.Set(nil)
+	if counts := versions(t, s, 100); counts["1.3.0"] != 100 {
+		t.Fatalf("expected every call to the weighted version, got %v", counts)
+	}
+
+	s.Set(Weights{"greeter": {"1.2.0": 0, "1.3.0": 0}})
+	if _, err := s.Strategy()(testServices)(); err == nil {
+		t.Fatal("expected none available without any weight")
+	}
+}
+
+func TestSticky(t *testing.T) {
+	s := New(Weights{"greeter": {"1.2.0": 50, "1.3.0": 50}})
+
+	seen := make(map[string]bool)
+	for _, user := range []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi"} {
+		node, err := s.Sticky(user)(testServices)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			n, err := s.Sticky(user)(testServices)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.Id != node.Id {
+				t.Fatalf("expected %s to stick to %s, got %s", user, node.Id, n.Id)
+			}
+		}
+		seen[node.Id[:len("greeter-1.2.0")]] = true
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("expected the users to be split between the versions, got %v", seen)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"split": {"greeter": {"1.2.0": 100}}}`)))
+	c, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load(src); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(nil)
+	w, err := s.Watch(c, "split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if counts := versions(t, s, 100); counts["1.2.0"] != 100 {
+		t.Fatalf("expected every call to 1.2.0, got %v", counts)
+	}
+
+	// shift the traffic over, writing until the source is watched
+	for i := 0; ; i++ {
+		if err := src.Write(&source.ChangeSet{
+			Data:   []byte(`{"split": {"greeter": {"1.3.0": 100}}}`),
+			Format: "json",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if counts := versions(t, s, 10); counts["1.3.0"] == 10 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("expected the weights to be updated, got %s", c.Get("split").Bytes())
+		}
+	}
+}
+
+func TestDebugStats(t *testing.T) {
+	s := New(Weights{"stats": {"1.0.0": 1}})
+
+	next := s.Strategy()([]*registry.Service{
+		{Name: "stats", Version: "1.0.0", Nodes: []*registry.Node{{Id: "stats-1"}}},
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st, err := stats.DefaultStats.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range st[len(st)-1].Versions {
+		if v.Service == "stats" {
+			if v.Version != "1.0.0" || v.Weight != 1 || v.Selected != 3 {
+				t.Fatalf("unexpected version stat %+v", v)
+			}
+			return
+		}
+	}
+	t.Fatal("expected the split in the debug stats")
+}
@@ -0,0 +1,280 @@
+package selector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+// Version is a semantic version, see https://semver.org
+type Version struct {
+	Major, Minor, Patch uint64
+	// Prerelease identifiers e.g. rc.1
+	Prerelease []string
+}
+
+// ParseVersion parses a semantic version. A leading v and missing minor
+// or patch numbers are allowed e.g. v1.4 is 1.4.0. Build metadata is ignored.
+func ParseVersion(s string) (*Version, error) {
+	v := new(Version)
+
+	str := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(str, '+'); i >= 0 {
+		str = str[:i]
+	}
+	if i := strings.IndexByte(str, '-'); i >= 0 {
+		for _, id := range strings.Split(str[i+1:], ".") {
+			if len(id) == 0 {
+				return nil, fmt.Errorf("invalid version %q: empty prerelease identifier", s)
+			}
+			v.Prerelease = append(v.Prerelease, id)
+		}
+		str = str[:i]
+	}
+
+	parts := strings.Split(str, ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid version %q", s)
+	}
+
+	nums := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		*nums[i] = n
+	}
+
+	return v, nil
+}
+
+// Compare the versions by precedence, returning -1, 0 or 1
+func (v *Version) Compare(o *Version) int {
+	for _, c := range [][2]uint64{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if c[0] < c[1] {
+			return -1
+		}
+		if c[0] > c[1] {
+			return 1
+		}
+	}
+
+	// a prerelease has a lower precedence than the release
+	switch {
+	case len(v.Prerelease) == 0 && len(o.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		if c := compareIdentifier(v.Prerelease[i], o.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(v.Prerelease) < len(o.Prerelease):
+		return -1
+	case len(v.Prerelease) > len(o.Prerelease):
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier of prereleases, numbers are lower than strings
+func compareIdentifier(a, b string) int {
+	an, aerr := strconv.ParseUint(a, 10, 64)
+	bn, berr := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case aerr == nil && berr == nil:
+		if an < bn {
+			return -1
+		}
+		if an > bn {
+			return 1
+		}
+		return 0
+	case aerr == nil:
+		return -1
+	case berr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func (v *Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	return s
+}
+
+// comparison of a version against another one
+type comparison struct {
+	op      string
+	version *Version
+}
+
+func (c comparison) check(v *Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "=", "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// Constraint on versions, a list of comparisons which must all be met
+// e.g. ">=1.4 <2.0" or ">=1.4, <2.0" optionally or'd together with || e.g. "1.2.3 || >=2".
+// ~1.4 allows patch releases, >=1.4.0 <1.5.0, and ^1.4 minor releases,
+// >=1.4.0 <2.0.0.
+//
+// Prereleases only meet a constraint which has a comparison with a
+// prerelease of the same major, minor and patch version, so >=1.4.0-rc.1
+// allows 1.4.0-rc.2 but not 1.5.0-rc.1.
+type Constraint struct {
+	str    string
+	groups [][]comparison
+}
+
+// ParseConstraint parses a version constraint
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{str: s}
+
+	for _, group := range strings.Split(s, "||") {
+		var comparisons []comparison
+
+		fields := strings.Fields(strings.Replace(group, ",", " ", -1))
+		for i := 0; i < len(fields); i++ {
+			f := fields[i]
+
+			// an operator separated from its version
+			if strings.Trim(f, "=!<>~^") == "" && i+1 < len(fields) {
+				f += fields[i+1]
+				i++
+			}
+
+			cs, err := parseComparison(f)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %v", s, err)
+			}
+			comparisons = append(comparisons, cs...)
+		}
+
+		if len(comparisons) == 0 {
+			return nil, fmt.Errorf("invalid version constraint %q", s)
+		}
+		c.groups = append(c.groups, comparisons)
+	}
+
+	return c, nil
+}
+
+func parseComparison(s string) ([]comparison, error) {
+	op := s[:len(s)-len(strings.TrimLeft(s, "=!<>~^"))]
+	v, err := ParseVersion(s[len(op):])
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "", "=", "==", "!=", ">", ">=", "<", "<=":
+		if op == "" {
+			op = "="
+		}
+		return []comparison{{op, v}}, nil
+	case "~":
+		upper := &Version{Major: v.Major, Minor: v.Minor + 1}
+		return []comparison{{">=", v}, {"<", upper}}, nil
+	case "^":
+		upper := &Version{Major: v.Major + 1}
+		if v.Major == 0 {
+			upper = &Version{Minor: v.Minor + 1}
+		}
+		return []comparison{{">=", v}, {"<", upper}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+// Check whether the version meets the constraint
+func (c *Constraint) Check(v *Version) bool {
+	for _, group := range c.groups {
+		if checkGroup(group, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkGroup(group []comparison, v *Version) bool {
+	for _, c := range group {
+		if !c.check(v) {
+			return false
+		}
+	}
+
+	if len(v.Prerelease) == 0 {
+		return true
+	}
+
+	// prereleases need to be asked for
+	for _, c := range group {
+		cv := c.version
+		if len(cv.Prerelease) > 0 && cv.Major == v.Major && cv.Minor == v.Minor && cv.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Constraint) String() string {
+	return c.str
+}
+
+// FilterVersionConstraint is a version based Select Filter which will
+// only return services with a semantic version meeting the constraint
+// e.g. ">=1.4 <2.0". Services which aren't semantically versioned are
+// skipped, as are all of them if the constraint is invalid so it should
+// be checked with ParseConstraint first.
+func FilterVersionConstraint(constraint string) Filter {
+	c, err := ParseConstraint(constraint)
+
+	return func(old []*registry.Service) []*registry.Service {
+		if err != nil {
+			return nil
+		}
+
+		var services []*registry.Service
+
+		for _, service := range old {
+			v, err := ParseVersion(service.Version)
+			if err != nil {
+				continue
+			}
+			if c.Check(v) {
+				services = append(services, service)
+			}
+		}
+
+		return services
+	}
+}
@@ -0,0 +1,112 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/asim/go-micro/v3/registry"
+)
+
+func TestVersionCompare(t *testing.T) {
+	// in order of precedence
+	versions := []string{
+		"0.9",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"v1.0.0+build.5",
+		"1.4",
+		"1.10.2",
+	}
+
+	for i := 0; i < len(versions)-1; i++ {
+		a, err := ParseVersion(versions[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseVersion(versions[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Compare(b) != -1 || b.Compare(a) != 1 || a.Compare(a) != 0 {
+			t.Fatalf("Expected %s < %s", a, b)
+		}
+	}
+
+	for _, v := range []string{"", "latest", "1.2.3.4", "1.x", "1.0.0-"} {
+		if _, err := ParseVersion(v); err == nil {
+			t.Fatalf("Expected %q to be invalid", v)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	testData := []struct {
+		constraint string
+		version    string
+		ok         bool
+	}{
+		{">=1.4 <2.0", "1.4.0", true},
+		{">=1.4 <2.0", "1.9.12", true},
+		{">=1.4 <2.0", "2.0.0", false},
+		{">=1.4 <2.0", "1.3.9", false},
+		{">= 1.4, < 2.0", "1.5.0", true},
+		{">=1.4 <2.0", "2.0.0-rc.1", false},
+		{">=1.4 <2.0", "1.5.0-rc.1", false},
+		{">=1.5.0-rc.1 <2.0", "1.5.0-rc.2", true},
+		{">=1.5.0-rc.1 <2.0", "1.6.0-rc.1", false},
+		{">=1.5.0-rc.1 <2.0", "1.6.0", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "1.2.4", false},
+		{"!=1.2.3", "1.2.4", true},
+		{"~1.4", "1.4.7", true},
+		{"~1.4", "1.5.0", false},
+		{"^1.4", "1.9.0", true},
+		{"^1.4", "2.0.0", false},
+		{"^0.4", "0.5.0", false},
+		{"1.2.3 || >=2", "1.2.3", true},
+		{"1.2.3 || >=2", "2.1.0", true},
+		{"1.2.3 || >=2", "1.9.0", false},
+	}
+
+	for _, d := range testData {
+		c, err := ParseConstraint(d.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := ParseVersion(d.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Check(v) != d.ok {
+			t.Fatalf("Expected %s meeting %s to be %v", d.version, d.constraint, d.ok)
+		}
+	}
+
+	for _, c := range []string{"", ">=", ">=1.4 ||", "=>1.4", "latest"} {
+		if _, err := ParseConstraint(c); err == nil {
+			t.Fatalf("Expected %q to be invalid", c)
+		}
+	}
+}
+
+func TestFilterVersionConstraint(t *testing.T) {
+	services := []*registry.Service{
+		{Name: "test", Version: "1.3.0"},
+		{Name: "test", Version: "1.4.2"},
+		{Name: "test", Version: "2.0.0-rc.1"},
+		{Name: "test", Version: "latest"},
+	}
+
+	filtered := FilterVersionConstraint(">=1.4 <2.0")(services)
+	if len(filtered) != 1 || filtered[0].Version != "1.4.2" {
+		t.Fatalf("Expected 1.4.2 got %+v", filtered)
+	}
+
+	if filtered := FilterVersionConstraint("not a constraint")(services); len(filtered) != 0 {
+		t.Fatalf("Expected no services for an invalid constraint got %+v", filtered)
+	}
+}
@@ -3,6 +3,8 @@ package server
 import (
 	"context"
 	"sync"
+
+	"github.com/asim/go-micro/v3/metadata"
 )
 
 type serverKey struct{}
@@ -26,3 +28,44 @@ func FromContext(ctx context.Context) (Server, bool) {
 func NewContext(ctx context.Context, s Server) context.Context {
 	return context.WithValue(ctx, serverKey{}, s)
 }
+
+type responseMetadataKey struct{}
+
+// responseMetadata is the metadata sent back with the response to a request
+type responseMetadata struct {
+	sync.Mutex
+	md map[string]string
+}
+
+// SetResponseMetadata sets metadata which is sent back to the caller with the
+// response to the request, e.g. a Cache-Control header for the api gateway.
+// It returns false if the context isn't that of a request.
+func SetResponseMetadata(ctx context.Context, k, v string) bool {
+	rmd, ok := ctx.Value(responseMetadataKey{}).(*responseMetadata)
+	if !ok {
+		return false
+	}
+	rmd.Lock()
+	rmd.md[k] = v
+	rmd.Unlock()
+	return true
+}
+
+func newResponseMetadataContext(ctx context.Context) (context.Context, *responseMetadata) {
+	rmd := &responseMetadata{md: make(map[string]string)}
+	return context.WithValue(ctx, responseMetadataKey{}, rmd), rmd
+}
+
+// header returns the metadata for the response prefixed with metadata.ResponsePrefix
+func (r *responseMetadata) header() map[string]string {
+	r.Lock()
+	defer r.Unlock()
+	if len(r.md) == 0 {
+		return nil
+	}
+	hdr := make(map[string]string, len(r.md))
+	for k, v := range r.md {
+		hdr[metadata.ResponsePrefix+k] = v
+	}
+	return hdr
+}
@@ -6,8 +6,46 @@ import (
 	"strings"
 
 	"github.com/asim/go-micro/v3/registry"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
+// oneofMember is a field of a proto oneof, by the name it's encoded with
+type oneofMember struct {
+	Name  string
+	Field reflect.StructField
+}
+
+// oneofMembers returns the fields of the oneof the field of the proto
+// message holds, found by setting each one in turn
+func oneofMembers(v reflect.Type, f reflect.StructField) []oneofMember {
+	msg, ok := reflect.New(v).Interface().(protoreflect.ProtoMessage)
+	if !ok {
+		return nil
+	}
+	m := msg.ProtoReflect()
+	od := m.Descriptor().Oneofs().ByName(protoreflect.Name(f.Tag.Get("protobuf_oneof")))
+	if od == nil {
+		return nil
+	}
+
+	var members []oneofMember
+	fields := od.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		m.Set(fd, m.NewField(fd))
+		// the oneof holds a pointer to a wrapper of the field
+		w := reflect.ValueOf(msg).Elem().FieldByIndex(f.Index).Elem()
+		if w.Kind() != reflect.Ptr || w.Elem().Kind() != reflect.Struct || w.Elem().NumField() != 1 {
+			continue
+		}
+		members = append(members, oneofMember{
+			Name:  string(fd.Name()),
+			Field: w.Elem().Type().Field(0),
+		})
+	}
+	return members
+}
+
 func extractValue(v reflect.Type, d int) *registry.Value {
 	if d == 3 {
 		return nil
@@ -29,26 +67,29 @@ func extractValue(v reflect.Type, d int) *registry.Value {
 	case reflect.Struct:
 		for i := 0; i < v.NumField(); i++ {
 			f := v.Field(i)
-			val := extractValue(f.Type, d+1)
-			if val == nil {
+			// skip unexported fields such as the state of protos
+			if len(f.PkgPath) > 0 {
 				continue
 			}
-
-			// if we can find a json tag use it
-			if tags := f.Tag.Get("json"); len(tags) > 0 {
-				parts := strings.Split(tags, ",")
-				if parts[0] == "-" || parts[0] == "omitempty" {
-					continue
+			// a proto oneof is encoded as the field it holds
+			if len(f.Tag.Get("protobuf_oneof")) > 0 {
+				for _, m := range oneofMembers(v, f) {
+					if val := extractValue(m.Field.Type, d+1); val != nil {
+						val.Name = m.Name
+						arg.Values = append(arg.Values, val)
+					}
 				}
-				val.Name = parts[0]
+				continue
 			}
-
-			// if there's no name default it
-			if len(val.Name) == 0 {
-				val.Name = v.Field(i).Name
+			val := extractValue(f.Type, d+1)
+			if val == nil {
+				continue
 			}
 
-			// still no name then continue
+			// use the name the field is encoded with
+			val.Name = fieldName(f)
+
+			// no name then continue
 			if len(val.Name) == 0 {
 				continue
 			}
@@ -66,6 +107,61 @@ func extractValue(v reflect.Type, d int) *registry.Value {
 	return arg
 }
 
+// fieldName returns the json name of the field, empty if it's skipped
+func fieldName(f reflect.StructField) string {
+	// if we can find a json tag use it
+	if tags := f.Tag.Get("json"); len(tags) > 0 {
+		parts := strings.Split(tags, ",")
+		if parts[0] == "-" || parts[0] == "omitempty" {
+			return ""
+		}
+		if len(parts[0]) > 0 {
+			return parts[0]
+		}
+	}
+	return f.Name
+}
+
+// isRequired checks for a validate:"required" tag or a proto2 required field
+func isRequired(f reflect.StructField) bool {
+	for _, k := range []string{"validate", "protobuf"} {
+		for _, p := range strings.Split(f.Tag.Get(k), ",") {
+			if p == "required" || (k == "protobuf" && p == "req") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractRequired returns the dotted paths of the required fields
+// found within the same depth as extractValue
+func extractRequired(v reflect.Type, prefix string, d int) []string {
+	if v == nil || d >= 2 {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var req []string
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		name := fieldName(f)
+		if len(name) == 0 {
+			continue
+		}
+		if isRequired(f) {
+			req = append(req, prefix+name)
+		}
+		req = append(req, extractRequired(f.Type, prefix+name+".", d+1)...)
+	}
+	return req
+}
+
 func extractEndpoint(method reflect.Method) *registry.Endpoint {
 	if method.PkgPath != "" {
 		return nil
@@ -104,25 +200,34 @@ func extractEndpoint(method reflect.Method) *registry.Endpoint {
 
 	// set endpoint metadata for stream
 	if stream {
-		ep.Metadata = map[string]string{
-			"stream": fmt.Sprintf("%v", stream),
-		}
+		ep.Metadata["stream"] = fmt.Sprintf("%v", stream)
 	}
 
+	// set the required fields of the request
+	if req := extractRequired(reqType, "", 0); len(req) > 0 {
+		ep.Metadata["required"] = strings.Join(req, ",")
+	}
+
+	// set the full schema of the request and response
+	describe(ep.Metadata, "request", reqType)
+	describe(ep.Metadata, "response", rspType)
+
 	return ep
 }
 
-func extractSubValue(typ reflect.Type) *registry.Value {
-	var reqType reflect.Type
+func extractSubType(typ reflect.Type) reflect.Type {
 	switch typ.NumIn() {
 	case 1:
-		reqType = typ.In(0)
+		return typ.In(0)
 	case 2:
-		reqType = typ.In(1)
+		return typ.In(1)
 	case 3:
-		reqType = typ.In(2)
+		return typ.In(2)
 	default:
 		return nil
 	}
-	return extractValue(reqType, 0)
+}
+
+func extractSubValue(typ reflect.Type) *registry.Value {
+	return extractValue(extractSubType(typ), 0)
 }
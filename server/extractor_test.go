@@ -2,10 +2,15 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"reflect"
 	"testing"
 
 	"github.com/asim/go-micro/v3/registry"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type testHandler struct{}
@@ -63,3 +68,158 @@ func TestExtractEndpoint(t *testing.T) {
 	}
 
 }
+
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type testRequired struct {
+	Name    string       `json:"name" validate:"required"`
+	Id      int64        `protobuf:"varint,2,req,name=id" json:"id,omitempty"`
+	Age     int32        `json:"age"`
+	Address *testAddress `json:"address"`
+	Skip    string       `json:"-" validate:"required"`
+}
+
+func TestExtractRequired(t *testing.T) {
+	req := extractRequired(reflect.TypeOf(&testRequired{}), "", 0)
+	expect := []string{"name", "id", "address.city"}
+	if !reflect.DeepEqual(req, expect) {
+		t.Fatalf("Expected %v got %v", expect, req)
+	}
+
+	val := extractValue(reflect.TypeOf(&testRequired{}), 0)
+	var names []string
+	for _, v := range val.Values {
+		names = append(names, v.Name)
+	}
+	if expect := []string{"name", "id", "age", "address"}; !reflect.DeepEqual(names, expect) {
+		t.Fatalf("Expected values %v got %v", expect, names)
+	}
+}
+
+func TestExtractOneof(t *testing.T) {
+	val := extractValue(reflect.TypeOf(&structpb.Value{}), 0)
+	types := make(map[string]string)
+	for _, v := range val.Values {
+		types[v.Name] = v.Type
+	}
+	expect := map[string]string{
+		"null_value":   "NullValue",
+		"number_value": "float64",
+		"string_value": "string",
+		"bool_value":   "bool",
+		"struct_value": "Struct",
+		"list_value":   "ListValue",
+	}
+	if !reflect.DeepEqual(types, expect) {
+		t.Fatalf("Expected the members of the oneof %v got %v", expect, types)
+	}
+}
+
+func TestExtractOneofSchema(t *testing.T) {
+	md := make(map[string]string)
+	describe(md, "request", reflect.TypeOf(&structpb.Value{}))
+
+	var s struct {
+		Properties map[string]interface{} `json:"properties"`
+		Not        struct {
+			AnyOf []struct {
+				Required []string `json:"required"`
+			} `json:"anyOf"`
+		} `json:"not"`
+	}
+	if err := json.Unmarshal([]byte(md["request_schema"]), &s); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"null_value", "number_value", "string_value", "bool_value", "struct_value", "list_value"} {
+		if _, ok := s.Properties[name]; !ok {
+			t.Fatalf("Expected the member %s got %v", name, s.Properties)
+		}
+	}
+	if _, ok := s.Properties["Kind"]; ok {
+		t.Fatal("Expected the oneof to be left out")
+	}
+	// any two of the six members can't be set
+	if n := len(s.Not.AnyOf); n != 15 || len(s.Not.AnyOf[0].Required) != 2 {
+		t.Fatalf("Expected pairs of members which can't be set together got %v", s.Not.AnyOf)
+	}
+}
+
+func TestExtractSchema(t *testing.T) {
+	md := make(map[string]string)
+	describe(md, "request", reflect.TypeOf(&testRequired{}))
+
+	var s map[string]interface{}
+	if err := json.Unmarshal([]byte(md["request_schema"]), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s["type"] != "object" || s["additionalProperties"] != false {
+		t.Fatalf("Expected a closed object got %v", s)
+	}
+	props := s["properties"].(map[string]interface{})
+	if v := props["id"].(map[string]interface{}); v["type"] != "integer" || v["format"] != "int64" {
+		t.Fatalf("Expected id to be an int64 got %v", v)
+	}
+	city := props["address"].(map[string]interface{})["properties"].(map[string]interface{})["city"]
+	if city.(map[string]interface{})["type"] != "string" {
+		t.Fatalf("Expected nested city got %v", city)
+	}
+	if req := s["required"].([]interface{}); len(req) != 2 || req[0] != "name" || req[1] != "id" {
+		t.Fatalf("Expected name and id to be required got %v", req)
+	}
+	if _, ok := props["Skip"]; ok {
+		t.Fatal("Expected skipped fields to be left out")
+	}
+
+	// proto messages carry their type
+	md = make(map[string]string)
+	describe(md, "response", reflect.TypeOf(&descriptorpb.FileDescriptorSet{}))
+	if md["response_type"] != "google.protobuf.FileDescriptorSet" {
+		t.Fatalf("Expected the full name of the message got %q", md["response_type"])
+	}
+	if _, ok := md["response_descriptor"]; ok {
+		t.Fatal("Expected descriptors to be added when registering")
+	}
+
+	eps := AddDescriptors([]*registry.Endpoint{{Name: "Test.Call", Metadata: md}})
+	b, err := base64.StdEncoding.DecodeString(eps[0].Metadata["response_descriptor"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(b, set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.File) != 1 || set.File[0].GetName() != "google/protobuf/descriptor.proto" {
+		t.Fatalf("Expected the descriptor file got %v", set.File)
+	}
+}
+
+func TestPublishDescriptors(t *testing.T) {
+	if publishDescriptors(newOptions()) {
+		t.Fatal("Expected descriptors to be left out by default")
+	}
+	if !publishDescriptors(newOptions(Descriptors(true))) {
+		t.Fatal("Expected descriptors to be published when enabled")
+	}
+
+	ep := &registry.Endpoint{
+		Name: "Test.Call",
+		Metadata: map[string]string{
+			"request_schema": "{}",
+			"request_type":   "test.Unknown",
+			"response_type":  "google.protobuf.FileDescriptorSet",
+		},
+	}
+	eps := AddDescriptors([]*registry.Endpoint{ep})
+	if _, ok := eps[0].Metadata["request_descriptor"]; ok {
+		t.Fatal("Expected no descriptor of an unknown type")
+	}
+	if len(eps[0].Metadata["response_descriptor"]) == 0 {
+		t.Fatalf("Expected the response descriptor got %v", eps[0].Metadata)
+	}
+	if len(ep.Metadata) != 3 {
+		t.Fatalf("Expected the endpoint to be left as it was got %v", ep.Metadata)
+	}
+}
@@ -243,6 +243,20 @@ func Wait(wg *sync.WaitGroup) Option {
 	}
 }
 
+type descriptorsKey struct{}
+
+// Descriptors publishes the <key>_descriptor of proto requests and responses,
+// the FileDescriptorSet of their file and its imports, in the metadata of the
+// endpoints. They're left out by default as they add kilobytes per endpoint.
+func Descriptors(b bool) Option {
+	return func(o *Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, descriptorsKey{}, b)
+	}
+}
+
 // Adds a handler Wrapper to a list of options passed into the server
 func WrapHandler(w HandlerWrapper) Option {
 	return func(o *Options) {
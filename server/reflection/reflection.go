@@ -0,0 +1,151 @@
+// Package reflection implements a handler describing what a service serves.
+// It lists the handlers and subscribers of the server along with the schema
+// of every endpoint so a generic client can call a service without its protos.
+package reflection
+
+import (
+	"context"
+	"sort"
+
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/server"
+)
+
+// Reflection handler. The request and response types are plain structs so
+// it must be called with a json content type e.g. application/json.
+type Reflection struct {
+	server server.Server
+}
+
+// lister is implemented by servers which can list their handlers and subscribers
+type lister interface {
+	Handlers() []server.Handler
+	Subscribers() []server.Subscriber
+}
+
+type InfoRequest struct{}
+
+type InfoResponse struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Id      string `json:"id"`
+	// ContentTypes the server has codecs for
+	ContentTypes []string      `json:"content_types"`
+	Handlers     []*Handler    `json:"handlers"`
+	Subscribers  []*Subscriber `json:"subscribers"`
+}
+
+// Handler and its endpoints. The metadata of an endpoint holds its
+// request_schema and response_schema along with the request_type,
+// response_type and base64 encoded FileDescriptorSets of protos.
+type Handler struct {
+	Name      string               `json:"name"`
+	Internal  bool                 `json:"internal"`
+	Endpoints []*registry.Endpoint `json:"endpoints"`
+}
+
+// Subscriber to a topic and its endpoints
+type Subscriber struct {
+	Topic     string               `json:"topic"`
+	Queue     string               `json:"queue"`
+	Internal  bool                 `json:"internal"`
+	Endpoints []*registry.Endpoint `json:"endpoints"`
+}
+
+type EndpointRequest struct {
+	// Name of the endpoint e.g. Greeter.Hello
+	Name string `json:"name"`
+}
+
+type EndpointResponse struct {
+	Endpoint *registry.Endpoint `json:"endpoint"`
+}
+
+// Info lists the handlers, subscribers and content types of the server
+func (r *Reflection) Info(ctx context.Context, req *InfoRequest, rsp *InfoResponse) error {
+	opts := r.server.Options()
+
+	rsp.Name = opts.Name
+	rsp.Version = opts.Version
+	rsp.Id = opts.Id
+	rsp.ContentTypes = contentTypes(opts)
+
+	l, ok := r.server.(lister)
+	if !ok {
+		return nil
+	}
+
+	for _, h := range l.Handlers() {
+		rsp.Handlers = append(rsp.Handlers, &Handler{
+			Name:      h.Name(),
+			Internal:  h.Options().Internal,
+			Endpoints: server.AddDescriptors(h.Endpoints()),
+		})
+	}
+
+	for _, sb := range l.Subscribers() {
+		rsp.Subscribers = append(rsp.Subscribers, &Subscriber{
+			Topic:     sb.Topic(),
+			Queue:     sb.Options().Queue,
+			Internal:  sb.Options().Internal,
+			Endpoints: server.AddDescriptors(sb.Endpoints()),
+		})
+	}
+
+	return nil
+}
+
+// Endpoint describes a single endpoint of a handler or subscriber
+func (r *Reflection) Endpoint(ctx context.Context, req *EndpointRequest, rsp *EndpointResponse) error {
+	id := r.server.Options().Name
+
+	if len(req.Name) == 0 {
+		return errors.BadRequest(id, "endpoint name required")
+	}
+
+	l, ok := r.server.(lister)
+	if !ok {
+		return errors.NotFound(id, "endpoint %s not found", req.Name)
+	}
+
+	var endpoints []*registry.Endpoint
+	for _, h := range l.Handlers() {
+		endpoints = append(endpoints, h.Endpoints()...)
+	}
+	for _, sb := range l.Subscribers() {
+		endpoints = append(endpoints, sb.Endpoints()...)
+	}
+
+	for _, ep := range endpoints {
+		if ep.Name == req.Name {
+			rsp.Endpoint = server.AddDescriptors([]*registry.Endpoint{ep})[0]
+			return nil
+		}
+	}
+
+	return errors.NotFound(id, "endpoint %s not found", req.Name)
+}
+
+// contentTypes of the server's codecs, including the defaults it falls back to
+func contentTypes(opts server.Options) []string {
+	seen := make(map[string]bool)
+	for ct := range opts.Codecs {
+		seen[ct] = true
+	}
+	for ct := range server.DefaultCodecs {
+		seen[ct] = true
+	}
+
+	types := make([]string, 0, len(seen))
+	for ct := range seen {
+		types = append(types, ct)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewHandler returns the reflection handler of the server
+func NewHandler(s server.Server) *Reflection {
+	return &Reflection{server: s}
+}
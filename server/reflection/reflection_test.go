@@ -0,0 +1,96 @@
+package reflection
+
+import (
+	"context"
+	"testing"
+
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/server"
+)
+
+type TestRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type TestResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+type Greeter struct{}
+
+func (g *Greeter) Hello(ctx context.Context, req *TestRequest, rsp *TestResponse) error {
+	rsp.Greeting = "hello " + req.Name
+	return nil
+}
+
+func TestReflection(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	s := server.NewServer(
+		server.Name("test.reflection"),
+		server.Registry(r),
+		server.Address("127.0.0.1:0"),
+	)
+	if err := s.Handle(s.NewHandler(new(Greeter))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Subscribe(s.NewSubscriber("test.topic", func(ctx context.Context, req *TestRequest) error {
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Handle(s.NewHandler(NewHandler(s), server.InternalHandler(true))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	c := client.NewClient(client.Registry(r))
+
+	var info InfoResponse
+	req := c.NewRequest("test.reflection", "Reflection.Info", &InfoRequest{}, client.WithContentType("application/json"))
+	if err := c.Call(context.TODO(), req, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Name != "test.reflection" {
+		t.Fatalf("Expected test.reflection got %s", info.Name)
+	}
+	var json bool
+	for _, ct := range info.ContentTypes {
+		if ct == "application/json" {
+			json = true
+		}
+	}
+	if !json {
+		t.Fatalf("Expected application/json in %v", info.ContentTypes)
+	}
+	if len(info.Handlers) != 2 || info.Handlers[0].Name != "Greeter" || info.Handlers[1].Name != "Reflection" {
+		t.Fatalf("Expected the Greeter and Reflection handlers got %+v", info.Handlers)
+	}
+	if !info.Handlers[1].Internal {
+		t.Fatal("Expected the reflection handler to be internal")
+	}
+	if len(info.Subscribers) != 1 || info.Subscribers[0].Topic != "test.topic" {
+		t.Fatalf("Expected the test.topic subscriber got %+v", info.Subscribers)
+	}
+
+	var ep EndpointResponse
+	req = c.NewRequest("test.reflection", "Reflection.Endpoint", &EndpointRequest{Name: "Greeter.Hello"}, client.WithContentType("application/json"))
+	if err := c.Call(context.TODO(), req, &ep); err != nil {
+		t.Fatal(err)
+	}
+	if ep.Endpoint == nil || ep.Endpoint.Request.Name != "TestRequest" {
+		t.Fatalf("Expected the Greeter.Hello endpoint got %+v", ep.Endpoint)
+	}
+	if len(ep.Endpoint.Metadata["request_schema"]) == 0 || ep.Endpoint.Metadata["required"] != "name" {
+		t.Fatalf("Expected the request schema got %v", ep.Endpoint.Metadata)
+	}
+
+	req = c.NewRequest("test.reflection", "Reflection.Endpoint", &EndpointRequest{Name: "Greeter.Bye"}, client.WithContentType("application/json"))
+	if err := c.Call(context.TODO(), req, &ep); err == nil {
+		t.Fatal("Expected an error for an unknown endpoint")
+	}
+}
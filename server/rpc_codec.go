@@ -321,8 +321,8 @@ func (c *rpcCodec) Write(r *codec.Message, b interface{}) error {
 			return err
 		}
 	} else {
-		// set the body
-		body = c.buf.wbuf.Bytes()
+		// copy the body as the buffer is reused before a stream message is sent
+		body = append([]byte(nil), c.buf.wbuf.Bytes()...)
 	}
 
 	// Set content type if theres content
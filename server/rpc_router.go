@@ -186,9 +186,10 @@ func prepareMethod(method reflect.Method) *methodType {
 	return &methodType{method: method, ArgType: argType, ReplyType: replyType, ContextType: contextType, stream: stream}
 }
 
-func (router *router) sendResponse(sending sync.Locker, req *request, reply interface{}, cc codec.Writer, last bool) error {
+func (router *router) sendResponse(sending sync.Locker, req *request, reply interface{}, cc codec.Writer, hdr map[string]string) error {
 	msg := new(codec.Message)
 	msg.Type = codec.Response
+	msg.Header = hdr
 	resp := router.getResponse()
 	resp.msg = msg
 
@@ -221,6 +222,9 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 	}
 
 	if !mtype.stream {
+		// the handler may set metadata to send back with the response
+		ctx, rmd := newResponseMetadataContext(ctx)
+
 		fn := func(ctx context.Context, req Request, rsp interface{}) error {
 			returnValues = function.Call([]reflect.Value{s.rcvr, mtype.prepareContext(ctx), reflect.ValueOf(argv.Interface()), reflect.ValueOf(rsp)})
 
@@ -243,7 +247,7 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 		}
 
 		// send response
-		return router.sendResponse(sending, req, replyv.Interface(), cc, true)
+		return router.sendResponse(sending, req, replyv.Interface(), cc, rmd.header())
 	}
 
 	// declare a local error to see if we errored out already
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/asim/go-micro/v3/auth"
 	"github.com/asim/go-micro/v3/broker"
 	"github.com/asim/go-micro/v3/codec"
 	raw "github.com/asim/go-micro/v3/codec/bytes"
@@ -136,6 +137,9 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 	// streams are multiplexed on Micro-Stream or Micro-Id header
 	pool := socket.NewPool()
 
+	// the identity of a mutual tls peer is fixed for the connection
+	acc := peerAccount(sock)
+
 	// get global waitgroup
 	s.Lock()
 	gg := s.wg
@@ -279,6 +283,11 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		// create new context with the metadata
 		ctx := metadata.NewContext(context.Background(), hdr)
 
+		// set the account of an authenticated peer
+		if acc != nil {
+			ctx = auth.ContextWithAccount(ctx, acc)
+		}
+
 		// set the timeout from the header if we have it
 		if len(to) > 0 {
 			if n, err := strconv.ParseUint(to, 10, 64); err == nil {
@@ -512,6 +521,36 @@ func (s *rpcServer) Subscribe(sb Subscriber) error {
 	return nil
 }
 
+// Handlers returns the registered handlers sorted by name
+func (s *rpcServer) Handlers() []Handler {
+	s.RLock()
+	defer s.RUnlock()
+
+	handlers := make([]Handler, 0, len(s.handlers))
+	for _, h := range s.handlers {
+		handlers = append(handlers, h)
+	}
+	sort.Slice(handlers, func(i, j int) bool {
+		return handlers[i].Name() < handlers[j].Name()
+	})
+	return handlers
+}
+
+// Subscribers returns the registered subscribers sorted by topic
+func (s *rpcServer) Subscribers() []Subscriber {
+	s.RLock()
+	defer s.RUnlock()
+
+	subscribers := make([]Subscriber, 0, len(s.subscribers))
+	for sb := range s.subscribers {
+		subscribers = append(subscribers, sb)
+	}
+	sort.SliceStable(subscribers, func(i, j int) bool {
+		return subscribers[i].Topic() < subscribers[j].Topic()
+	})
+	return subscribers
+}
+
 func (s *rpcServer) Register() error {
 	s.RLock()
 	rsvc := s.rsvc
@@ -637,6 +676,11 @@ func (s *rpcServer) Register() error {
 		endpoints = append(endpoints, e.Endpoints()...)
 	}
 
+	// proto descriptors are only published when asked for
+	if publishDescriptors(config) {
+		endpoints = AddDescriptors(endpoints)
+	}
+
 	service := &registry.Service{
 		Name:      config.Name,
 		Version:   config.Version,
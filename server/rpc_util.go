@@ -2,6 +2,10 @@ package server
 
 import (
 	"sync"
+
+	"github.com/asim/go-micro/v3/auth"
+	"github.com/asim/go-micro/v3/transport"
+	"github.com/asim/go-micro/v3/util/pki"
 )
 
 // waitgroup for global management of connections
@@ -30,3 +34,31 @@ func (w *waitGroup) Wait() {
 	// only wait on local group
 	w.lg.Wait()
 }
+
+// peerAccount returns a service account for the peer of a socket established
+// over mutual TLS. Only verified certificates are considered, nil is returned
+// when the client did not present one.
+func peerAccount(sock transport.Socket) *auth.Account {
+	ts, ok := sock.(transport.TLSSocket)
+	if !ok {
+		return nil
+	}
+	state := ts.ConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	cert := state.VerifiedChains[0][0]
+	id := pki.Identity(cert)
+	if len(id) == 0 {
+		return nil
+	}
+	return &auth.Account{
+		ID:     id,
+		Type:   "service",
+		Issuer: cert.Issuer.CommonName,
+		Metadata: map[string]string{
+			"serial": cert.SerialNumber.String(),
+		},
+		Scopes: []string{"service"},
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/v3/registry"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// schema is the JSON Schema of a request or response
+type schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AnyOf                []*schema          `json:"anyOf,omitempty"`
+	Not                  *schema            `json:"not,omitempty"`
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	enumType = reflect.TypeOf((*protoreflect.Enum)(nil)).Elem()
+)
+
+// extractSchema returns the json schema of the type. Types which refer
+// to themselves are described as an object the second time round.
+func extractSchema(v reflect.Type, seen map[reflect.Type]bool) *schema {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	// proto enums are encoded by name
+	if v.Kind() != reflect.Interface && v.Implements(enumType) {
+		s := &schema{Type: "string", Title: v.Name()}
+		values := reflect.Zero(v).Interface().(protoreflect.Enum).Descriptor().Values()
+		for i := 0; i < values.Len(); i++ {
+			s.Enum = append(s.Enum, string(values.Get(i).Name()))
+		}
+		return s
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema{Type: "integer", Format: v.Kind().String()}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number", Format: v.Kind().String()}
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if v.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: extractSchema(v.Elem(), seen)}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: extractSchema(v.Elem(), seen)}
+	case reflect.Struct:
+	default:
+		// interfaces, including proto oneofs, may hold anything
+		return &schema{}
+	}
+
+	if v == timeType {
+		return &schema{Type: "string", Format: "date-time"}
+	}
+
+	s := &schema{Type: "object", Title: v.Name()}
+	if seen[v] {
+		return s
+	}
+	seen[v] = true
+	defer delete(seen, v)
+
+	s.Properties = make(map[string]*schema)
+	s.AdditionalProperties = false
+
+	// pairs of fields of a oneof which can't both be set
+	var both []*schema
+
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		// skip unexported fields
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		// a proto oneof is encoded as the field it holds
+		if len(f.Tag.Get("protobuf_oneof")) > 0 {
+			members := oneofMembers(v, f)
+			for j, m := range members {
+				s.Properties[m.Name] = extractSchema(m.Field.Type, seen)
+				for _, o := range members[:j] {
+					both = append(both, &schema{Required: []string{o.Name, m.Name}})
+				}
+			}
+			continue
+		}
+		name := fieldName(f)
+		if len(name) == 0 {
+			continue
+		}
+		p := extractSchema(f.Type, seen)
+		p.Description = f.Tag.Get("description")
+		s.Properties[name] = p
+		if isRequired(f) {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	if len(both) > 0 {
+		s.Not = &schema{AnyOf: both}
+	}
+
+	return s
+}
+
+// descriptors of proto messages by full name, they don't change
+var descriptors sync.Map
+
+// extractDescriptor returns the base64 encoded FileDescriptorSet of the
+// file of the proto message and everything it imports
+func extractDescriptor(name string) string {
+	if v, ok := descriptors.Load(name); ok {
+		return v.(string)
+	}
+
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return ""
+	}
+
+	set := new(descriptorpb.FileDescriptorSet)
+	seen := make(map[string]bool)
+
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		// dependencies come first
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(desc.ParentFile())
+
+	b, err := proto.Marshal(set)
+	if err != nil {
+		return ""
+	}
+	v := base64.StdEncoding.EncodeToString(b)
+	descriptors.Store(name, v)
+	return v
+}
+
+// describe sets the json schema of the type in the metadata as
+// <key>_schema along with the <key>_type of protos
+func describe(md map[string]string, key string, v reflect.Type) {
+	if v == nil {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Func, reflect.Interface:
+		// streams have no schema
+		return
+	}
+
+	if b, err := json.Marshal(extractSchema(v, make(map[reflect.Type]bool))); err == nil {
+		md[key+"_schema"] = string(b)
+	}
+	if v.Kind() != reflect.Ptr {
+		v = reflect.PtrTo(v)
+	}
+	if m, ok := reflect.New(v.Elem()).Interface().(protoreflect.ProtoMessage); ok {
+		md[key+"_type"] = string(m.ProtoReflect().Descriptor().FullName())
+	}
+}
+
+// publishDescriptors is whether the server was asked for Descriptors
+func publishDescriptors(opts Options) bool {
+	if opts.Context == nil {
+		return false
+	}
+	b, _ := opts.Context.Value(descriptorsKey{}).(bool)
+	return b
+}
+
+// AddDescriptors returns the endpoints with the <key>_descriptor of their
+// proto <key>_type in the metadata, the endpoints themselves are left as
+// they are. Servers add them when registering if asked for Descriptors.
+func AddDescriptors(eps []*registry.Endpoint) []*registry.Endpoint {
+	out := make([]*registry.Endpoint, len(eps))
+	for i, ep := range eps {
+		md := make(map[string]string, len(ep.Metadata))
+		for k, v := range ep.Metadata {
+			md[k] = v
+			if !strings.HasSuffix(k, "_type") {
+				continue
+			}
+			if desc := extractDescriptor(v); len(desc) > 0 {
+				md[strings.TrimSuffix(k, "_type")+"_descriptor"] = desc
+			}
+		}
+		e := *ep
+		e.Metadata = md
+		out[i] = &e
+	}
+	return out
+}
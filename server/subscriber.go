@@ -54,14 +54,16 @@ func newSubscriber(topic string, sub interface{}, opts ...SubscriberOption) Subs
 
 		handlers = append(handlers, h)
 
-		endpoints = append(endpoints, &registry.Endpoint{
+		ep := &registry.Endpoint{
 			Name:    "Func",
 			Request: extractSubValue(typ),
 			Metadata: map[string]string{
 				"topic":      topic,
 				"subscriber": "true",
 			},
-		})
+		}
+		describe(ep.Metadata, "request", extractSubType(typ))
+		endpoints = append(endpoints, ep)
 	} else {
 		hdlr := reflect.ValueOf(sub)
 		name := reflect.Indirect(hdlr).Type().Name()
@@ -82,14 +84,16 @@ func newSubscriber(topic string, sub interface{}, opts ...SubscriberOption) Subs
 
 			handlers = append(handlers, h)
 
-			endpoints = append(endpoints, &registry.Endpoint{
+			ep := &registry.Endpoint{
 				Name:    name + "." + method.Name,
 				Request: extractSubValue(method.Type),
 				Metadata: map[string]string{
 					"topic":      topic,
 					"subscriber": "true",
 				},
-			})
+			}
+			describe(ep.Metadata, "request", extractSubType(method.Type))
+			endpoints = append(endpoints, ep)
 		}
 	}
 
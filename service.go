@@ -15,6 +15,7 @@ import (
 	"github.com/asim/go-micro/v3/logger"
 	"github.com/asim/go-micro/v3/plugins"
 	"github.com/asim/go-micro/v3/server"
+	"github.com/asim/go-micro/v3/server/reflection"
 	"github.com/asim/go-micro/v3/store"
 	signalutil "github.com/asim/go-micro/v3/util/signal"
 	"github.com/asim/go-micro/v3/util/wrapper"
@@ -101,7 +102,9 @@ func (s *service) Init(opts ...Option) {
 			cmd.Server(&s.opts.Server),
 			cmd.Store(&s.opts.Store),
 			cmd.Profile(&s.opts.Profile),
-		); err != nil {
+		); err == cmd.ErrPrintConfig {
+			os.Exit(0)
+		} else if err != nil {
 			logger.Fatal(err)
 		}
 
@@ -177,6 +180,16 @@ func (s *service) Run() (err error) {
 		),
 	)
 
+	// register the reflection handler
+	if s.opts.Reflection {
+		s.opts.Server.Handle(
+			s.opts.Server.NewHandler(
+				reflection.NewHandler(s.opts.Server),
+				server.InternalHandler(true),
+			),
+		)
+	}
+
 	// start the profiler
 	if s.opts.Profile != nil {
 		// to view mutex contention
@@ -80,6 +80,15 @@ func (h *httpTransportClient) Remote() string {
 	return h.remote
 }
 
+func (h *httpTransportClient) ConnectionState() *tls.ConnectionState {
+	c, ok := h.conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := c.ConnectionState()
+	return &state
+}
+
 func (h *httpTransportClient) Send(m *Message) error {
 	header := make(http.Header)
 
@@ -188,6 +197,10 @@ func (h *httpTransportSocket) Remote() string {
 	return h.remote
 }
 
+func (h *httpTransportSocket) ConnectionState() *tls.ConnectionState {
+	return h.r.TLS
+}
+
 func (h *httpTransportSocket) Recv(m *Message) error {
 	if m == nil {
 		return errors.New("message passed in is nil")
@@ -2,6 +2,7 @@
 package transport
 
 import (
+	"crypto/tls"
 	"time"
 )
 
@@ -29,6 +30,13 @@ type Socket interface {
 	Remote() string
 }
 
+// TLSSocket is implemented by sockets established over TLS. The connection
+// state carries the verified peer certificates after a mutual TLS handshake.
+type TLSSocket interface {
+	Socket
+	ConnectionState() *tls.ConnectionState
+}
+
 type Client interface {
 	Socket
 }
@@ -0,0 +1,22 @@
+// Package broker provides broker utility functions
+package broker
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/asim/go-micro/v3/broker"
+)
+
+// Body returns json bodies of messages as is and encodes anything else,
+// e.g. protobuf, as a base64 string. Empty bodies are nil.
+func Body(m *broker.Message) json.RawMessage {
+	if len(m.Body) == 0 {
+		return nil
+	}
+	if strings.Contains(m.Header["Content-Type"], "json") && json.Valid(m.Body) {
+		return m.Body
+	}
+	b, _ := json.Marshal(m.Body)
+	return b
+}
@@ -0,0 +1,31 @@
+package broker
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/asim/go-micro/v3/broker"
+)
+
+func TestBody(t *testing.T) {
+	testData := []struct {
+		msg    *broker.Message
+		expect string
+	}{
+		{&broker.Message{Header: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{"name":"john"}`)}, `{"name":"john"}`},
+		{&broker.Message{Header: map[string]string{"Content-Type": "application/protobuf"}, Body: []byte{0x0a, 0x04, 'j', 'o', 'h', 'n'}}, `"CgRqb2hu"`},
+		{&broker.Message{Header: map[string]string{"Content-Type": "application/json"}, Body: []byte("not json")}, `"bm90IGpzb24="`},
+		{&broker.Message{}, `null`},
+	}
+
+	for _, d := range testData {
+		b, err := json.Marshal(map[string]interface{}{"body": Body(d.msg)})
+		if err != nil {
+			t.Fatalf("%s: %v", d.msg.Body, err)
+		}
+		if !strings.Contains(string(b), d.expect) {
+			t.Fatalf("Expected %s got %s", d.expect, b)
+		}
+	}
+}
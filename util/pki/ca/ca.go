@@ -0,0 +1,141 @@
+// Package ca provides a certificate authority service which signs certificate
+// requests over RPC and a manager which keeps a service certificate renewed
+package ca
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/asim/go-micro/v3/auth"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/util/pki"
+)
+
+var (
+	// DefaultService is the name of the certificate authority service
+	DefaultService = "go.micro.ca"
+	// DefaultTTL is the validity of certificates issued by the authority
+	DefaultTTL = time.Hour * 24
+)
+
+// SignRequest carries a PEM encoded certificate request
+type SignRequest struct {
+	Csr []byte `json:"csr"`
+}
+
+// SignResponse returns the PEM encoded certificate and CA certificate
+type SignResponse struct {
+	Certificate []byte `json:"certificate"`
+	Ca          []byte `json:"ca"`
+}
+
+// Authorize is the default Authorizer, only a caller authenticated with the
+// account of the service may request a certificate for it
+func Authorize(ctx context.Context, id string) error {
+	acc, ok := auth.AccountFromContext(ctx)
+	if !ok {
+		return errors.Forbidden(DefaultService, "an account is required to request a certificate for %s", id)
+	}
+	if acc.ID != id {
+		return errors.Forbidden(DefaultService, "%s can not request a certificate for %s", acc.ID, id)
+	}
+	return nil
+}
+
+// checkNames of the certificate request, which may only name the service
+// it's for, so a caller authorized for one can't get a certificate valid
+// for any other host or address
+func checkNames(csr *x509.CertificateRequest, id string) error {
+	if cn := csr.Subject.CommonName; len(cn) > 0 && cn != id {
+		return errors.Forbidden(DefaultService, "common name %s is not %s", cn, id)
+	}
+	for _, name := range csr.DNSNames {
+		if name != id {
+			return errors.Forbidden(DefaultService, "dns name %s is not %s", name, id)
+		}
+	}
+	for _, u := range csr.URIs {
+		if *u != *pki.ServiceURI(id) {
+			return errors.Forbidden(DefaultService, "uri %s is not of %s", u, id)
+		}
+	}
+	if len(csr.IPAddresses) > 0 || len(csr.EmailAddresses) > 0 {
+		return errors.Forbidden(DefaultService, "certificates for %s can only name the service", id)
+	}
+	return nil
+}
+
+// CA is the certificate authority handler
+type CA struct {
+	opts Options
+	crt  []byte
+	key  []byte
+}
+
+// NewHandler returns a certificate authority handler signing with the PEM encoded
+// CA certificate and key. Register it with server.NewHandler to serve CA.Sign.
+func NewHandler(crt, key []byte, opts ...Option) *CA {
+	options := Options{
+		TTL:        DefaultTTL,
+		Authorizer: Authorize,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &CA{
+		opts: options,
+		crt:  crt,
+		key:  key,
+	}
+}
+
+// Sign a certificate request. Callers are authorized by the Authorizer option,
+// by default they must be authenticated with an account of the service named,
+// and the request may only name that service.
+func (c *CA) Sign(ctx context.Context, req *SignRequest, rsp *SignResponse) error {
+	block, _ := pem.Decode(req.Csr)
+	if block == nil {
+		return errors.BadRequest(DefaultService, "csr is not valid PEM")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return errors.BadRequest(DefaultService, "csr is invalid: %v", err)
+	}
+	id := pki.Identity(&x509.Certificate{
+		Subject:  csr.Subject,
+		DNSNames: csr.DNSNames,
+		URIs:     csr.URIs,
+	})
+	if len(id) == 0 {
+		return errors.BadRequest(DefaultService, "csr does not name a service")
+	}
+	if err := c.opts.Authorizer(ctx, id); err != nil {
+		return err
+	}
+	if err := checkNames(csr, id); err != nil {
+		return err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return errors.InternalServerError(DefaultService, "%v", err)
+	}
+
+	now := time.Now()
+	crt, err := pki.Sign(c.crt, c.key, req.Csr,
+		pki.SerialNumber(serial),
+		pki.NotBefore(now.Add(-time.Minute)),
+		pki.NotAfter(now.Add(c.opts.TTL)),
+	)
+	if err != nil {
+		return errors.BadRequest(DefaultService, "%v", err)
+	}
+
+	rsp.Certificate = crt
+	rsp.Ca = c.crt
+	return nil
+}
@@ -0,0 +1,326 @@
+package ca
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/asim/go-micro/v3/auth"
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/errors"
+	"github.com/asim/go-micro/v3/registry"
+	"github.com/asim/go-micro/v3/server"
+	"github.com/asim/go-micro/v3/transport"
+	"github.com/asim/go-micro/v3/util/pki"
+)
+
+func testCA(t *testing.T) ([]byte, []byte) {
+	pub, priv, err := pki.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, key, err := pki.CA(
+		pki.KeyPair(pub, priv),
+		pki.Subject(pkix.Name{CommonName: "ca"}),
+		pki.SerialNumber(big.NewInt(1)),
+		pki.NotBefore(time.Now().Add(-time.Minute)),
+		pki.NotAfter(time.Now().Add(time.Hour)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crt, key
+}
+
+// testAuthority is a certificate authority served over mutual TLS
+type testAuthority struct {
+	crt, key []byte
+	registry registry.Registry
+	stop     func()
+}
+
+// issue a certificate to the service straight from the authority's key,
+// as services are bootstrapped
+func (a *testAuthority) issue(t *testing.T, name string) (*tls.Certificate, *x509.CertPool) {
+	pub, priv, err := pki.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := pki.CSR(pki.Service(name), pki.KeyPair(pub, priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, err := pki.Sign(a.crt, a.key, csr,
+		pki.SerialNumber(big.NewInt(time.Now().UnixNano())),
+		pki.NotBefore(time.Now().Add(-time.Minute)),
+		pki.NotAfter(time.Now().Add(time.Hour)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := pki.EncodeKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(crt, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(a.crt)
+	return &cert, pool
+}
+
+// manager of the service bootstrapped with a certificate for the
+// bootstrapped one, calling the authority over mutual TLS
+func (a *testAuthority) manager(t *testing.T, name, bootstrapped string, opts ...ManagerOption) *Manager {
+	m := NewManager(append([]ManagerOption{Name(name), Id(name + "-1")}, opts...)...)
+	m.cert, m.pool = a.issue(t, bootstrapped)
+	m.leaf = m.cert.Leaf
+	m.opts.Client = client.NewClient(
+		client.Registry(a.registry),
+		client.Transport(transport.NewHTTPTransport(transport.TLSConfig(m.TLSConfig(DefaultService)))),
+	)
+	return m
+}
+
+// testServer serves a certificate authority over mutual TLS authorizing
+// callers by the account of their certificate
+func testServer(t *testing.T, opts ...Option) *testAuthority {
+	crt, key := testCA(t)
+	a := &testAuthority{crt: crt, key: key, registry: registry.NewMemoryRegistry()}
+
+	m := NewManager(Name(DefaultService))
+	m.cert, m.pool = a.issue(t, DefaultService)
+	m.leaf = m.cert.Leaf
+
+	s := server.NewServer(
+		server.Name(DefaultService),
+		server.Registry(a.registry),
+		server.Address("127.0.0.1:0"),
+		server.Transport(transport.NewHTTPTransport(transport.TLSConfig(m.TLSConfig()))),
+	)
+	if err := s.Handle(s.NewHandler(NewHandler(crt, key, opts...))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	a.stop = func() { s.Stop() }
+	return a
+}
+
+func testManager(t *testing.T, a *testAuthority, name string, opts ...ManagerOption) *Manager {
+	m := a.manager(t, name, name, opts...)
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSign(t *testing.T) {
+	crt, key := testCA(t)
+	c := NewHandler(crt, key)
+
+	pub, priv, err := pki.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := pki.CSR(pki.Service("greeter"), pki.KeyPair(pub, priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &SignRequest{Csr: csr}
+
+	testData := []struct {
+		name string
+		ctx  context.Context
+		code int32
+	}{
+		{"Unauthenticated", context.TODO(), 403},
+		{"OtherService", auth.ContextWithAccount(context.TODO(), &auth.Account{ID: "other", Type: "service"}), 403},
+		{"OtherType", auth.ContextWithAccount(context.TODO(), &auth.Account{ID: "other", Type: "user"}), 403},
+		{"Service", auth.ContextWithAccount(context.TODO(), &auth.Account{ID: "greeter", Type: "service"}), 0},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			err := c.Sign(d.ctx, req, new(SignResponse))
+			if d.code == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if e := errors.FromError(err); e.Code != d.code {
+				t.Fatalf("expected code %d got %v", d.code, err)
+			}
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	a := testServer(t, TTL(time.Minute))
+	defer a.stop()
+
+	m := testManager(t, a, "greeter", RenewBefore(time.Minute-time.Millisecond*200))
+	defer m.Stop()
+
+	cert, err := m.Certificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := pki.Identity(cert.Leaf); id != "greeter" {
+		t.Fatalf("expected identity greeter got %s", id)
+	}
+	if cert.Leaf.Subject.SerialNumber != "greeter-1" {
+		t.Fatalf("expected id greeter-1 got %s", cert.Leaf.Subject.SerialNumber)
+	}
+
+	// the certificate is renewed shortly before expiry
+	time.Sleep(time.Millisecond * 500)
+
+	renewed, err := m.Certificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.Leaf.SerialNumber.Cmp(cert.Leaf.SerialNumber) == 0 {
+		t.Fatal("expected the certificate to be renewed")
+	}
+}
+
+func TestManagerHandshake(t *testing.T) {
+	a := testServer(t)
+	defer a.stop()
+
+	greeter := testManager(t, a, "greeter")
+	defer greeter.Stop()
+	other := testManager(t, a, "other")
+	defer other.Stop()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", greeter.TLSConfig("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.(*tls.Conn).Handshake()
+			c.Close()
+		}
+	}()
+
+	foo := testManager(t, a, "foo")
+	defer foo.Stop()
+
+	// a certificate issued by another authority
+	a2 := testServer(t)
+	defer a2.stop()
+	untrusted := testManager(t, a2, "other")
+	defer untrusted.Stop()
+
+	testData := []struct {
+		name   string
+		config *tls.Config
+		ok     bool
+	}{
+		{"Peer", other.TLSConfig("greeter"), true},
+		{"UnexpectedServer", other.TLSConfig("foo"), false},
+		{"UnexpectedClient", foo.TLSConfig("greeter"), false},
+		{"UntrustedPeer", untrusted.TLSConfig("greeter"), false},
+		{"NoCertificate", &tls.Config{InsecureSkipVerify: true}, false},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			c, err := tls.Dial("tcp", l.Addr().String(), d.config)
+			if err == nil {
+				// the server verifies the client after the client's handshake
+				_, err = c.Read(make([]byte, 1))
+				c.Close()
+				if err == io.EOF {
+					err = nil
+				}
+			}
+			if d.ok && err != nil {
+				t.Fatalf("expected the handshake to succeed got %v", err)
+			}
+			if !d.ok && err == nil {
+				t.Fatal("expected the handshake to fail")
+			}
+		})
+	}
+}
+
+func TestSignNames(t *testing.T) {
+	crt, key := testCA(t)
+	c := NewHandler(crt, key)
+	ctx := auth.ContextWithAccount(context.TODO(), &auth.Account{ID: "greeter", Type: "service"})
+
+	testData := []struct {
+		name string
+		opts []pki.CertOption
+		code int32
+	}{
+		{"Service", []pki.CertOption{pki.Service("greeter")}, 0},
+		{"OtherHost", []pki.CertOption{pki.Service("greeter"), pki.DNSNames("example.com")}, 403},
+		{"Address", []pki.CertOption{pki.Service("greeter"), pki.IPAddresses(net.ParseIP("10.0.0.1"))}, 403},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			pub, priv, err := pki.GenerateKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			csr, err := pki.CSR(append(d.opts, pki.KeyPair(pub, priv))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.Sign(ctx, &SignRequest{Csr: csr}, new(SignResponse))
+			if d.code == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if e := errors.FromError(err); e.Code != d.code {
+				t.Fatalf("expected code %d got %v", d.code, err)
+			}
+		})
+	}
+}
+
+func TestManagerAuthorized(t *testing.T) {
+	a := testServer(t)
+	defer a.stop()
+
+	// renewed with the account of the certificate presented
+	m := a.manager(t, "greeter", "greeter")
+	if err := m.renew(); err != nil {
+		t.Fatal(err)
+	}
+	if id := pki.Identity(m.leaf); id != "greeter" {
+		t.Fatalf("expected identity greeter got %s", id)
+	}
+
+	// but not for another service
+	m = a.manager(t, "other", "greeter")
+	if e := errors.FromError(m.renew()); e.Code != 403 {
+		t.Fatalf("expected a certificate for another service to be forbidden got %v", e)
+	}
+}
@@ -0,0 +1,249 @@
+package ca
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/asim/go-micro/v3/client"
+	"github.com/asim/go-micro/v3/logger"
+	"github.com/asim/go-micro/v3/util/backoff"
+	"github.com/asim/go-micro/v3/util/pki"
+)
+
+// Manager requests a certificate from the certificate authority and renews it
+// before it expires. The tls.Config returned by TLSConfig always presents the
+// current certificate so it can be passed to the transport and broker once.
+type Manager struct {
+	opts ManagerOptions
+
+	sync.RWMutex
+	cert *tls.Certificate
+	leaf *x509.Certificate
+	pool *x509.CertPool
+	exit chan bool
+}
+
+// NewManager returns a certificate manager
+func NewManager(opts ...ManagerOption) *Manager {
+	options := ManagerOptions{
+		Client:  client.DefaultClient,
+		Service: DefaultService,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &Manager{
+		opts: options,
+	}
+}
+
+// Start requests the initial certificate and begins renewing it
+func (m *Manager) Start() error {
+	if len(m.opts.Name) == 0 {
+		return errors.New("service name required to request a certificate")
+	}
+	if err := m.renew(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if m.exit != nil {
+		return nil
+	}
+	m.exit = make(chan bool)
+	go m.run(m.exit)
+	return nil
+}
+
+// Stop renewing the certificate
+func (m *Manager) Stop() error {
+	m.Lock()
+	defer m.Unlock()
+	if m.exit != nil {
+		close(m.exit)
+		m.exit = nil
+	}
+	return nil
+}
+
+// Certificate returns the current certificate
+func (m *Manager) Certificate() (*tls.Certificate, error) {
+	m.RLock()
+	defer m.RUnlock()
+	if m.cert == nil {
+		return nil, errors.New("no certificate issued")
+	}
+	return m.cert, nil
+}
+
+// TLSConfig returns a config for mutual TLS which presents the current certificate
+// and requires peers to present one issued by the certificate authority to a
+// service in peers, or to any service if none are given. Start must be called first.
+func (m *Manager) TLSConfig(peers ...string) *tls.Config {
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return m.Certificate()
+	}
+	verifyConnection := func(cs tls.ConnectionState) error {
+		return m.verify(cs.PeerCertificates, peers)
+	}
+
+	return &tls.Config{
+		GetCertificate: getCertificate,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return m.Certificate()
+		},
+		// services are dialed by address rather than name so the hostname check
+		// is skipped and the peer verified against the CA and peers instead
+		InsecureSkipVerify: true,
+		VerifyConnection:   verifyConnection,
+		// servers verify clients against the current CA pool, which fills in
+		// the verified chains the server takes the peer's account from
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			m.RLock()
+			pool := m.pool
+			m.RUnlock()
+			if pool == nil {
+				return nil, errors.New("no certificate authority to verify the peer with")
+			}
+			return &tls.Config{
+				ClientAuth:       tls.RequireAndVerifyClientCert,
+				ClientCAs:        pool,
+				GetCertificate:   getCertificate,
+				VerifyConnection: verifyConnection,
+			}, nil
+		},
+	}
+}
+
+// verify the peer certificate chain against the current CA pool and its
+// identity against the peers
+func (m *Manager) verify(certs []*x509.Certificate, peers []string) error {
+	if len(certs) == 0 {
+		return errors.New("peer presented no certificate")
+	}
+
+	m.RLock()
+	pool := m.pool
+	m.RUnlock()
+	if pool == nil {
+		return errors.New("no certificate authority to verify the peer with")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return err
+	}
+
+	id := pki.Identity(certs[0])
+	if len(id) == 0 {
+		return errors.New("peer certificate does not name a service")
+	}
+	if len(peers) == 0 {
+		return nil
+	}
+	for _, p := range peers {
+		if p == id {
+			return nil
+		}
+	}
+	return fmt.Errorf("peer %s is not one of %v", id, peers)
+}
+
+func (m *Manager) run(exit chan bool) {
+	var attempts int
+
+	for {
+		wait := m.renewIn()
+		if attempts > 0 {
+			wait = backoff.Do(attempts)
+		}
+
+		select {
+		case <-exit:
+			return
+		case <-time.After(wait):
+		}
+
+		if err := m.renew(); err != nil {
+			attempts++
+			logger.Errorf("Failed to renew certificate for %s: %v", m.opts.Name, err)
+			continue
+		}
+		attempts = 0
+	}
+}
+
+// renewIn returns the time until the current certificate should be renewed
+func (m *Manager) renewIn() time.Duration {
+	m.RLock()
+	defer m.RUnlock()
+
+	before := m.opts.RenewBefore
+	if before == 0 {
+		before = m.leaf.NotAfter.Sub(m.leaf.NotBefore) / 3
+	}
+	return time.Until(m.leaf.NotAfter.Add(-before))
+}
+
+// renew generates a new key and has the certificate authority sign it
+func (m *Manager) renew() error {
+	pub, priv, err := pki.GenerateKey()
+	if err != nil {
+		return err
+	}
+	csr, err := pki.CSR(
+		pki.Subject(pkix.Name{SerialNumber: m.opts.Id}),
+		pki.Service(m.opts.Name),
+		pki.KeyPair(pub, priv),
+	)
+	if err != nil {
+		return err
+	}
+
+	req := m.opts.Client.NewRequest(m.opts.Service, "CA.Sign", &SignRequest{Csr: csr}, client.WithContentType("application/json"))
+	rsp := new(SignResponse)
+	if err := m.opts.Client.Call(context.Background(), req, rsp); err != nil {
+		return err
+	}
+
+	key, err := pki.EncodeKey(priv)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.X509KeyPair(rsp.Certificate, key)
+	if err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	cert.Leaf = leaf
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(rsp.Ca) {
+		return errors.New("certificate authority returned an invalid CA")
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.pool = pool
+	m.cert = &cert
+	m.leaf = leaf
+	return nil
+}
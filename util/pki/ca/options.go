@@ -0,0 +1,91 @@
+package ca
+
+import (
+	"context"
+	"time"
+
+	"github.com/asim/go-micro/v3/client"
+)
+
+// Options for the certificate authority handler
+type Options struct {
+	// TTL of the certificates issued
+	TTL time.Duration
+	// Authorizer decides whether the caller may request a certificate
+	// for the service named, Authorize by default
+	Authorizer Authorizer
+}
+
+// Authorizer returns an error if the caller in the context may not request
+// a certificate for the service named e.g. to bootstrap services with a token
+type Authorizer func(ctx context.Context, service string) error
+
+// Option sets Options
+type Option func(o *Options)
+
+// TTL sets the validity of the certificates issued
+func TTL(t time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = t
+	}
+}
+
+// WithAuthorizer sets the authorizer of certificate requests
+func WithAuthorizer(a Authorizer) Option {
+	return func(o *Options) {
+		o.Authorizer = a
+	}
+}
+
+// ManagerOptions for the certificate manager
+type ManagerOptions struct {
+	// Client used to call the certificate authority
+	Client client.Client
+	// Service is the name of the certificate authority service
+	Service string
+	// Name of the service requesting a certificate
+	Name string
+	// Id of the service node requesting a certificate
+	Id string
+	// RenewBefore is how long before expiry the certificate is renewed.
+	// Defaults to a third of the certificate lifetime.
+	RenewBefore time.Duration
+}
+
+// ManagerOption sets ManagerOptions
+type ManagerOption func(o *ManagerOptions)
+
+// Client to call the certificate authority with
+func Client(c client.Client) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.Client = c
+	}
+}
+
+// Service is the name of the certificate authority service
+func Service(s string) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.Service = s
+	}
+}
+
+// Name of the service the certificate is issued to
+func Name(n string) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.Name = n
+	}
+}
+
+// Id of the service node the certificate is issued to
+func Id(id string) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.Id = id
+	}
+}
+
+// RenewBefore sets how long before expiry the certificate is renewed
+func RenewBefore(d time.Duration) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.RenewBefore = d
+	}
+}
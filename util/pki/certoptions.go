@@ -6,6 +6,7 @@ import (
 	"crypto/x509/pkix"
 	"math/big"
 	"net"
+	"net/url"
 	"time"
 )
 
@@ -15,6 +16,7 @@ type CertOptions struct {
 	Subject      pkix.Name
 	DNSNames     []string
 	IPAddresses  []net.IP
+	URIs         []*url.URL
 	SerialNumber *big.Int
 	NotBefore    time.Time
 	NotAfter     time.Time
@@ -55,6 +57,23 @@ func IPAddresses(ips ...net.IP) CertOption {
 	}
 }
 
+// URIs is a list of URIs to sign in to the certificate
+func URIs(uris ...*url.URL) CertOption {
+	return func(c *CertOptions) {
+		c.URIs = uris
+	}
+}
+
+// Service signs the service name in to the certificate as a URI and DNS SAN
+// and sets it as the common name, identifying the service during mutual TLS
+func Service(name string) CertOption {
+	return func(c *CertOptions) {
+		c.Subject.CommonName = name
+		c.DNSNames = append(c.DNSNames, name)
+		c.URIs = append(c.URIs, ServiceURI(name))
+	}
+}
+
 // KeyPair is the key pair to sign the certificate with
 func KeyPair(pub ed25519.PublicKey, priv ed25519.PrivateKey) CertOption {
 	return func(c *CertOptions) {
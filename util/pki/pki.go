@@ -8,10 +8,14 @@ import (
 	"crypto/rand"
 	"crypto/x509"
 	"encoding/pem"
+	"net/url"
 
 	"github.com/pkg/errors"
 )
 
+// ServiceScheme is the URI scheme of the SAN identifying a service
+const ServiceScheme = "micro"
+
 // GenerateKey returns an ed25519 key
 func GenerateKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
 	return ed25519.GenerateKey(rand.Reader)
@@ -29,8 +33,9 @@ func CA(opts ...CertOption) ([]byte, []byte, error) {
 		Subject:               options.Subject,
 		DNSNames:              options.DNSNames,
 		IPAddresses:           options.IPAddresses,
+		URIs:                  options.URIs,
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		NotBefore:             options.NotBefore,
 		NotAfter:              options.NotAfter,
 		SerialNumber:          options.SerialNumber,
@@ -44,19 +49,25 @@ func CA(opts ...CertOption) ([]byte, []byte, error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	cert, key := &bytes.Buffer{}, &bytes.Buffer{}
+	cert := &bytes.Buffer{}
 	if err := pem.Encode(cert, &pem.Block{Type: "CERTIFICATE", Bytes: x509Cert}); err != nil {
 		return nil, nil, err
 	}
-	x509Key, err := x509.MarshalPKCS8PrivateKey(options.Priv)
+	key, err := EncodeKey(options.Priv)
 	if err != nil {
 		return nil, nil, err
 	}
-	if err := pem.Encode(key, &pem.Block{Type: "PRIVATE KEY", Bytes: x509Key}); err != nil {
-		return nil, nil, err
-	}
 
-	return cert.Bytes(), key.Bytes(), nil
+	return cert.Bytes(), key, nil
+}
+
+// EncodeKey returns a private key in PEM format
+func EncodeKey(priv ed25519.PrivateKey) ([]byte, error) {
+	x509Key, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: x509Key}), nil
 }
 
 // CSR generates a certificate request in PEM format
@@ -70,6 +81,7 @@ func CSR(opts ...CertOption) ([]byte, error) {
 		SignatureAlgorithm: x509.PureEd25519,
 		DNSNames:           options.DNSNames,
 		IPAddresses:        options.IPAddresses,
+		URIs:               options.URIs,
 	}
 	out := &bytes.Buffer{}
 	csr, err := x509.CreateCertificateRequest(rand.Reader, csrTemplate, options.Priv)
@@ -122,20 +134,24 @@ func Sign(CACrt, CAKey, CSR []byte, opts ...CertOption) ([]byte, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "csr is invalid")
 	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, errors.Wrap(err, "csr signature is invalid")
+	}
 	template := &x509.Certificate{
 		SignatureAlgorithm:    x509.PureEd25519,
 		Subject:               csr.Subject,
 		DNSNames:              csr.DNSNames,
 		IPAddresses:           csr.IPAddresses,
+		URIs:                  csr.URIs,
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		NotBefore:             options.NotBefore,
 		NotAfter:              options.NotAfter,
 		SerialNumber:          options.SerialNumber,
 		BasicConstraintsValid: true,
 	}
 
-	x509Cert, err := x509.CreateCertificate(rand.Reader, template, caCrt, caCrt.PublicKey, caKey)
+	x509Cert, err := x509.CreateCertificate(rand.Reader, template, caCrt, csr.PublicKey, caKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "Couldn't sign certificate")
 	}
@@ -146,6 +162,31 @@ func Sign(CACrt, CAKey, CSR []byte, opts ...CertOption) ([]byte, error) {
 	return out.Bytes(), nil
 }
 
+// ServiceURI returns the URI used to identify a service in a certificate SAN
+func ServiceURI(name string) *url.URL {
+	return &url.URL{Scheme: ServiceScheme, Host: name}
+}
+
+// Identity returns the service name a certificate was issued to. The service URI SAN
+// is preferred, falling back to the subject common name and then the first DNS name
+func Identity(cert *x509.Certificate) string {
+	if cert == nil {
+		return ""
+	}
+	for _, u := range cert.URIs {
+		if u.Scheme == ServiceScheme && len(u.Host) > 0 {
+			return u.Host
+		}
+	}
+	if len(cert.Subject.CommonName) > 0 {
+		return cert.Subject.CommonName
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return ""
+}
+
 func decodePEM(PEM []byte) ([]*pem.Block, error) {
 	var blocks []*pem.Block
 	var asn1 *pem.Block
@@ -88,3 +88,44 @@ func TestCSR(t *testing.T) {
 	}
 	assert.Equal(t, decodedcsr.Subject.String(), expected.String())
 }
+
+func TestServiceIdentity(t *testing.T) {
+	caPub, caPriv, err := GenerateKey()
+	assert.NoError(t, err)
+	caCrt, caKey, err := CA(
+		KeyPair(caPub, caPriv),
+		Subject(pkix.Name{CommonName: "ca"}),
+		SerialNumber(big.NewInt(1)),
+		NotBefore(time.Now().Add(time.Minute*-1)),
+		NotAfter(time.Now().Add(time.Minute)),
+	)
+	assert.NoError(t, err)
+
+	pub, priv, err := GenerateKey()
+	assert.NoError(t, err)
+	csr, err := CSR(Service("greeter"), KeyPair(pub, priv))
+	assert.NoError(t, err)
+
+	crt, err := Sign(caCrt, caKey, csr,
+		SerialNumber(big.NewInt(2)),
+		NotBefore(time.Now().Add(time.Minute*-1)),
+		NotAfter(time.Now().Add(time.Minute)),
+	)
+	assert.NoError(t, err)
+
+	asn1Cert, _ := pem.Decode(crt)
+	assert.NotNil(t, asn1Cert)
+	x509cert, err := x509.ParseCertificate(asn1Cert.Bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, "greeter", Identity(x509cert))
+	assert.Len(t, x509cert.URIs, 1)
+	assert.Equal(t, "micro://greeter", x509cert.URIs[0].String())
+
+	pool := x509.NewCertPool()
+	assert.True(t, pool.AppendCertsFromPEM(caCrt))
+	_, err = x509cert.Verify(x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.NoError(t, err, "Cert should verify for client auth")
+}
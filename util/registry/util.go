@@ -68,14 +68,26 @@ func CopyService(service *registry.Service) *registry.Service {
 	s.Nodes = nodes
 
 	// copy endpoints
-	eps := make([]*registry.Endpoint, len(service.Endpoints))
-	for j, ep := range service.Endpoints {
+	s.Endpoints = copyEndpoints(service.Endpoints)
+	return s
+}
+
+// copyEndpoints copies the endpoints along with their metadata,
+// which carries the schema of the request and response
+func copyEndpoints(endpoints []*registry.Endpoint) []*registry.Endpoint {
+	eps := make([]*registry.Endpoint, len(endpoints))
+	for j, ep := range endpoints {
 		e := new(registry.Endpoint)
 		*e = *ep
+		if ep.Metadata != nil {
+			e.Metadata = make(map[string]string, len(ep.Metadata))
+			for k, v := range ep.Metadata {
+				e.Metadata[k] = v
+			}
+		}
 		eps[j] = e
 	}
-	s.Endpoints = eps
-	return s
+	return eps
 }
 
 // Copy makes a copy of services
@@ -100,6 +112,10 @@ func Merge(olist []*registry.Service, nlist []*registry.Service) []*registry.Ser
 				*sp = *o
 				// set nodes
 				sp.Nodes = addNodes(o.Nodes, n.Nodes)
+				// the latest registration describes the endpoints
+				if len(n.Endpoints) > 0 {
+					sp.Endpoints = copyEndpoints(n.Endpoints)
+				}
 
 				// mark as seen
 				seen = true
@@ -76,3 +76,36 @@ func TestRemoveNodes(t *testing.T) {
 		t.Logf("Nodes %+v", nodes)
 	}
 }
+
+func TestMergeEndpoints(t *testing.T) {
+	old := &registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Endpoints: []*registry.Endpoint{
+			{Name: "Foo.Bar", Metadata: map[string]string{"request_schema": `{"type":"object"}`}},
+		},
+		Nodes: []*registry.Node{{Id: "foo-123", Address: "localhost:9999"}},
+	}
+	neu := &registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Endpoints: []*registry.Endpoint{
+			{Name: "Foo.Bar", Metadata: map[string]string{"request_schema": `{"type":"string"}`}},
+		},
+		Nodes: []*registry.Node{{Id: "foo-321", Address: "localhost:6666"}},
+	}
+
+	cp := CopyService(old)
+	cp.Endpoints[0].Metadata["request_schema"] = "changed"
+	if v := old.Endpoints[0].Metadata["request_schema"]; v != `{"type":"object"}` {
+		t.Fatalf("Expected the copy to leave the schema alone got %s", v)
+	}
+
+	servs := Merge([]*registry.Service{old}, []*registry.Service{neu})
+	if len(servs) != 1 || len(servs[0].Nodes) != 2 {
+		t.Fatalf("Expected 1 service with 2 nodes got %+v", servs)
+	}
+	if v := servs[0].Endpoints[0].Metadata["request_schema"]; v != `{"type":"string"}` {
+		t.Fatalf("Expected the schema of the latest registration got %s", v)
+	}
+}